@@ -1,17 +1,22 @@
 package main
 
 import (
-	"bytes"
-	"database/sql"
-	"encoding/json"
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"mangahub/internal/auth"
+	"mangahub/internal/config"
 	"mangahub/internal/database"
-	"mangahub/internal/shared"
+	"mangahub/internal/events"
+	"mangahub/internal/progress"
+	"mangahub/internal/repository"
+	"mangahub/internal/servers"
 	"mangahub/pkg/models"
 
 	"github.com/gin-contrib/cors"
@@ -23,30 +28,102 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-const tcpServerURL = "http://localhost:9091/internal/progress"
-const udpServerURL = "http://localhost:9094/internal/progress"
-
 // Request types for Swagger
 type AddToLibraryRequest struct {
 	MangaID string `json:"manga_id" binding:"required"`
 	Status  string `json:"status" binding:"oneof=reading completed plan_to_read"`
 }
 
+type UpdateMangaRequest struct {
+	Title         string   `json:"title" binding:"required"`
+	Author        string   `json:"author"`
+	Genres        []string `json:"genres"`
+	Status        string   `json:"status" binding:"required,oneof=ongoing completed hiatus"`
+	TotalChapters int      `json:"total_chapters" binding:"gte=0"`
+	Description   string   `json:"description"`
+}
+
+type SetRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user moderator admin"`
+}
+
 type UpdateProgressRequest struct {
 	MangaID        string `json:"manga_id" binding:"required"`
 	CurrentChapter int    `json:"current_chapter" binding:"gte=0"`
 	Status         string `json:"status" binding:"omitempty,oneof=reading completed plan_to_read"`
 }
 
+// API serves the REST endpoints on top of the storage repositories
+type API struct {
+	manga    repository.MangaRepository
+	users    repository.UserRepository
+	progress repository.ProgressRepository
+	audit    repository.AuditRepository
+	sessions repository.SessionRepository
+	outbox   repository.OutboxRepository
+
+	// updates records progress and queues its broadcast in the outbox
+	updates *progress.Service
+	// events is told about events requeued in the outbox
+	events events.Notifier
+}
+
+// NewAPI creates the REST handlers for a storage backend
+func NewAPI(store *repository.Store, notifier events.Notifier) *API {
+	return &API{
+		manga:    store.Manga,
+		users:    store.Users,
+		progress: store.Progress,
+		audit:    store.Audit,
+		sessions: store.Sessions,
+		outbox:   store.Outbox,
+		updates:  progress.NewService(store, notifier),
+		events:   notifier,
+	}
+}
+
+// promoteAdmins makes the accounts with the configured IDs admins, so a
+// fresh deployment can get its first administrator. IDs are issued by the
+// server, so an operator has to pick an account that already exists;
+// nobody can claim the role by registering a particular username.
+func promoteAdmins(ctx context.Context, users repository.UserRepository, ids []string) {
+	for _, id := range ids {
+		err := users.SetRole(ctx, id, auth.RoleAdmin)
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			log.Printf("Warning: admin user %s does not exist; register the account, then restart", id)
+		case err != nil:
+			log.Printf("Warning: Failed to promote %s to admin: %v", id, err)
+		default:
+			log.Printf("User %s is an admin", id)
+		}
+	}
+}
+
 func main() {
-	if err := database.Initialize("./data/mangahub.db"); err != nil {
+	cfg := config.MustLoad()
+	if err := cfg.RequireJWTSecret(); err != nil {
+		log.Fatal(err)
+	}
+	// Progress events are signed for, or checked by, the real-time servers
+	if err := cfg.RequireEventsSecret(); err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := database.Open(cfg.Database.URL)
+	if err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
-	defer database.Close()
+	defer db.Close()
 
-	if err := database.SeedManga(); err != nil {
+	store := db.Store()
+	if err := database.Seed(context.Background(), store.Manga); err != nil {
 		log.Printf("Warning: Failed to seed manga data: %v", err)
 	}
+	promoteAdmins(context.Background(), store.Users, cfg.Auth.AdminIDs)
+	dispatcher := servers.NewDispatcher(cfg, store)
+	defer dispatcher.Close()
+	api := NewAPI(store, dispatcher)
 
 	router := gin.Default()
 
@@ -69,23 +146,44 @@ func main() {
 
 	public := router.Group("/")
 	{
-		public.POST("/auth/register", registerHandler)
-		public.POST("/auth/login", loginHandler)
+		public.POST("/auth/register", api.registerHandler)
+		public.POST("/auth/login", api.loginHandler)
+		public.POST("/auth/refresh", api.refreshHandler)
 	}
 
+	// Catalog writes are limited to admins and moderators
+	editors := auth.RequireRole(auth.RoleAdmin, auth.RoleModerator)
+
 	protected := router.Group("/")
-	protected.Use(auth.Middleware())
+	protected.Use(auth.Middleware(store.Sessions))
 	{
-		protected.GET("/manga", getMangaHandler)
-		protected.GET("/manga/:id", getMangaDetailHandler)
-		protected.POST("/manga", createMangaHandler)
-		protected.POST("/users/library", addToLibraryHandler)
-		protected.GET("/users/library", getLibraryHandler)
-		protected.PUT("/users/progress", updateProgressHandler)
+		protected.POST("/auth/logout", api.logoutHandler)
+		protected.GET("/auth/sessions", api.getSessionsHandler)
+		protected.DELETE("/auth/sessions/:id", api.deleteSessionHandler)
+		protected.GET("/manga", api.getMangaHandler)
+		protected.GET("/manga/:id", api.getMangaDetailHandler)
+		protected.POST("/manga", editors, api.createMangaHandler)
+		protected.PUT("/manga/:id", editors, api.updateMangaHandler)
+		protected.PATCH("/manga/:id", editors, api.patchMangaHandler)
+		protected.DELETE("/manga/:id", editors, api.deleteMangaHandler)
+		protected.POST("/manga/:id/restore", editors, api.restoreMangaHandler)
+		protected.GET("/manga/:id/audit", editors, api.getMangaAuditHandler)
+		protected.GET("/genres", api.getGenresHandler)
+		protected.POST("/users/library", api.addToLibraryHandler)
+		protected.GET("/users/library", api.getLibraryHandler)
+		protected.PUT("/users/progress", api.updateProgressHandler)
+	}
+
+	admin := protected.Group("/admin")
+	admin.Use(auth.RequireRole(auth.RoleAdmin))
+	{
+		admin.PUT("/users/:id/role", api.setUserRoleHandler)
+		admin.GET("/outbox/dead-letters", api.getDeadLettersHandler)
+		admin.POST("/outbox/dead-letters/:id/requeue", api.requeueDeadLetterHandler)
 	}
 
-	log.Println("API Server starting on http://localhost:8080")
-	log.Println("Swagger docs: http://localhost:8080/swagger/index.html")
+	log.Printf("API Server starting on http://%s", config.DialAddr(cfg.API.Addr))
+	log.Printf("Swagger docs: http://%s/swagger/index.html", config.DialAddr(cfg.API.Addr))
 
 	// Serve static web files
 	router.Static("/web", "./web")
@@ -96,7 +194,9 @@ func main() {
 		c.File("./web/search_manga.html")
 	})
 
-	router.Run(":8080")
+	if err := router.Run(cfg.API.Addr); err != nil {
+		log.Fatal("Failed to start API server:", err)
+	}
 }
 
 // Register new user
@@ -110,7 +210,7 @@ func main() {
 // @Failure      400 {object} map[string]string "Invalid request"
 // @Failure      500 {object} map[string]string "Server error"
 // @Router       /auth/register [post]
-func registerHandler(c *gin.Context) {
+func (a *API) registerHandler(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -124,21 +224,27 @@ func registerHandler(c *gin.Context) {
 	}
 
 	userID := auth.GenerateID("usr")
-
-	_, err = database.DB.Exec(`INSERT INTO users (id, username, email, password_hash) VALUES (?, ?, ?, ?)`,
-		userID, req.Username, req.Email, passwordHash)
+	role := auth.RoleUser
+
+	err = a.users.Create(c.Request.Context(), models.User{
+		ID:           userID,
+		Username:     req.Username,
+		Email:        req.Email,
+		PasswordHash: passwordHash,
+		Role:         role,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
 
-	token, err := auth.GenerateToken(userID, req.Username)
+	resp, err := a.startSession(c, models.User{ID: userID, Username: req.Username, Role: role})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, models.LoginResponse{Token: token, Username: req.Username, UserID: userID})
+	c.JSON(http.StatusOK, resp)
 }
 
 // Login user
@@ -153,17 +259,15 @@ func registerHandler(c *gin.Context) {
 // @Failure      401 {object} map[string]string "Invalid credentials"
 // @Failure      500 {object} map[string]string "Server error"
 // @Router       /auth/login [post]
-func loginHandler(c *gin.Context) {
+func (a *API) loginHandler(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var user models.User
-	err := database.DB.QueryRow("SELECT id, username, password_hash FROM users WHERE username = ?", req.Username).
-		Scan(&user.ID, &user.Username, &user.PasswordHash)
-	if err == sql.ErrNoRows {
+	user, err := a.users.GetByUsername(c.Request.Context(), req.Username)
+	if errors.Is(err, repository.ErrNotFound) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	} else if err != nil {
@@ -176,13 +280,168 @@ func loginHandler(c *gin.Context) {
 		return
 	}
 
-	token, err := auth.GenerateToken(user.ID, user.Username)
+	resp, err := a.startSession(c, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// startSession opens a login session for user and returns its first token pair
+func (a *API) startSession(c *gin.Context, user models.User) (models.LoginResponse, error) {
+	refreshToken, refreshHash, err := auth.NewRefreshToken()
+	if err != nil {
+		return models.LoginResponse{}, err
+	}
+
+	session := models.Session{
+		ID:        auth.GenerateID("ses"),
+		UserID:    user.ID,
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+		ExpiresAt: time.Now().Add(auth.RefreshTokenTTL),
+	}
+	if err := a.sessions.Create(c.Request.Context(), session, refreshHash); err != nil {
+		return models.LoginResponse{}, err
+	}
+
+	return tokenResponse(user, session.ID, refreshToken)
+}
+
+// tokenResponse issues an access token for user in a session alongside its refresh token
+func tokenResponse(user models.User, sessionID, refreshToken string) (models.LoginResponse, error) {
+	token, err := auth.GenerateToken(user.ID, user.Username, user.Role, sessionID)
+	if err != nil {
+		return models.LoginResponse{}, err
+	}
+	return models.LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(auth.AccessTokenTTL / time.Second),
+		Username:     user.Username,
+		UserID:       user.ID,
+		Role:         user.Role,
+	}, nil
+}
+
+// Refresh tokens
+// @Summary      Refresh tokens
+// @Description  Exchange a refresh token for a new access token and refresh token. The old refresh
+// @Description  token stops working; presenting it again revokes the whole session.
+// @Description  The new access token carries the user's current role.
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Param        request body models.RefreshRequest true "Refresh token"
+// @Success      200 {object} models.LoginResponse "New token pair"
+// @Failure      400 {object} map[string]string "Invalid request"
+// @Failure      401 {object} map[string]string "Invalid or expired refresh token"
+// @Failure      500 {object} map[string]string "Server error"
+// @Router       /auth/refresh [post]
+func (a *API) refreshHandler(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refreshToken, refreshHash, err := auth.NewRefreshToken()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, models.LoginResponse{Token: token, Username: user.Username, UserID: user.ID})
+	ctx := c.Request.Context()
+	session, err := a.sessions.Rotate(ctx, auth.HashRefreshToken(req.RefreshToken), refreshHash, time.Now().Add(auth.RefreshTokenTTL))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	} else if err != nil {
+		log.Printf("Database error refreshing session: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	user, err := a.users.GetByID(ctx, session.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	resp, err := tokenResponse(user, session.ID, refreshToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Logout
+// @Summary      Logout
+// @Description  End the session of the calling token. Its access and refresh tokens stop working at once.
+// @Tags         Auth
+// @Produce      json
+// @Param        Authorization header string true "Bearer {token}"
+// @Success      200 {object} map[string]string "Logged out"
+// @Failure      401 {object} map[string]string "Invalid or revoked token"
+// @Failure      500 {object} map[string]string "Server error"
+// @Router       /auth/logout [post]
+func (a *API) logoutHandler(c *gin.Context) {
+	err := a.sessions.Revoke(c.Request.Context(), c.GetString("user_id"), c.GetString("session_id"))
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// List sessions
+// @Summary      List sessions
+// @Description  List the authenticated user's active sessions, most recently used first.
+// @Description  The session of the calling token is marked current.
+// @Tags         Auth
+// @Produce      json
+// @Param        Authorization header string true "Bearer {token}"
+// @Success      200 {object} map[string]any "Sessions with count"
+// @Failure      500 {object} map[string]string "Server error"
+// @Router       /auth/sessions [get]
+func (a *API) getSessionsHandler(c *gin.Context) {
+	sessions, err := a.sessions.List(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		log.Printf("Database error fetching sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == c.GetString("session_id")
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions, "count": len(sessions)})
+}
+
+// Kill a session
+// @Summary      Revoke session
+// @Description  End one of the authenticated user's sessions, for example a login on a lost device.
+// @Tags         Auth
+// @Produce      json
+// @Param        id path string true "Session ID"
+// @Param        Authorization header string true "Bearer {token}"
+// @Success      200 {object} map[string]string "Session revoked"
+// @Failure      404 {object} map[string]string "Session not found"
+// @Failure      500 {object} map[string]string "Server error"
+// @Router       /auth/sessions/{id} [delete]
+func (a *API) deleteSessionHandler(c *gin.Context) {
+	err := a.sessions.Revoke(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
 }
 
 // Get manga detail
@@ -196,12 +455,10 @@ func loginHandler(c *gin.Context) {
 // @Failure      404 {object} map[string]string "Manga not found"
 // @Failure      500 {object} map[string]string "Server error"
 // @Router       /manga/{id} [get]
-func getMangaDetailHandler(c *gin.Context) {
+func (a *API) getMangaDetailHandler(c *gin.Context) {
 	id := c.Param("id")
-	var m models.Manga
-	err := database.DB.QueryRow(`SELECT id, title, author, genres, status, total_chapters, description FROM manga WHERE id = ?`, id).
-		Scan(&m.ID, &m.Title, &m.Author, &m.GenresString, &m.Status, &m.TotalChapters, &m.Description)
-	if err == sql.ErrNoRows {
+	m, err := a.manga.Get(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Manga not found"})
 		return
 	} else if err != nil {
@@ -209,74 +466,142 @@ func getMangaDetailHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
 	}
-	m.PostScan()
 	c.JSON(http.StatusOK, m)
 }
 
 // Search or list manga
-// @Summary      Search or list all manga
-// @Description  Search manga by title (?search= or ?title=) or return all if no query
+// @Summary      Search or list manga
+// @Description  List the catalog one page at a time with optional filters and sorting.
+// @Description  ?search= runs a ranked full-text search over title, author and description with
+// @Description  prefix matching and typo tolerance; each result then carries a highlighted match.
+// @Description  The response includes genre and status facet counts over all matching manga.
+// @Description  Pass next_cursor back as ?cursor= (with the same filters) to fetch the next page.
 // @Tags         Manga
 // @Produce      json
-// @Param        search query string false "Search term (title)"
-// @Param        title  query string false "Alternative search term"
+// @Param        search        query string false "Full-text search term"
+// @Param        title         query string false "Title (partial match)"
+// @Param        author        query string false "Author name (partial match)"
+// @Param        genre         query []string false "Genre; repeat or comma-separate to require several" collectionFormat(multi)
+// @Param        status        query []string false "Status; repeat or comma-separate to match any" collectionFormat(multi)
+// @Param        min_chapters  query int false "Minimum total chapters"
+// @Param        max_chapters  query int false "Maximum total chapters"
+// @Param        sort          query string false "Sort key (default relevance when searching, else title)" Enums(relevance, title, total_chapters, popularity)
+// @Param        order         query string false "Sort direction" Enums(asc, desc)
+// @Param        limit         query int false "Page size (default 20, max 100)"
+// @Param        cursor        query string false "Cursor from a previous page"
 // @Param        Authorization header string true "Bearer {token}"
-// @Success      200 {object} map[string]any "List of manga with count"
+// @Success      200 {object} models.MangaPage "One page of manga with facets"
+// @Failure      400 {object} map[string]string "Invalid query parameters"
 // @Failure      500 {object} map[string]string "Server error"
 // @Router       /manga [get]
-func getMangaHandler(c *gin.Context) {
-	query := c.Query("search")
-	if query == "" {
-		query = c.Query("title")
+func (a *API) getMangaHandler(c *gin.Context) {
+	query := models.MangaQuery{
+		Search:   strings.TrimSpace(c.Query("search")),
+		Title:    c.Query("title"),
+		Author:   c.Query("author"),
+		Genres:   splitQueryList(c.QueryArray("genre")),
+		Statuses: splitQueryList(c.QueryArray("status")),
+		Sort:     c.Query("sort"),
+		Cursor:   c.Query("cursor"),
+	}
+	if query.Sort == "" {
+		query.Sort = "title"
+		if query.Search != "" {
+			query.Sort = "relevance"
+		}
 	}
 
-	var mangaList []models.Manga
-	var rows *sql.Rows
-	var err error
+	if !repository.ValidMangaSort(query.Sort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of relevance, title, total_chapters, popularity"})
+		return
+	}
 
-	if query != "" {
-		searchTerm := "%" + query + "%"
-		rows, err = database.DB.Query(`
-			SELECT id, title, author, genres, status, total_chapters, description 
-			FROM manga 
-			WHERE LOWER(title) LIKE LOWER(?)
-			ORDER BY title
-		`, searchTerm)
-	} else {
-		rows, err = database.DB.Query(`
-			SELECT id, title, author, genres, status, total_chapters, description 
-			FROM manga 
-			ORDER BY title
-		`)
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
+		return
 	}
 
-	if err != nil {
+	var err error
+	if raw := c.Query("limit"); raw != "" {
+		if query.Limit, err = strconv.Atoi(raw); err != nil || query.Limit < 1 || query.Limit > repository.MaxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+	}
+	if query.MinChapters, err = intQuery(c, "min_chapters"); err != nil || query.MinChapters < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_chapters must be a non-negative integer"})
+		return
+	}
+	if query.MaxChapters, err = intQuery(c, "max_chapters"); err != nil || query.MaxChapters < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_chapters must be a non-negative integer"})
+		return
+	}
+	if query.MaxChapters > 0 && query.MaxChapters < query.MinChapters {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_chapters must not be less than min_chapters"})
+		return
+	}
+
+	page, err := a.manga.List(c.Request.Context(), query)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	} else if err != nil {
 		log.Printf("Database error fetching manga: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch manga"})
 		return
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var m models.Manga
-		err := rows.Scan(&m.ID, &m.Title, &m.Author, &m.GenresString, &m.Status, &m.TotalChapters, &m.Description)
-		if err != nil {
-			log.Printf("Scan error: %v", err)
-			continue
+	c.JSON(http.StatusOK, page)
+}
+
+// intQuery reads an optional integer query parameter, returning 0 when absent
+func intQuery(c *gin.Context, key string) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// splitQueryList flattens repeated and comma-separated query values
+func splitQueryList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
 		}
-		m.PostScan()
-		mangaList = append(mangaList, m)
 	}
+	return out
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"manga": mangaList,
-		"count": len(mangaList),
-	})
+// List genres
+// @Summary      List genres
+// @Description  Return every genre with the number of manga tagged with it
+// @Tags         Manga
+// @Produce      json
+// @Param        Authorization header string true "Bearer {token}"
+// @Success      200 {object} map[string]any "Genres with count"
+// @Failure      500 {object} map[string]string "Server error"
+// @Router       /genres [get]
+func (a *API) getGenresHandler(c *gin.Context) {
+	genres, err := a.manga.Genres(c.Request.Context())
+	if err != nil {
+		log.Printf("Database error fetching genres: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch genres"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"genres": genres, "count": len(genres)})
 }
 
-// Create new manga (admin only in real app)
+// Create new manga
 // @Summary      Create new manga
-// @Description  Add a new manga to the catalog
+// @Description  Add a new manga to the catalog. Requires the admin or moderator role.
 // @Tags         Manga
 // @Accept       json
 // @Produce      json
@@ -284,23 +609,29 @@ func getMangaHandler(c *gin.Context) {
 // @Param        Authorization header string true "Bearer {token}"
 // @Success      201 {object} models.Manga
 // @Failure      400 {object} map[string]string "Invalid input"
+// @Failure      403 {object} map[string]string "Insufficient permissions"
 // @Failure      500 {object} map[string]string "Server error"
 // @Router       /manga [post]
-func createMangaHandler(c *gin.Context) {
+func (a *API) createMangaHandler(c *gin.Context) {
 	var m models.Manga
 	if err := c.ShouldBindJSON(&m); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if strings.TrimSpace(m.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title must not be blank"})
+		return
+	}
+	if m.TotalChapters < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "total_chapters must not be negative"})
+		return
+	}
 
 	m.ID = auth.GenerateID("mng")
-	m.PreSave()
+	m.Genres = models.NormalizeGenres(m.Genres)
+	m.Match = nil
 
-	_, err := database.DB.Exec(
-		"INSERT INTO manga (id, title, author, genres, status, total_chapters, description) VALUES (?, ?, ?, ?, ?, ?, ?)",
-		m.ID, m.Title, m.Author, m.GenresString, m.Status, m.TotalChapters, m.Description,
-	)
-	if err != nil {
+	if err := a.manga.Create(actorContext(c), m); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create manga"})
 		return
 	}
@@ -308,6 +639,263 @@ func createMangaHandler(c *gin.Context) {
 	c.JSON(http.StatusCreated, m)
 }
 
+// Replace manga
+// @Summary      Update manga
+// @Description  Replace every editable field of a manga. Omitted genres clear the genre list.
+// @Description  Changed fields are recorded in the audit log. Requires the admin or moderator role.
+// @Tags         Manga
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Manga ID"
+// @Param        manga body UpdateMangaRequest true "Manga data"
+// @Param        Authorization header string true "Bearer {token}"
+// @Success      200 {object} models.Manga
+// @Failure      400 {object} map[string]string "Invalid input"
+// @Failure      403 {object} map[string]string "Insufficient permissions"
+// @Failure      404 {object} map[string]string "Manga not found"
+// @Failure      500 {object} map[string]string "Server error"
+// @Router       /manga/{id} [put]
+func (a *API) updateMangaHandler(c *gin.Context) {
+	var req UpdateMangaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title must not be blank"})
+		return
+	}
+
+	a.applyMangaPatch(c, models.MangaPatch{
+		Title:         &req.Title,
+		Author:        &req.Author,
+		Genres:        &req.Genres,
+		Status:        &req.Status,
+		TotalChapters: &req.TotalChapters,
+		Description:   &req.Description,
+	})
+}
+
+// Partially update manga
+// @Summary      Patch manga
+// @Description  Change only the fields present in the body. Changed fields are recorded in the audit log.
+// @Description  Requires the admin or moderator role.
+// @Tags         Manga
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Manga ID"
+// @Param        manga body models.MangaPatch true "Fields to change"
+// @Param        Authorization header string true "Bearer {token}"
+// @Success      200 {object} models.Manga
+// @Failure      400 {object} map[string]string "Invalid input"
+// @Failure      403 {object} map[string]string "Insufficient permissions"
+// @Failure      404 {object} map[string]string "Manga not found"
+// @Failure      500 {object} map[string]string "Server error"
+// @Router       /manga/{id} [patch]
+func (a *API) patchMangaHandler(c *gin.Context) {
+	var patch models.MangaPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if patch.Empty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No fields to update"})
+		return
+	}
+	if patch.Title != nil && strings.TrimSpace(*patch.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title must not be blank"})
+		return
+	}
+
+	a.applyMangaPatch(c, patch)
+}
+
+// applyMangaPatch saves patch to the manga named in the path and writes the result
+func (a *API) applyMangaPatch(c *gin.Context, patch models.MangaPatch) {
+	m, err := a.manga.Update(actorContext(c), c.Param("id"), patch)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Manga not found"})
+		return
+	} else if err != nil {
+		log.Printf("Database error updating manga: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update manga"})
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+// Delete manga
+// @Summary      Delete manga
+// @Description  Hide a manga from the catalog and search. User progress is kept and the manga
+// @Description  can be restored. Requires the admin or moderator role.
+// @Tags         Manga
+// @Produce      json
+// @Param        id path string true "Manga ID"
+// @Param        Authorization header string true "Bearer {token}"
+// @Success      200 {object} map[string]string "Manga deleted"
+// @Failure      403 {object} map[string]string "Insufficient permissions"
+// @Failure      404 {object} map[string]string "Manga not found"
+// @Failure      500 {object} map[string]string "Server error"
+// @Router       /manga/{id} [delete]
+func (a *API) deleteMangaHandler(c *gin.Context) {
+	err := a.manga.Delete(actorContext(c), c.Param("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Manga not found"})
+		return
+	} else if err != nil {
+		log.Printf("Database error deleting manga: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete manga"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Manga deleted"})
+}
+
+// Restore deleted manga
+// @Summary      Restore manga
+// @Description  Bring a deleted manga back into the catalog. Requires the admin or moderator role.
+// @Tags         Manga
+// @Produce      json
+// @Param        id path string true "Manga ID"
+// @Param        Authorization header string true "Bearer {token}"
+// @Success      200 {object} models.Manga
+// @Failure      403 {object} map[string]string "Insufficient permissions"
+// @Failure      404 {object} map[string]string "Manga not found"
+// @Failure      500 {object} map[string]string "Server error"
+// @Router       /manga/{id}/restore [post]
+func (a *API) restoreMangaHandler(c *gin.Context) {
+	m, err := a.manga.Restore(actorContext(c), c.Param("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Manga not found"})
+		return
+	} else if err != nil {
+		log.Printf("Database error restoring manga: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore manga"})
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+// Get manga audit trail
+// @Summary      Manga audit log
+// @Description  List who created, changed, deleted or restored a manga and which fields changed,
+// @Description  oldest first. Requires the admin or moderator role.
+// @Tags         Manga
+// @Produce      json
+// @Param        id path string true "Manga ID"
+// @Param        Authorization header string true "Bearer {token}"
+// @Success      200 {object} map[string]any "Audit entries with count"
+// @Failure      403 {object} map[string]string "Insufficient permissions"
+// @Failure      500 {object} map[string]string "Server error"
+// @Router       /manga/{id}/audit [get]
+func (a *API) getMangaAuditHandler(c *gin.Context) {
+	entries, err := a.audit.List(c.Request.Context(), models.AuditEntityManga, c.Param("id"))
+	if err != nil {
+		log.Printf("Database error fetching audit log: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "count": len(entries)})
+}
+
+// actorContext attributes catalog writes made by the request to the authenticated user
+func actorContext(c *gin.Context) context.Context {
+	return repository.WithActor(c.Request.Context(), c.GetString("user_id"))
+}
+
+// Change a user's role
+// @Summary      Set user role
+// @Description  Grant a user the user, moderator or admin role. Requires the admin role.
+// @Description  The change applies to access tokens issued after it, including on refresh.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "User ID"
+// @Param        Authorization header string true "Bearer {token}"
+// @Param        request body SetRoleRequest true "New role"
+// @Success      200 {object} map[string]string "Role updated"
+// @Failure      400 {object} map[string]string "Invalid input"
+// @Failure      403 {object} map[string]string "Insufficient permissions"
+// @Failure      404 {object} map[string]string "User not found"
+// @Failure      500 {object} map[string]string "Server error"
+// @Router       /admin/users/{id}/role [put]
+func (a *API) setUserRoleHandler(c *gin.Context) {
+	var req SetRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := a.users.SetRole(c.Request.Context(), c.Param("id"), req.Role)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated", "role": req.Role})
+}
+
+// List dead-lettered events
+// @Summary      List dead letters
+// @Description  Outbox events whose delivery to the real-time servers failed too often, most recent first,
+// @Description  with the last error and the servers that did receive them. Requires the admin role.
+// @Tags         Admin
+// @Produce      json
+// @Param        Authorization header string true "Bearer {token}"
+// @Param        limit query int false "Maximum number of events (default 50, max 500)"
+// @Success      200 {object} map[string]any "Dead letters with count"
+// @Failure      400 {object} map[string]string "Invalid input"
+// @Failure      403 {object} map[string]string "Insufficient permissions"
+// @Failure      500 {object} map[string]string "Server error"
+// @Router       /admin/outbox/dead-letters [get]
+func (a *API) getDeadLettersHandler(c *gin.Context) {
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+		limit = n
+	}
+
+	letters, err := a.outbox.DeadLetters(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dead letters"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"dead_letters": letters, "count": len(letters)})
+}
+
+// Requeue a dead-lettered event
+// @Summary      Requeue dead letter
+// @Description  Move a dead-lettered event back into the outbox for another round of delivery attempts.
+// @Description  Servers that already received it are skipped. Requires the admin role.
+// @Tags         Admin
+// @Produce      json
+// @Param        id path string true "Event ID"
+// @Param        Authorization header string true "Bearer {token}"
+// @Success      200 {object} map[string]string "Event requeued"
+// @Failure      403 {object} map[string]string "Insufficient permissions"
+// @Failure      404 {object} map[string]string "Dead letter not found"
+// @Failure      500 {object} map[string]string "Server error"
+// @Router       /admin/outbox/dead-letters/{id}/requeue [post]
+func (a *API) requeueDeadLetterHandler(c *gin.Context) {
+	err := a.outbox.Requeue(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dead letter not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to requeue event"})
+		return
+	}
+	a.events.Notify()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Event requeued"})
+}
+
 // Add manga to user library
 // @Summary      Add manga to library
 // @Description  Add a manga to the authenticated user's library with optional status
@@ -321,7 +909,7 @@ func createMangaHandler(c *gin.Context) {
 // @Failure      404 {object} map[string]string "Manga not found"
 // @Failure      500 {object} map[string]string "Server error"
 // @Router       /users/library [post]
-func addToLibraryHandler(c *gin.Context) {
+func (a *API) addToLibraryHandler(c *gin.Context) {
 	userID := c.GetString("user_id")
 
 	var req AddToLibraryRequest
@@ -330,9 +918,8 @@ func addToLibraryHandler(c *gin.Context) {
 		return
 	}
 
-	var exists int
-	err := database.DB.QueryRow("SELECT COUNT(*) FROM manga WHERE id = ?", req.MangaID).Scan(&exists)
-	if err != nil || exists == 0 {
+	exists, err := a.manga.Exists(c.Request.Context(), req.MangaID)
+	if err != nil || !exists {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Manga not found"})
 		return
 	}
@@ -342,12 +929,7 @@ func addToLibraryHandler(c *gin.Context) {
 		status = "reading"
 	}
 
-	_, err = database.DB.Exec(`
-		INSERT INTO user_progress (user_id, manga_id, current_chapter, status)
-		VALUES (?, ?, 0, ?)
-		ON CONFLICT(user_id, manga_id) DO UPDATE SET status = excluded.status
-	`, userID, req.MangaID, status)
-	if err != nil {
+	if err := a.progress.AddToLibrary(c.Request.Context(), userID, req.MangaID, status); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add to library"})
 		return
 	}
@@ -364,39 +946,21 @@ func addToLibraryHandler(c *gin.Context) {
 // @Success      200 {object} map[string]any "Library with count"
 // @Failure      500 {object} map[string]string "Server error"
 // @Router       /users/library [get]
-func getLibraryHandler(c *gin.Context) {
+func (a *API) getLibraryHandler(c *gin.Context) {
 	userID := c.GetString("user_id")
 
-	rows, err := database.DB.Query(`
-		SELECT m.id, m.title, up.current_chapter, up.status
-		FROM user_progress up
-		JOIN manga m ON up.manga_id = m.id
-		WHERE up.user_id = ?
-	`, userID)
+	library, err := a.progress.Library(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch library"})
 		return
 	}
-	defer rows.Close()
-
-	type item struct {
-		MangaID        string `json:"manga_id"`
-		Title          string `json:"title"`
-		CurrentChapter int    `json:"current_chapter"`
-		Status         string `json:"status"`
-	}
-	var library []item
-	for rows.Next() {
-		var i item
-		rows.Scan(&i.MangaID, &i.Title, &i.CurrentChapter, &i.Status)
-		library = append(library, i)
-	}
 	c.JSON(http.StatusOK, gin.H{"library": library, "count": len(library)})
 }
 
 // Update reading progress
 // @Summary      Update reading progress
-// @Description  Update current chapter and optional status. Triggers broadcast to TCP.
+// @Description  Update current chapter and optional status. The update is queued in the outbox with the
+// @Description  progress change and broadcast to the TCP, UDP and WebSocket servers.
 // @Tags         Progress
 // @Accept       json
 // @Produce      json
@@ -404,9 +968,10 @@ func getLibraryHandler(c *gin.Context) {
 // @Param        request body UpdateProgressRequest true "Progress update data"
 // @Success      200 {object} map[string]string "Progress updated and broadcasted"
 // @Failure      400 {object} map[string]string "Invalid input"
+// @Failure      404 {object} map[string]string "Manga not found"
 // @Failure      500 {object} map[string]string "Server error"
 // @Router       /users/progress [put]
-func updateProgressHandler(c *gin.Context) {
+func (a *API) updateProgressHandler(c *gin.Context) {
 	userID := c.GetString("user_id")
 	username := c.GetString("username")
 
@@ -416,71 +981,23 @@ func updateProgressHandler(c *gin.Context) {
 		return
 	}
 
-	_, err := database.DB.Exec(`
-		UPDATE user_progress 
-		SET current_chapter = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE user_id = ? AND manga_id = ?
-	`, req.CurrentChapter, userID, req.MangaID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update progress"})
-		return
-	}
-
-	if req.Status != "" {
-		_, err = database.DB.Exec(`
-			UPDATE user_progress SET status = ? WHERE user_id = ? AND manga_id = ?
-		`, req.Status, userID, req.MangaID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
-			return
-		}
-	}
-
-	var mangaTitle string
-	if err := database.DB.QueryRow("SELECT title FROM manga WHERE id = ?", req.MangaID).Scan(&mangaTitle); err != nil {
-		mangaTitle = "Unknown Manga"
-	}
-
-	if username == "" {
-		database.DB.QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&username)
-		username = "Unknown User"
-	}
-
-	payload := shared.ProgressUpdate{
+	_, err := a.updates.Record(c.Request.Context(), progress.Update{
 		UserID:         userID,
 		Username:       username,
 		MangaID:        req.MangaID,
-		MangaTitle:     mangaTitle,
 		CurrentChapter: req.CurrentChapter,
 		Status:         req.Status,
-		Timestamp:      time.Now().Unix(),
+	})
+	if errors.Is(err, progress.ErrMangaNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Manga not found"})
+		return
+	} else if errors.Is(err, progress.ErrInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update progress"})
+		return
 	}
 
-	jsonData, _ := json.Marshal(payload)
-
-	go func() {
-		resp, err := http.Post(tcpServerURL, "application/json", bytes.NewBuffer(jsonData))
-		if err != nil {
-			log.Printf("FAILED to broadcast to TCP server: %v", err)
-			return
-		}
-		defer resp.Body.Close()
-		if resp.StatusCode != http.StatusOK {
-			log.Printf("TCP server responded with status: %d", resp.StatusCode)
-		}
-	}()
-
-	go func() {
-		resp, err := http.Post(udpServerURL, "application/json", bytes.NewBuffer(jsonData))
-		if err != nil {
-			log.Printf("FAILED to broadcast to UDP server: %v", err)
-			return
-		}
-		defer resp.Body.Close()
-		if resp.StatusCode != http.StatusOK {
-			log.Printf("UDP server responded with status: %d", resp.StatusCode)
-		}
-	}()
-
 	c.JSON(http.StatusOK, gin.H{"message": "Progress updated and broadcasted"})
-}
+}
\ No newline at end of file
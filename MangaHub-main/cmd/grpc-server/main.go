@@ -1,46 +1,57 @@
 package main
 
 import (
-	"database/sql"
 	"log"
 	"net"
 
+	"mangahub/internal/config"
+	"mangahub/internal/database"
 	"mangahub/internal/grpc"
+	"mangahub/internal/servers"
 	pb "mangahub/proto"
 
 	grpcServer "google.golang.org/grpc"
-	_ "modernc.org/sqlite"
 )
 
 func main() {
-	// Open database 
-	db, err := sql.Open("sqlite", "./data/mangahub.db")
-	if err != nil {
-		log.Fatalf("Failed to open database: %v", err)
+	cfg := config.MustLoad()
+	if err := cfg.RequireJWTSecret(); err != nil {
+		log.Fatal(err)
+	}
+	// Progress events are signed for, or checked by, the real-time servers
+	if err := cfg.RequireEventsSecret(); err != nil {
+		log.Fatal(err)
 	}
-	defer db.Close()
 
-	// Test connection
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	// Open database and create tables, including the search index
+	db, err := database.Open(cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
 	}
+	defer db.Close()
+	store := db.Store()
 
 	log.Println("✓ Connected to database")
 
 	// Create TCP listener
-	lis, err := net.Listen("tcp", ":9092")
+	lis, err := net.Listen("tcp", cfg.GRPC.Addr)
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	// Create gRPC server
-	grpcSrv := grpcServer.NewServer()
+	// Create gRPC server; catalog writes are limited to admins and moderators
+	grpcSrv := grpcServer.NewServer(grpcServer.UnaryInterceptor(grpc.AuthInterceptor(store.Sessions)))
 
 	// Register manga service
-	mangaService := grpc.NewMangaServiceServer(db)
+	// Progress updates reach the real-time servers through the outbox. In
+	// inprocess mode the API server runs them, so they are posted to their
+	// internal URLs either way.
+	dispatcher := servers.NetworkDispatcher(cfg, store.Outbox)
+	defer dispatcher.Close()
+	mangaService := grpc.NewMangaServiceServer(store, dispatcher)
 	pb.RegisterMangaServiceServer(grpcSrv, mangaService)
 
-	log.Println("🚀 gRPC server listening on :9092")
+	log.Printf("🚀 gRPC server listening on %s", cfg.GRPC.Addr)
 	log.Println("📡 Manga service registered")
 
 	// Start serving
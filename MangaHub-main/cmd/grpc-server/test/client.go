@@ -4,17 +4,22 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"time"
 
+	"mangahub/internal/config"
 	pb "mangahub/proto"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
 func main() {
+	cfg := config.MustLoad()
+
 	// Connect to gRPC server
-	conn, err := grpc.Dial("localhost:9092",
+	conn, err := grpc.Dial(config.DialAddr(cfg.GRPC.Addr),
 		grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Fatalf("Failed to connect: %v", err)
@@ -73,10 +78,16 @@ func main() {
 		fmt.Println()
 	}
 
-	// Test 4: UpdateProgress
+	// UpdateProgress and CreateManga need a token.
+	// Set MANGAHUB_TOKEN to a token from /auth/login to call them authenticated.
+	authCtx := ctx
+	if token := os.Getenv("MANGAHUB_TOKEN"); token != "" {
+		authCtx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	}
+
+	// Test 4: UpdateProgress records the progress of the token's user
 	fmt.Println("=== Test 4: Update Progress ===")
-	progressResp, err := client.UpdateProgress(ctx, &pb.UpdateProgressRequest{
-		UserId:         "usr_test123",
+	progressResp, err := client.UpdateProgress(authCtx, &pb.UpdateProgressRequest{
 		MangaId:        "one-piece",
 		CurrentChapter: 1095,
 	})
@@ -95,5 +106,16 @@ func main() {
 		fmt.Printf(" Expected error: %v\n\n", err)
 	}
 
+	// Test 6: CreateManga is limited to admins and moderators
+	fmt.Println("=== Test 6: Create Manga (admin/moderator only) ===")
+	createResp, err := client.CreateManga(authCtx, &pb.CreateMangaRequest{
+		Manga: &pb.Manga{Title: "gRPC Test Manga", Author: "Tester", Genres: []string{"Action"}, TotalChapters: 1},
+	})
+	if err != nil {
+		fmt.Printf(" Rejected: %v\n\n", err)
+	} else {
+		fmt.Printf(" Created: %s\n\n", createResp.Manga.Id)
+	}
+
 	fmt.Println("✅ All tests completed!")
 }
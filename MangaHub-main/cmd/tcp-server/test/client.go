@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"log"
+	"os"
+	"time"
+
+	"mangahub/internal/config"
+	"mangahub/pkg/progressclient"
+)
+
+func main() {
+	cfg := config.MustLoad()
+
+	// Set MANGAHUB_TOKEN to a token from /auth/login
+	token := os.Getenv("MANGAHUB_TOKEN")
+	if token == "" {
+		log.Fatal("Set MANGAHUB_TOKEN to an access token from POST /auth/login")
+	}
+
+	// With TLS, trust the server's own certificate, as for a self-signed one
+	var tlsConfig *tls.Config
+	if cfg.TCP.TLSEnabled() {
+		pem, err := os.ReadFile(cfg.TCP.TLSCert)
+		if err != nil {
+			log.Fatal("Read certificate:", err)
+		}
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(pem)
+		tlsConfig = &tls.Config{RootCAs: pool}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Connect with protobuf framing
+	client, err := progressclient.Dial(ctx, config.DialAddr(cfg.TCP.Addr), token, tlsConfig)
+	if err != nil {
+		log.Fatal("Dial error:", err)
+	}
+	defer client.Close()
+	log.Printf("Connected as %s at seq %d", client.Username, client.Seq)
+
+	if err := client.Subscribe(ctx, progressclient.TopicSelf); err != nil {
+		log.Fatal("Subscribe error:", err)
+	}
+	topics, err := client.Subscriptions(ctx)
+	if err != nil {
+		log.Fatal("List error:", err)
+	}
+	log.Printf("Subscribed to %v. Waiting for updates...", topics)
+
+	for u := range client.Updates() {
+		log.Printf("Update #%d: %s → %s chapter %d (%s)", u.GetSeq(), u.GetUsername(), u.GetMangaTitle(), u.GetCurrentChapter(), u.GetStatus())
+	}
+	log.Println("Disconnected:", client.Err())
+}
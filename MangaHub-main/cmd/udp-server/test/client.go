@@ -1,55 +1,59 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net"
+	"os"
+	"strconv"
 	"time"
+
+	"mangahub/internal/config"
+	"mangahub/pkg/udpclient"
 )
 
 func main() {
-	// Connect to server
-	serverAddr, err := net.ResolveUDPAddr("udp", "localhost:9091")
-	if err != nil {
-		log.Fatal("Resolve error:", err)
+	cfg := config.MustLoad()
+
+	// Set MANGAHUB_TOKEN to a token from /auth/login
+	token := os.Getenv("MANGAHUB_TOKEN")
+	if token == "" {
+		log.Fatal("Set MANGAHUB_TOKEN to an access token from POST /auth/login")
 	}
+	// Topics to follow, e.g. self manga:one-piece
+	topics := os.Args[1:]
+	if len(topics) == 0 {
+		topics = []string{"self"}
+	}
+	// MANGAHUB_RELIABLE=1 asks for reliable delivery; MANGAHUB_LOSS=0.3
+	// drops 30% of datagrams to try it on a bad network
+	opts := udpclient.Options{Reliable: os.Getenv("MANGAHUB_RELIABLE") == "1"}
+	loss, _ := strconv.ParseFloat(os.Getenv("MANGAHUB_LOSS"), 64)
 
-	conn, err := net.DialUDP("udp", nil, serverAddr)
+	conn, err := net.Dial("udp", config.DialAddr(cfg.UDP.Addr))
 	if err != nil {
 		log.Fatal("Dial error:", err)
 	}
-	defer conn.Close()
-
-	// Periodically send PING to stay subscribed
-	go func() {
-		for {
-			_, err := conn.Write([]byte("PING\n"))
-			if err != nil {
-				log.Println("Failed to send PING:", err)
-				return
-			}
-			log.Println("Sent PING to server")
-			time.Sleep(20 * time.Second)
-		}
-	}()
-
-	log.Println("UDP client started. Waiting for notifications from server...")
-
-	// Buffer for incoming notifications
-	buffer := make([]byte, 2048)
+	var lossy *udpclient.LossyConn
+	if loss > 0 {
+		lossy = udpclient.NewLossyConn(conn, loss)
+		conn = lossy
+	}
 
-	for {
-		// Correct way to read from UDP: use ReadFromUDP
-		n, server, err := conn.ReadFromUDP(buffer)
-		if err != nil {
-			log.Println("Read error:", err)
-			continue
-		}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := udpclient.NewClient(ctx, conn, token, topics, opts)
+	if err != nil {
+		log.Fatal("Subscribe error:", err)
+	}
+	defer client.Close()
+	log.Printf("Subscribed to %v (reliable: %v). Waiting for notifications...", topics, opts.Reliable)
 
-		message := string(buffer[:n])
-		if message == "PONG\n" {
-			log.Println("Received PONG from server")
-		} else {
-			log.Printf("Notification from %s: %s", server, message)
+	for u := range client.Updates() {
+		log.Printf("Update: %s → %s chapter %d (%s)", u.Username, u.MangaTitle, u.CurrentChapter, u.Status)
+		if lossy != nil {
+			log.Printf("Stats: %+v, dropped %d datagrams", client.Stats(), lossy.Dropped())
 		}
 	}
+	log.Println("Disconnected:", client.Err())
 }
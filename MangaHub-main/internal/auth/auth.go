@@ -2,6 +2,8 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"time"
@@ -10,13 +12,26 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-// JWTSecret is used to sign tokens 
-var JWTSecret = []byte("your-secret-key-change-this-in-production")
+// JWTSecret is used to sign tokens. It is set from the configuration at
+// startup; tokens cannot be issued or validated while it is empty.
+var JWTSecret []byte
+
+// errNoSecret is returned when no signing key has been configured
+var errNoSecret = errors.New("auth: JWT secret is not configured")
+
+// Lifetimes of the two halves of a login. Access tokens are short-lived JWTs;
+// refresh tokens are opaque, stored hashed and replaced on every use.
+var (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
 
 // Claims represents JWT claims
 type Claims struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role,omitempty"`
+	SessionID string `json:"sid,omitempty"` // Login session the token belongs to
 	jwt.RegisteredClaims
 }
 
@@ -32,23 +47,32 @@ func CheckPassword(password, hash string) bool {
 	return err == nil
 }
 
-// GenerateToken creates a new JWT token
-func GenerateToken(userID, username string) (string, error) {
+// GenerateToken creates a new access token carrying the user's role and
+// session. It expires after AccessTokenTTL.
+func GenerateToken(userID, username, role, sessionID string) (string, error) {
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
+	if len(JWTSecret) == 0 {
+		return "", errNoSecret
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(JWTSecret)
 }
 
 // ValidateToken validates a JWT token and returns claims
 func ValidateToken(tokenString string) (*Claims, error) {
+	if len(JWTSecret) == 0 {
+		return nil, errNoSecret
+	}
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return JWTSecret, nil
 	})
@@ -64,6 +88,23 @@ func ValidateToken(tokenString string) (*Claims, error) {
 	return nil, errors.New("invalid token")
 }
 
+// NewRefreshToken returns a random refresh token and the hash to store for it
+func NewRefreshToken() (token, hash string, err error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(bytes)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken returns the stored form of a refresh token.
+// Refresh tokens are long and random, so a fast unsalted hash is enough.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // GenerateID generates a random unique ID
 func GenerateID(prefix string) string {
 	
@@ -1,14 +1,17 @@
 package auth
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-// returns a Gin middleware function for JWT authentication
-func Middleware() gin.HandlerFunc {
+// returns a Gin middleware function for JWT authentication.
+// Tokens whose session has been revoked are rejected.
+func Middleware(sessions Sessions) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization") // Read the Authorization header from the request
 		if authHeader == "" {
@@ -19,16 +22,40 @@ func Middleware() gin.HandlerFunc {
 
 		// Remove "Bearer " prefix to extract raw token
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		claims, err := ValidateToken(tokenString)
-		if err != nil {
+		claims, err := Authenticate(c.Request.Context(), sessions, tokenString)
+		if errors.Is(err, ErrInvalidToken) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
+		} else if errors.Is(err, ErrSessionRevoked) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			c.Abort()
+			return
+		} else if err != nil {
+			log.Printf("Failed to check session: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check session"})
+			c.Abort()
+			return
 		}
 
 		// Store user info for handlers to use
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
+		c.Set("session_id", claims.SessionID)
+		c.Next()
+	}
+}
+
+// RequireRole returns a Gin middleware that only lets through users holding
+// one of the given roles. It must run after Middleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasRole(c.GetString("role"), roles...) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
\ No newline at end of file
@@ -0,0 +1,34 @@
+package auth
+
+// Roles a user can hold. Tokens without a role belong to a plain user.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Roles lists every valid role
+var Roles = []string{RoleUser, RoleModerator, RoleAdmin}
+
+// ValidRole reports whether role is one of Roles
+func ValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole reports whether role is one of allowed. An empty role counts as RoleUser.
+func HasRole(role string, allowed ...string) bool {
+	if role == "" {
+		role = RoleUser
+	}
+	for _, r := range allowed {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,356 @@
+// Package config loads the settings shared by every MangaHub binary.
+//
+// Values come from, in increasing order of precedence: built-in defaults,
+// a JSON file named by -config or MANGAHUB_CONFIG, environment variables and
+// command-line flags. Load validates the result, so a binary either starts
+// with a usable configuration or exits with an error explaining what to fix.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"mangahub/internal/auth"
+	"mangahub/internal/events"
+)
+
+// PlaceholderJWTSecret is the signing key the project used to ship with.
+// It is public, so Validate refuses it.
+const PlaceholderJWTSecret = "your-secret-key-change-this-in-production"
+
+// MinJWTSecretLength is the shortest accepted HS256 signing key, in bytes
+const MinJWTSecretLength = 32
+
+// MinEventsSecretLength is the shortest accepted events signing key, in bytes
+const MinEventsSecretLength = 32
+
+// Config holds every setting of the MangaHub servers and tools.
+// Each binary reads the sections it needs.
+type Config struct {
+	Database  DatabaseConfig `json:"database"`
+	Auth      AuthConfig     `json:"auth"`
+	API       ServerConfig   `json:"api"`
+	GRPC      ServerConfig   `json:"grpc"`
+	TCP       RealtimeConfig `json:"tcp"`
+	UDP       UDPConfig      `json:"udp"`
+	WebSocket RealtimeConfig `json:"websocket"`
+	Events    EventsConfig   `json:"events"`
+}
+
+// DatabaseConfig selects the storage backend
+type DatabaseConfig struct {
+	// URL is a postgres:// URL or a SQLite file path
+	URL string `json:"url"`
+}
+
+// AuthConfig controls token signing and bootstrap administrators
+type AuthConfig struct {
+	JWTSecret       string   `json:"jwt_secret"`
+	AccessTokenTTL  Duration `json:"access_token_ttl"`
+	RefreshTokenTTL Duration `json:"refresh_token_ttl"`
+	// AdminIDs are IDs of existing users the API server makes admins
+	// when it starts
+	AdminIDs []string `json:"admin_ids"`
+}
+
+// ServerConfig is a server with a single listener
+type ServerConfig struct {
+	Addr string `json:"addr"`
+}
+
+// RealtimeConfig is a real-time server: the listener its clients connect to
+// and the internal HTTP endpoint the API server sends progress updates to.
+type RealtimeConfig struct {
+	Addr         string `json:"addr"`
+	InternalAddr string `json:"internal_addr"`
+	InternalURL  string `json:"internal_url"`
+	// TLSCert and TLSKey are PEM files. If set, the internal endpoint and,
+	// for TCP and WebSocket, the client listener serve TLS.
+	TLSCert string `json:"tls_cert"`
+	TLSKey  string `json:"tls_key"`
+}
+
+// UDPConfig is the UDP notification server. Its TLS settings only secure
+// the internal endpoint.
+type UDPConfig struct {
+	RealtimeConfig
+	// MTU is the largest datagram sent to subscribers, in bytes. Longer
+	// messages are split into fragments the client reassembles.
+	MTU int `json:"mtu"`
+	// CoalesceWindow is how long updates for a subscriber are collected
+	// into one datagram. Zero sends every update at once.
+	CoalesceWindow Duration `json:"coalesce_window"`
+	// SubscriptionLifetime is how long a subscription lasts unless the
+	// client renews it with another SUB, whatever its token's expiry
+	SubscriptionLifetime Duration `json:"subscription_lifetime"`
+}
+
+// Bounds of udp.mtu: the payload that fits the smallest IPv4 datagram every
+// host must accept, and the largest UDP payload
+const (
+	MinUDPMTU = 508
+	MaxUDPMTU = 65507
+)
+
+// MaxCoalesceWindow bounds udp.coalesce_window, since it delays every update
+const MaxCoalesceWindow = time.Second
+
+// MinSubscriptionLifetime bounds udp.subscription_lifetime, which clients
+// renew before it runs out
+const MinSubscriptionLifetime = time.Minute
+
+// TLSEnabled reports whether a certificate is configured
+func (r RealtimeConfig) TLSEnabled() bool {
+	return r.TLSCert != ""
+}
+
+// Event delivery modes, see EventsConfig
+const (
+	// EventsNetwork posts updates to the internal URLs of separately run servers
+	EventsNetwork = "network"
+	// EventsInProcess runs the TCP, UDP and WebSocket servers inside the API
+	// server and hands its updates straight to their hubs
+	EventsInProcess = "inprocess"
+)
+
+// EventsConfig controls how progress updates reach the real-time servers
+type EventsConfig struct {
+	Mode string `json:"mode"`
+	// MaxAttempts is how often delivery of an outbox event is tried
+	// before it is moved to the dead letters
+	MaxAttempts    int      `json:"max_attempts"`
+	InitialBackoff Duration `json:"initial_backoff"`
+	MaxBackoff     Duration `json:"max_backoff"`
+	// SigningSecret authenticates requests to the internal endpoints.
+	// Publishers and real-time servers must share it.
+	SigningSecret string `json:"signing_secret"`
+	// CAFile is a PEM bundle trusted, besides the system roots, when
+	// posting to https internal URLs
+	CAFile string `json:"ca_file"`
+}
+
+// Default returns the settings used when nothing overrides them.
+// It has no JWT or events secret; they must always be configured.
+func Default() *Config {
+	return &Config{
+		Database: DatabaseConfig{URL: "./data/mangahub.db"},
+		Auth: AuthConfig{
+			AccessTokenTTL:  Duration{15 * time.Minute},
+			RefreshTokenTTL: Duration{30 * 24 * time.Hour},
+		},
+		API:  ServerConfig{Addr: ":8080"},
+		GRPC: ServerConfig{Addr: ":9092"},
+		TCP: RealtimeConfig{
+			Addr:         ":9090",
+			InternalAddr: ":9091",
+			InternalURL:  "http://localhost:9091/internal/progress",
+		},
+		UDP: UDPConfig{
+			RealtimeConfig: RealtimeConfig{
+				Addr:         ":9091",
+				InternalAddr: ":9094",
+				InternalURL:  "http://localhost:9094/internal/progress",
+			},
+			MTU:                  1200,
+			CoalesceWindow:       Duration{20 * time.Millisecond},
+			SubscriptionLifetime: Duration{time.Hour},
+		},
+		WebSocket: RealtimeConfig{
+			Addr:         ":9093",
+			InternalAddr: ":9095",
+			InternalURL:  "http://localhost:9095/internal/progress",
+		},
+		Events: EventsConfig{
+			Mode:           EventsNetwork,
+			MaxAttempts:    10,
+			InitialBackoff: Duration{time.Second},
+			MaxBackoff:     Duration{time.Minute},
+		},
+	}
+}
+
+// Validate reports every invalid setting at once. Missing secrets are only
+// errors in RequireJWTSecret and RequireEventsSecret, since not every
+// binary needs them.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if strings.TrimSpace(c.Database.URL) == "" {
+		check(errors.New("database.url must not be empty"))
+	}
+
+	if c.Auth.JWTSecret != "" {
+		check(validateJWTSecret(c.Auth.JWTSecret))
+	}
+	if c.Auth.AccessTokenTTL.Duration <= 0 {
+		check(errors.New("auth.access_token_ttl must be positive"))
+	}
+	if c.Auth.RefreshTokenTTL.Duration <= c.Auth.AccessTokenTTL.Duration {
+		check(errors.New("auth.refresh_token_ttl must be longer than auth.access_token_ttl"))
+	}
+
+	check(validateAddr("api.addr", c.API.Addr))
+	check(validateAddr("grpc.addr", c.GRPC.Addr))
+	check(validateAddr("tcp.addr", c.TCP.Addr))
+	check(validateAddr("tcp.internal_addr", c.TCP.InternalAddr))
+	check(validateURL("tcp.internal_url", c.TCP.InternalURL))
+	check(validateAddr("udp.addr", c.UDP.Addr))
+	check(validateAddr("udp.internal_addr", c.UDP.InternalAddr))
+	check(validateURL("udp.internal_url", c.UDP.InternalURL))
+	check(validateAddr("websocket.addr", c.WebSocket.Addr))
+	check(validateAddr("websocket.internal_addr", c.WebSocket.InternalAddr))
+	check(validateURL("websocket.internal_url", c.WebSocket.InternalURL))
+	if c.UDP.MTU < MinUDPMTU || c.UDP.MTU > MaxUDPMTU {
+		check(fmt.Errorf("udp.mtu must be between %d and %d", MinUDPMTU, MaxUDPMTU))
+	}
+	if c.UDP.CoalesceWindow.Duration < 0 || c.UDP.CoalesceWindow.Duration > MaxCoalesceWindow {
+		check(fmt.Errorf("udp.coalesce_window must be between 0 and %v", MaxCoalesceWindow))
+	}
+	if c.UDP.SubscriptionLifetime.Duration < MinSubscriptionLifetime {
+		check(fmt.Errorf("udp.subscription_lifetime must be at least %v", MinSubscriptionLifetime))
+	}
+	check(validateTLS("tcp", c.TCP))
+	check(validateTLS("udp", c.UDP.RealtimeConfig))
+	check(validateTLS("websocket", c.WebSocket))
+
+	if c.Events.Mode != EventsNetwork && c.Events.Mode != EventsInProcess {
+		check(fmt.Errorf("events.mode must be %q or %q", EventsNetwork, EventsInProcess))
+	}
+	if c.Events.MaxAttempts < 1 {
+		check(errors.New("events.max_attempts must be at least 1"))
+	}
+	if c.Events.InitialBackoff.Duration <= 0 || c.Events.MaxBackoff.Duration < c.Events.InitialBackoff.Duration {
+		check(errors.New("events.initial_backoff must be positive and no longer than events.max_backoff"))
+	}
+	if c.Events.SigningSecret != "" {
+		check(c.validateEventsSecret())
+	}
+
+	return errors.Join(errs...)
+}
+
+// RequireJWTSecret checks that a signing key is configured and installs it,
+// with the token lifetimes, in the auth package. Binaries that issue or
+// verify tokens call it at startup.
+func (c *Config) RequireJWTSecret() error {
+	if c.Auth.JWTSecret == "" {
+		return errors.New("auth.jwt_secret is required: set MANGAHUB_JWT_SECRET or -jwt-secret (for example to the output of `openssl rand -hex 32`)")
+	}
+	if err := validateJWTSecret(c.Auth.JWTSecret); err != nil {
+		return err
+	}
+
+	auth.JWTSecret = []byte(c.Auth.JWTSecret)
+	auth.AccessTokenTTL = c.Auth.AccessTokenTTL.Duration
+	auth.RefreshTokenTTL = c.Auth.RefreshTokenTTL.Duration
+	return nil
+}
+
+// validateJWTSecret rejects signing keys that are public or easy to guess
+func validateJWTSecret(secret string) error {
+	if secret == PlaceholderJWTSecret {
+		return errors.New("auth.jwt_secret is the published placeholder; generate a random secret")
+	}
+	if len(secret) < MinJWTSecretLength {
+		return fmt.Errorf("auth.jwt_secret must be at least %d bytes long", MinJWTSecretLength)
+	}
+	return nil
+}
+
+// RequireEventsSecret checks that the events signing secret is configured and
+// installs it in the events package. Binaries that publish progress or serve
+// an internal endpoint call it at startup.
+func (c *Config) RequireEventsSecret() error {
+	if c.Events.SigningSecret == "" {
+		return errors.New("events.signing_secret is required: set MANGAHUB_EVENTS_SECRET or -events-secret to the same random value (for example `openssl rand -hex 32`) for every server")
+	}
+	if err := c.validateEventsSecret(); err != nil {
+		return err
+	}
+
+	events.SigningSecret = []byte(c.Events.SigningSecret)
+	return nil
+}
+
+// validateEventsSecret rejects short events secrets and reuse of the JWT secret
+func (c *Config) validateEventsSecret() error {
+	if len(c.Events.SigningSecret) < MinEventsSecretLength {
+		return fmt.Errorf("events.signing_secret must be at least %d bytes long", MinEventsSecretLength)
+	}
+	if c.Events.SigningSecret == c.Auth.JWTSecret {
+		return errors.New("events.signing_secret must differ from auth.jwt_secret")
+	}
+	return nil
+}
+
+// validateTLS checks that a certificate and its key are configured together
+func validateTLS(name string, r RealtimeConfig) error {
+	if (r.TLSCert == "") != (r.TLSKey == "") {
+		return fmt.Errorf("%s.tls_cert and %s.tls_key must be set together", name, name)
+	}
+	return nil
+}
+
+// validateAddr checks a host:port listen address
+func validateAddr(name, addr string) error {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf("%s %q is not a host:port address", name, addr)
+	}
+	return nil
+}
+
+// validateURL checks an absolute http or https URL
+func validateURL(name, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s %q is not an http(s) URL", name, raw)
+	}
+	return nil
+}
+
+// DialAddr turns a listen address into one a local client can dial,
+// replacing a missing or wildcard host with localhost
+func DialAddr(listenAddr string) string {
+	host, port, err := net.SplitHostPort(listenAddr)
+	if err != nil {
+		return listenAddr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// Duration is a time.Duration written as a string such as "15m" in JSON
+type Duration struct {
+	time.Duration
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON reads a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"15m\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting is one value that can be set from the environment or a flag
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+// settings lists every value that can be overridden without a file.
+// DATABASE_URL keeps the name it had before the config package existed.
+var settings = []setting{
+	{"database-url", "DATABASE_URL", "postgres:// URL or SQLite file path", str(func(c *Config) *string { return &c.Database.URL })},
+	{"jwt-secret", "MANGAHUB_JWT_SECRET", "HS256 signing key, at least 32 bytes", str(func(c *Config) *string { return &c.Auth.JWTSecret })},
+	{"access-token-ttl", "MANGAHUB_ACCESS_TOKEN_TTL", "access token lifetime", duration(func(c *Config) *Duration { return &c.Auth.AccessTokenTTL })},
+	{"refresh-token-ttl", "MANGAHUB_REFRESH_TOKEN_TTL", "refresh token lifetime", duration(func(c *Config) *Duration { return &c.Auth.RefreshTokenTTL })},
+	{"admin-ids", "MANGAHUB_ADMIN_IDS", "comma-separated IDs of existing users made admins at startup", list(func(c *Config) *[]string { return &c.Auth.AdminIDs })},
+	{"api-addr", "MANGAHUB_API_ADDR", "REST API listen address", str(func(c *Config) *string { return &c.API.Addr })},
+	{"grpc-addr", "MANGAHUB_GRPC_ADDR", "gRPC listen address", str(func(c *Config) *string { return &c.GRPC.Addr })},
+	{"tcp-addr", "MANGAHUB_TCP_ADDR", "TCP progress stream listen address", str(func(c *Config) *string { return &c.TCP.Addr })},
+	{"tcp-internal-addr", "MANGAHUB_TCP_INTERNAL_ADDR", "TCP server internal HTTP listen address", str(func(c *Config) *string { return &c.TCP.InternalAddr })},
+	{"tcp-internal-url", "MANGAHUB_TCP_INTERNAL_URL", "URL the API server posts TCP broadcasts to", str(func(c *Config) *string { return &c.TCP.InternalURL })},
+	{"tcp-tls-cert", "MANGAHUB_TCP_TLS_CERT", "PEM certificate for the TCP listener and internal HTTP", str(func(c *Config) *string { return &c.TCP.TLSCert })},
+	{"tcp-tls-key", "MANGAHUB_TCP_TLS_KEY", "PEM key of -tcp-tls-cert", str(func(c *Config) *string { return &c.TCP.TLSKey })},
+	{"udp-addr", "MANGAHUB_UDP_ADDR", "UDP notification listen address", str(func(c *Config) *string { return &c.UDP.Addr })},
+	{"udp-internal-addr", "MANGAHUB_UDP_INTERNAL_ADDR", "UDP server internal HTTP listen address", str(func(c *Config) *string { return &c.UDP.InternalAddr })},
+	{"udp-internal-url", "MANGAHUB_UDP_INTERNAL_URL", "URL the API server posts UDP broadcasts to", str(func(c *Config) *string { return &c.UDP.InternalURL })},
+	{"udp-tls-cert", "MANGAHUB_UDP_TLS_CERT", "PEM certificate for the UDP server internal HTTP", str(func(c *Config) *string { return &c.UDP.TLSCert })},
+	{"udp-tls-key", "MANGAHUB_UDP_TLS_KEY", "PEM key of -udp-tls-cert", str(func(c *Config) *string { return &c.UDP.TLSKey })},
+	{"udp-mtu", "MANGAHUB_UDP_MTU", "largest datagram sent to UDP subscribers; longer messages are fragmented", integer(func(c *Config) *int { return &c.UDP.MTU })},
+	{"udp-coalesce-window", "MANGAHUB_UDP_COALESCE_WINDOW", "how long UDP updates are collected into one datagram, 0 to send each at once", duration(func(c *Config) *Duration { return &c.UDP.CoalesceWindow })},
+	{"udp-subscription-lifetime", "MANGAHUB_UDP_SUBSCRIPTION_LIFETIME", "how long a UDP subscription lasts unless renewed by another SUB", duration(func(c *Config) *Duration { return &c.UDP.SubscriptionLifetime })},
+	{"websocket-addr", "MANGAHUB_WEBSOCKET_ADDR", "WebSocket chat listen address", str(func(c *Config) *string { return &c.WebSocket.Addr })},
+	{"websocket-internal-addr", "MANGAHUB_WEBSOCKET_INTERNAL_ADDR", "WebSocket server internal HTTP listen address", str(func(c *Config) *string { return &c.WebSocket.InternalAddr })},
+	{"websocket-internal-url", "MANGAHUB_WEBSOCKET_INTERNAL_URL", "URL the API server posts WebSocket broadcasts to", str(func(c *Config) *string { return &c.WebSocket.InternalURL })},
+	{"websocket-tls-cert", "MANGAHUB_WEBSOCKET_TLS_CERT", "PEM certificate for the WebSocket listener and internal HTTP", str(func(c *Config) *string { return &c.WebSocket.TLSCert })},
+	{"websocket-tls-key", "MANGAHUB_WEBSOCKET_TLS_KEY", "PEM key of -websocket-tls-cert", str(func(c *Config) *string { return &c.WebSocket.TLSKey })},
+	{"events-mode", "MANGAHUB_EVENTS_MODE", "progress delivery: network or inprocess", str(func(c *Config) *string { return &c.Events.Mode })},
+	{"events-max-attempts", "MANGAHUB_EVENTS_MAX_ATTEMPTS", "delivery attempts per progress update before it is dead-lettered", integer(func(c *Config) *int { return &c.Events.MaxAttempts })},
+	{"events-secret", "MANGAHUB_EVENTS_SECRET", "HMAC key signing internal progress requests, at least 32 bytes", str(func(c *Config) *string { return &c.Events.SigningSecret })},
+	{"events-ca-file", "MANGAHUB_EVENTS_CA_FILE", "PEM CA bundle trusted for https internal URLs", str(func(c *Config) *string { return &c.Events.CAFile })},
+}
+
+// Load builds the configuration of the program called name from defaults,
+// the config file, the environment and args (usually os.Args[1:]), then
+// validates it. -h prints every flag with its environment variable.
+func Load(name string, args []string) (*Config, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	path := fs.String("config", os.Getenv("MANGAHUB_CONFIG"), "JSON config file (env MANGAHUB_CONFIG)")
+	for _, s := range settings {
+		fs.String(s.flag, "", fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	if *path != "" {
+		if err := cfg.readFile(*path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if value := os.Getenv(s.env); value != "" {
+			if err := s.set(cfg, value); err != nil {
+				return nil, fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+
+	byFlag := map[string]setting{}
+	for _, s := range settings {
+		byFlag[s.flag] = s
+	}
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		if s, ok := byFlag[f.Name]; ok && flagErr == nil {
+			if err := s.set(cfg, f.Value.String()); err != nil {
+				flagErr = fmt.Errorf("-%s: %w", f.Name, err)
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// MustLoad loads the configuration of the running program from os.Args and
+// exits if it is invalid. -h prints the flags and exits successfully.
+func MustLoad() *Config {
+	cfg, err := Load(filepath.Base(os.Args[0]), os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		log.Fatal(err)
+	}
+	return cfg
+}
+
+// readFile merges a JSON config file over c. Unknown keys are errors so
+// typos do not silently fall back to defaults.
+func (c *Config) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return nil
+}
+
+func str(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func duration(field func(*Config) *Duration) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field(c).Duration = d
+		return nil
+	}
+}
+
+func integer(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func list(field func(*Config) *[]string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		var out []string
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+		*field(c) = out
+		return nil
+	}
+}
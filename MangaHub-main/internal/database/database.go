@@ -1,90 +1,95 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"mangahub/internal/repository"
+	"mangahub/internal/repository/postgres"
+	"mangahub/internal/repository/sqlite"
 	"mangahub/pkg/models"
 
+	_ "github.com/lib/pq"
 	_ "modernc.org/sqlite"
 )
 
-// DB is the global database instance
-var DB *sql.DB
+// DB is an open database together with its dialect
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+}
 
-// Initialize sets up the database connection and creates tables
-// This function must be called before any database operations.
-// It sets the global database.DB variable.
-func Initialize(dbPath string) error {
-	// Create directory if not exists
-	if err := os.MkdirAll("./data", os.ModePerm); err != nil {
-		return err
-	}
+// Open connects to the database named by dsn and migrates the schema to the
+// latest version. A postgres:// URL selects PostgreSQL, anything else is a
+// SQLite file path. The caller owns the returned handle and must close it.
+func Open(dsn string) (*DB, error) {
+	dialect := DialectOf(dsn)
 
+	var sqlDB *sql.DB
 	var err error
-	DB, err = sql.Open("sqlite", dbPath)
+	if dialect == Postgres {
+		sqlDB, err = sql.Open("postgres", dsn)
+	} else {
+		// Create the directory of the database file if it does not exist
+		if err := os.MkdirAll(sqliteDir(dsn), os.ModePerm); err != nil {
+			return nil, err
+		}
+		sqlDB, err = sql.Open("sqlite", sqliteDSN(dsn))
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
+	db := &DB{DB: sqlDB, Dialect: dialect}
 
 	// Test connection
-	if err = DB.Ping(); err != nil {
-		return err
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
 	}
 
-	log.Println("Database connected successfully!")
+	log.Printf("Database connected successfully! (%s)", dialect)
 
-	// Create tables
-	if err = createTables(); err != nil {
-		return err
+	// Apply pending schema migrations
+	if err = Migrate(db.DB, dialect); err != nil {
+		db.Close()
+		return nil, err
 	}
 
-	return nil
+	return db, nil
 }
 
-// createTables creates all required database tables
-func createTables() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS users (
-			id TEXT PRIMARY KEY,
-			username TEXT UNIQUE NOT NULL,
-			email TEXT UNIQUE NOT NULL,
-			password_hash TEXT NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE TABLE IF NOT EXISTS manga (
-			id TEXT PRIMARY KEY,
-			title TEXT NOT NULL,
-			author TEXT,
-			genres TEXT,
-			status TEXT,
-			total_chapters INTEGER,
-			description TEXT
-		)`,
-		`CREATE TABLE IF NOT EXISTS user_progress (
-			user_id TEXT,
-			manga_id TEXT,
-			current_chapter INTEGER DEFAULT 0,
-			status TEXT DEFAULT 'reading',
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			PRIMARY KEY (user_id, manga_id),
-			FOREIGN KEY (user_id) REFERENCES users(id),
-			FOREIGN KEY (manga_id) REFERENCES manga(id)
-		)`,
+// Store returns the repositories for the database's dialect
+func (db *DB) Store() *repository.Store {
+	if db.Dialect == Postgres {
+		return postgres.NewStore(db.DB)
 	}
+	return sqlite.NewStore(db.DB)
+}
 
-	for _, query := range queries {
-		if _, err := DB.Exec(query); err != nil {
-			return err
-		}
+// sqliteDSN adds connection options to a database file path. Transactions
+// take the write lock up front and wait for it, so concurrent writers
+// (e.g. two servers migrating at startup) queue instead of failing.
+func sqliteDSN(dbPath string) string {
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
 	}
+	return dbPath + sep + "_pragma=busy_timeout(5000)&_txlock=immediate"
+}
 
-	log.Println("Database tables created successfully!")
-	return nil
+// sqliteDir returns the directory of a SQLite database file path, which
+// may be a file: URI with query options
+func sqliteDir(dsn string) string {
+	path, _, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	return filepath.Dir(path)
 }
 
-// Seed manga table with data
-func SeedManga() error {
+// Seed adds the starter catalog, skipping manga that already exist
+func Seed(ctx context.Context, manga repository.MangaRepository) error {
 	mangas := []models.Manga{
 		{ID:"one-piece", Title:"One Piece", Author:"Eiichiro Oda", Genres:[]string{"Action", "Adventure", "Shounen"}, Status:"ongoing", TotalChapters:1100, Description:"Pirate adventure"},
 		{ID:"demon-slayer", Title:"Demon Slayer: Kimetsu no Yaiba", Author:"Koyoharu Gotouge", Genres:[]string{"Action","Supernatural","Adventure"}, Status:"completed", TotalChapters:205, Description:"Tanjiro fights demons after his family is slaughtered."},
@@ -138,23 +143,10 @@ func SeedManga() error {
 	}
 
 	for _, m := range mangas {
-		m.PreSave()
-		_, err := DB.Exec(
-			"INSERT OR IGNORE INTO manga (id, title, author, genres, status, total_chapters, description) VALUES (?, ?, ?, ?, ?, ?, ?)",
-			m.ID, m.Title, m.Author, m.GenresString, m.Status, m.TotalChapters, m.Description,
-		)
-		if err != nil {
+		if err := manga.Create(ctx, m); err != nil && !errors.Is(err, repository.ErrDuplicate) {
 			return err
 		}
 	}
 	log.Println("Seeded manga entries")
 	return nil
 }
-
-// Close closes the database connection
-func Close() error {
-	if DB != nil {
-		return DB.Close()
-	}
-	return nil
-}
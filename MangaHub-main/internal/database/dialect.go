@@ -0,0 +1,62 @@
+package database
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// Dialect identifies the SQL database behind a DSN
+type Dialect string
+
+// Supported dialects
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// DialectOf picks the dialect for a DSN. postgres:// and postgresql:// URLs
+// select PostgreSQL; anything else is treated as a SQLite file path.
+func DialectOf(dsn string) Dialect {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return Postgres
+	}
+	return SQLite
+}
+
+// Rebind rewrites ? placeholders into the dialect's parameter syntax
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// migrations returns the schema history for the dialect
+func (d Dialect) migrations() []Migration {
+	if d == Postgres {
+		return postgresMigrations
+	}
+	return migrations
+}
+
+// lockMigrations serializes migrators on the same database. SQLite
+// transactions already take the write lock up front (see sqliteDSN);
+// PostgreSQL uses a transaction-scoped advisory lock.
+func (d Dialect) lockMigrations(tx *sql.Tx) error {
+	if d != Postgres {
+		return nil
+	}
+	_, err := tx.Exec("SELECT pg_advisory_xact_lock(4242001)")
+	return err
+}
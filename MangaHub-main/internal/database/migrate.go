@@ -0,0 +1,132 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// Migration is one forward-only schema change. Up runs inside a transaction
+// together with the schema_migrations bookkeeping, so a migration is either
+// fully applied and recorded or not applied at all.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+}
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer
+// binary than this one, so its schema may not match what this code expects
+type ErrSchemaTooNew struct {
+	Current int
+	Known   int
+}
+
+func (e *ErrSchemaTooNew) Error() string {
+	return fmt.Sprintf("database schema version %d is newer than the latest known version %d; upgrade this binary", e.Current, e.Known)
+}
+
+// LatestVersion returns the highest migration version this binary knows about.
+// Every dialect's history ends at the same version.
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// execAll runs each statement in order, stopping at the first error
+func execAll(tx *sql.Tx, queries ...string) error {
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SchemaVersion returns the highest migration version applied to db, or 0
+func SchemaVersion(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
+
+// Migrate brings db up to the latest schema version by applying every
+// migration that has not been recorded in schema_migrations yet, in order.
+// It refuses to touch a database whose schema is newer than this binary.
+func Migrate(db *sql.DB, dialect Dialect) error {
+	if err := createMigrationsTable(db, dialect); err != nil {
+		return err
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if current > LatestVersion() {
+		return &ErrSchemaTooNew{Current: current, Known: LatestVersion()}
+	}
+
+	for _, m := range dialect.migrations() {
+		applied, err := applyMigration(db, dialect, m)
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		if applied {
+			log.Printf("Applied migration %d: %s", m.Version, m.Name)
+		}
+	}
+
+	log.Printf("Database schema at version %d", LatestVersion())
+	return nil
+}
+
+// createMigrationsTable creates the schema_migrations bookkeeping table
+func createMigrationsTable(db *sql.DB, dialect Dialect) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := dialect.lockMigrations(tx); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// applyMigration runs m unless it is already recorded. The check happens
+// inside the transaction so two processes starting at once cannot both apply it.
+func applyMigration(db *sql.DB, dialect Dialect, m Migration) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err := dialect.lockMigrations(tx); err != nil {
+		return false, err
+	}
+
+	var done bool
+	if err := tx.QueryRow(dialect.Rebind("SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE version = ?)"), m.Version).Scan(&done); err != nil {
+		return false, err
+	}
+	if done {
+		return false, nil
+	}
+
+	if err := m.Up(tx); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(dialect.Rebind("INSERT INTO schema_migrations (version, name) VALUES (?, ?)"), m.Version, m.Name); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
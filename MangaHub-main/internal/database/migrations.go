@@ -0,0 +1,244 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// migrations is the ordered schema history. Append new migrations to the end
+// with the next version number and never edit one that has shipped.
+//
+// The early migrations use IF NOT EXISTS and check for legacy columns because
+// databases created before versioning already contain part of this schema.
+var migrations = []Migration{
+	{Version: 1, Name: "create users, manga and user_progress", Up: func(tx *sql.Tx) error {
+		return execAll(tx,
+			`CREATE TABLE IF NOT EXISTS users (
+				id TEXT PRIMARY KEY,
+				username TEXT UNIQUE NOT NULL,
+				email TEXT UNIQUE NOT NULL,
+				password_hash TEXT NOT NULL,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE TABLE IF NOT EXISTS manga (
+				id TEXT PRIMARY KEY,
+				title TEXT NOT NULL,
+				author TEXT,
+				genres TEXT,
+				status TEXT,
+				total_chapters INTEGER,
+				description TEXT
+			)`,
+			`CREATE TABLE IF NOT EXISTS user_progress (
+				user_id TEXT,
+				manga_id TEXT,
+				current_chapter INTEGER DEFAULT 0,
+				status TEXT DEFAULT 'reading',
+				updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (user_id, manga_id),
+				FOREIGN KEY (user_id) REFERENCES users(id),
+				FOREIGN KEY (manga_id) REFERENCES manga(id)
+			)`,
+		)
+	}},
+
+	// The index uses manga as external content keyed by rowid, so it stores
+	// only the inverted index and reads column values from manga. It is built
+	// once from the existing rows; triggers keep it in sync with every write.
+	{Version: 2, Name: "full-text search index over manga", Up: func(tx *sql.Tx) error {
+		return execAll(tx,
+			`CREATE VIRTUAL TABLE IF NOT EXISTS manga_fts USING fts5(
+				title, author, description,
+				content='manga', content_rowid='rowid',
+				tokenize='unicode61 remove_diacritics 2'
+			)`,
+			`CREATE VIRTUAL TABLE IF NOT EXISTS manga_fts_vocab USING fts5vocab(manga_fts, 'row')`,
+			`CREATE TRIGGER IF NOT EXISTS manga_fts_ai AFTER INSERT ON manga BEGIN
+				INSERT INTO manga_fts(rowid, title, author, description)
+				VALUES (new.rowid, new.title, new.author, new.description);
+			END`,
+			`CREATE TRIGGER IF NOT EXISTS manga_fts_ad AFTER DELETE ON manga BEGIN
+				INSERT INTO manga_fts(manga_fts, rowid, title, author, description)
+				VALUES ('delete', old.rowid, old.title, old.author, old.description);
+			END`,
+			`CREATE TRIGGER IF NOT EXISTS manga_fts_au AFTER UPDATE ON manga BEGIN
+				INSERT INTO manga_fts(manga_fts, rowid, title, author, description)
+				VALUES ('delete', old.rowid, old.title, old.author, old.description);
+				INSERT INTO manga_fts(rowid, title, author, description)
+				VALUES (new.rowid, new.title, new.author, new.description);
+			END`,
+			`INSERT INTO manga_fts(manga_fts) VALUES ('rebuild')`,
+		)
+	}},
+
+	// Genre names are unique regardless of case, so "action" and "Action" are
+	// one genre. Existing comma-joined manga.genres values are converted and
+	// the old column is dropped.
+	{Version: 3, Name: "normalize genres into genres and manga_genres", Up: func(tx *sql.Tx) error {
+		err := execAll(tx,
+			`CREATE TABLE IF NOT EXISTS genres (
+				id INTEGER PRIMARY KEY,
+				name TEXT UNIQUE NOT NULL COLLATE NOCASE
+			)`,
+			`CREATE TABLE IF NOT EXISTS manga_genres (
+				manga_id TEXT NOT NULL,
+				genre_id INTEGER NOT NULL,
+				position INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (manga_id, genre_id),
+				FOREIGN KEY (manga_id) REFERENCES manga(id) ON DELETE CASCADE,
+				FOREIGN KEY (genre_id) REFERENCES genres(id)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_manga_genres_genre ON manga_genres(genre_id)`,
+		)
+		if err != nil {
+			return err
+		}
+		return convertLegacyGenres(tx)
+	}},
+
+	{Version: 4, Name: "index user_progress by manga", Up: func(tx *sql.Tx) error {
+		return execAll(tx, `CREATE INDEX IF NOT EXISTS idx_user_progress_manga ON user_progress(manga_id)`)
+	}},
+
+	{Version: 5, Name: "add role to users", Up: func(tx *sql.Tx) error {
+		return execAll(tx, `ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user'`)
+	}},
+
+	// Deleted manga keep their row, genres and user progress so they can be
+	// restored. changes holds a JSON object of {field: {old, new}}.
+	{Version: 6, Name: "soft delete manga and audit log", Up: func(tx *sql.Tx) error {
+		return execAll(tx,
+			`ALTER TABLE manga ADD COLUMN deleted_at TIMESTAMP`,
+			`CREATE TABLE IF NOT EXISTS audit_log (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				entity TEXT NOT NULL,
+				entity_id TEXT NOT NULL,
+				action TEXT NOT NULL,
+				actor_id TEXT NOT NULL,
+				changes TEXT,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity, entity_id)`,
+		)
+	}},
+
+	// Sessions back refresh tokens. refresh_hash is the SHA-256 of the current
+	// token and previous_hash that of the one it replaced, to detect reuse.
+	{Version: 7, Name: "login sessions", Up: func(tx *sql.Tx) error {
+		return execAll(tx,
+			`CREATE TABLE IF NOT EXISTS sessions (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				refresh_hash TEXT UNIQUE NOT NULL,
+				previous_hash TEXT,
+				user_agent TEXT,
+				ip_address TEXT,
+				created_at TIMESTAMP NOT NULL,
+				last_used_at TIMESTAMP NOT NULL,
+				expires_at TIMESTAMP NOT NULL,
+				revoked_at TIMESTAMP,
+				FOREIGN KEY (user_id) REFERENCES users(id)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_sessions_previous_hash ON sessions(previous_hash)`,
+		)
+	}},
+	// The transactional outbox: events are inserted with the change they
+	// describe and deleted once delivered. delivered_to is a comma-separated
+	// list of the subscribers that already have an event.
+	{Version: 8, Name: "transactional outbox", Up: func(tx *sql.Tx) error {
+		return execAll(tx,
+			`CREATE TABLE IF NOT EXISTS outbox (
+				id TEXT PRIMARY KEY,
+				topic TEXT NOT NULL,
+				payload TEXT NOT NULL,
+				attempts INTEGER NOT NULL DEFAULT 0,
+				delivered_to TEXT NOT NULL DEFAULT '',
+				last_error TEXT,
+				next_attempt_at TIMESTAMP NOT NULL,
+				created_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_outbox_next_attempt ON outbox(next_attempt_at)`,
+			`CREATE TABLE IF NOT EXISTS outbox_dead_letters (
+				id TEXT PRIMARY KEY,
+				topic TEXT NOT NULL,
+				payload TEXT NOT NULL,
+				attempts INTEGER NOT NULL,
+				delivered_to TEXT NOT NULL DEFAULT '',
+				last_error TEXT,
+				created_at TIMESTAMP NOT NULL,
+				failed_at TIMESTAMP NOT NULL
+			)`,
+		)
+	}},
+}
+
+// convertLegacyGenres moves genres from the comma-joined manga.genres column
+// into manga_genres, then drops the column. It does nothing when the column
+// is already gone.
+func convertLegacyGenres(tx *sql.Tx) error {
+	var legacy bool
+	err := tx.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info('manga') WHERE name = 'genres'").Scan(&legacy)
+	if err != nil || !legacy {
+		return err
+	}
+
+	rows, err := tx.Query("SELECT id, COALESCE(genres, '') FROM manga ORDER BY rowid")
+	if err != nil {
+		return err
+	}
+	type legacyRow struct {
+		id     string
+		genres []string
+	}
+	var legacyRows []legacyRow
+	for rows.Next() {
+		var id, genres string
+		if err := rows.Scan(&id, &genres); err != nil {
+			rows.Close()
+			return err
+		}
+		legacyRows = append(legacyRows, legacyRow{id, strings.Split(genres, ",")})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, r := range legacyRows {
+		if err := linkLegacyGenres(tx, r.id, r.genres); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("ALTER TABLE manga DROP COLUMN genres")
+	return err
+}
+
+// linkLegacyGenres links a manga to its genres as of schema version 3.
+// It is kept separate from the repositories so this migration stays fixed
+// as the live schema evolves.
+func linkLegacyGenres(tx *sql.Tx, mangaID string, genres []string) error {
+	position := 0
+	seen := map[string]bool{}
+	for _, name := range genres {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+
+		if _, err := tx.Exec("INSERT INTO genres (name) SELECT ? WHERE NOT EXISTS (SELECT 1 FROM genres WHERE name = ?)", name, name); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`
+			INSERT OR IGNORE INTO manga_genres (manga_id, genre_id, position)
+			SELECT ?, id, ? FROM genres WHERE name = ?
+		`, mangaID, position, name)
+		if err != nil {
+			return err
+		}
+		position++
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package database
+
+import "database/sql"
+
+// postgresMigrations is the PostgreSQL counterpart of migrations. It must keep
+// the same version numbers and names so both dialects report the same schema
+// version; only the SQL differs. PostgreSQL databases never held the legacy
+// comma-joined genres column, so there is nothing to convert.
+var postgresMigrations = []Migration{
+	{Version: 1, Name: "create users, manga and user_progress", Up: func(tx *sql.Tx) error {
+		return execAll(tx,
+			`CREATE TABLE IF NOT EXISTS users (
+				id TEXT PRIMARY KEY,
+				username TEXT UNIQUE NOT NULL,
+				email TEXT UNIQUE NOT NULL,
+				password_hash TEXT NOT NULL,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE TABLE IF NOT EXISTS manga (
+				id TEXT PRIMARY KEY,
+				title TEXT NOT NULL,
+				author TEXT,
+				status TEXT,
+				total_chapters INTEGER,
+				description TEXT
+			)`,
+			// No foreign keys: SQLite does not enforce the ones it declares, and
+			// gRPC clients may record progress for users this service has not seen
+			`CREATE TABLE IF NOT EXISTS user_progress (
+				user_id TEXT,
+				manga_id TEXT,
+				current_chapter INTEGER DEFAULT 0,
+				status TEXT DEFAULT 'reading',
+				updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (user_id, manga_id)
+			)`,
+		)
+	}},
+
+	// The search vector is a generated column weighted A (title), B (author)
+	// and C (description). The simple configuration does no stemming, matching
+	// the SQLite unicode61 tokenizer.
+	{Version: 2, Name: "full-text search index over manga", Up: func(tx *sql.Tx) error {
+		return execAll(tx,
+			`ALTER TABLE manga ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+				setweight(to_tsvector('simple', COALESCE(title, '')), 'A') ||
+				setweight(to_tsvector('simple', COALESCE(author, '')), 'B') ||
+				setweight(to_tsvector('simple', COALESCE(description, '')), 'C')
+			) STORED`,
+			`CREATE INDEX IF NOT EXISTS idx_manga_search ON manga USING GIN (search_vector)`,
+		)
+	}},
+
+	{Version: 3, Name: "normalize genres into genres and manga_genres", Up: func(tx *sql.Tx) error {
+		return execAll(tx,
+			`CREATE TABLE IF NOT EXISTS genres (
+				id SERIAL PRIMARY KEY,
+				name TEXT NOT NULL
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_genres_name ON genres (LOWER(name))`,
+			`CREATE TABLE IF NOT EXISTS manga_genres (
+				manga_id TEXT NOT NULL REFERENCES manga(id) ON DELETE CASCADE,
+				genre_id INTEGER NOT NULL REFERENCES genres(id),
+				position INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (manga_id, genre_id)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_manga_genres_genre ON manga_genres(genre_id)`,
+		)
+	}},
+
+	{Version: 4, Name: "index user_progress by manga", Up: func(tx *sql.Tx) error {
+		return execAll(tx, `CREATE INDEX IF NOT EXISTS idx_user_progress_manga ON user_progress(manga_id)`)
+	}},
+
+	{Version: 5, Name: "add role to users", Up: func(tx *sql.Tx) error {
+		return execAll(tx, `ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user'`)
+	}},
+
+	{Version: 6, Name: "soft delete manga and audit log", Up: func(tx *sql.Tx) error {
+		return execAll(tx,
+			`ALTER TABLE manga ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ`,
+			`CREATE TABLE IF NOT EXISTS audit_log (
+				id BIGSERIAL PRIMARY KEY,
+				entity TEXT NOT NULL,
+				entity_id TEXT NOT NULL,
+				action TEXT NOT NULL,
+				actor_id TEXT NOT NULL,
+				changes JSONB,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity, entity_id)`,
+		)
+	}},
+
+	{Version: 7, Name: "login sessions", Up: func(tx *sql.Tx) error {
+		return execAll(tx,
+			`CREATE TABLE IF NOT EXISTS sessions (
+				id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				refresh_hash TEXT UNIQUE NOT NULL,
+				previous_hash TEXT,
+				user_agent TEXT,
+				ip_address TEXT,
+				created_at TIMESTAMPTZ NOT NULL,
+				last_used_at TIMESTAMPTZ NOT NULL,
+				expires_at TIMESTAMPTZ NOT NULL,
+				revoked_at TIMESTAMPTZ,
+				FOREIGN KEY (user_id) REFERENCES users(id)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_sessions_previous_hash ON sessions(previous_hash)`,
+		)
+	}},
+	{Version: 8, Name: "transactional outbox", Up: func(tx *sql.Tx) error {
+		return execAll(tx,
+			`CREATE TABLE IF NOT EXISTS outbox (
+				id TEXT PRIMARY KEY,
+				topic TEXT NOT NULL,
+				payload JSONB NOT NULL,
+				attempts INTEGER NOT NULL DEFAULT 0,
+				delivered_to TEXT NOT NULL DEFAULT '',
+				last_error TEXT,
+				next_attempt_at TIMESTAMPTZ NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_outbox_next_attempt ON outbox(next_attempt_at)`,
+			`CREATE TABLE IF NOT EXISTS outbox_dead_letters (
+				id TEXT PRIMARY KEY,
+				topic TEXT NOT NULL,
+				payload JSONB NOT NULL,
+				attempts INTEGER NOT NULL,
+				delivered_to TEXT NOT NULL DEFAULT '',
+				last_error TEXT,
+				created_at TIMESTAMPTZ NOT NULL,
+				failed_at TIMESTAMPTZ NOT NULL
+			)`,
+		)
+	}},
+}
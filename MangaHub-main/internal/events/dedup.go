@@ -0,0 +1,40 @@
+package events
+
+import "sync"
+
+// DedupWindow is how many recent event IDs, and request signatures, a
+// receiver remembers
+const DedupWindow = 4096
+
+// dedup remembers the last DedupWindow event IDs a receiver has seen
+type dedup struct {
+	mu    sync.Mutex
+	seen  map[string]bool
+	order []string // Ring buffer of the IDs in seen
+	next  int
+}
+
+func newDedup() *dedup {
+	return &dedup{seen: map[string]bool{}, order: make([]string, DedupWindow)}
+}
+
+// first records id and reports whether it had not been seen before.
+// Updates without an ID are never treated as repeats.
+func (d *dedup) first(id string) bool {
+	if id == "" {
+		return true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.seen[id] {
+		return false
+	}
+	if old := d.order[d.next]; old != "" {
+		delete(d.seen, old)
+	}
+	d.order[d.next] = id
+	d.next = (d.next + 1) % DedupWindow
+	d.seen[id] = true
+	return true
+}
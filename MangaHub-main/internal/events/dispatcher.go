@@ -0,0 +1,236 @@
+// Package events delivers reading progress updates from the API and gRPC
+// servers to the real-time hubs (TCP, UDP and WebSocket).
+//
+// Updates go through a transactional outbox: the repository stores each
+// update in the outbox table in the same transaction as the progress change
+// (see NewProgressEvent), so a committed change is never left without its
+// event. A Dispatcher then delivers outbox events to its subscribers with
+// at-least-once semantics. A subscriber is either a hub in the same process
+// or the internal HTTP endpoint of a hub in another process; see Local and
+// HTTP. Failed deliveries are retried with exponential backoff, and events
+// that run out of attempts are moved to a dead-letter table. Since an event
+// may arrive more than once, it carries its outbox ID as EventID and
+// receivers drop IDs they have already seen.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"mangahub/internal/auth"
+	"mangahub/internal/repository"
+	"mangahub/internal/shared"
+	"mangahub/pkg/models"
+)
+
+// TopicProgress is the outbox topic of shared.ProgressUpdate events
+const TopicProgress = "progress"
+
+// Dispatcher tuning
+const (
+	// PollInterval is how often the outbox is checked for due events when
+	// no Notify arrives, which picks up retries and events from other processes
+	PollInterval = time.Second
+	// ClaimLease hides claimed events from other dispatchers. An event whose
+	// delivery outlasts it may be delivered twice, which receivers tolerate.
+	ClaimLease = time.Minute
+	// ClaimBatch is how many events are claimed at once
+	ClaimBatch = 50
+)
+
+// Notifier is told when an event has been committed to the outbox
+type Notifier interface {
+	Notify()
+}
+
+// Subscriber receives progress updates from a Dispatcher
+type Subscriber interface {
+	// Name identifies the subscriber in logs and in OutboxEvent.DeliveredTo
+	Name() string
+	// Deliver hands over one update. An error makes the dispatcher retry it.
+	Deliver(ctx context.Context, update shared.ProgressUpdate) error
+}
+
+// NewProgressEvent wraps update in an outbox event with a new ID, to be
+// passed to ProgressRepository.UpdateProgress
+func NewProgressEvent(update shared.ProgressUpdate) (models.OutboxEvent, error) {
+	if update.Timestamp == 0 {
+		update.Timestamp = time.Now().Unix()
+	}
+	update.EventID = auth.GenerateID("evt")
+
+	payload, err := json.Marshal(update)
+	if err != nil {
+		return models.OutboxEvent{}, err
+	}
+	return models.OutboxEvent{ID: update.EventID, Topic: TopicProgress, Payload: payload}, nil
+}
+
+// RetryPolicy controls redelivery of failed events. The wait before retry
+// n is InitialBackoff * 2^(n-1), capped at MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts    int // Deliveries tried per event before it is dead-lettered
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Backoff returns the wait before retrying after the given failed attempt
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// Dispatcher delivers outbox events to a fixed set of subscribers. Several
+// dispatchers, in one or more processes, may share an outbox.
+type Dispatcher struct {
+	outbox      repository.OutboxRepository
+	policy      RetryPolicy
+	subscribers []Subscriber
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewDispatcher starts delivering the events in outbox to subscribers
+func NewDispatcher(outbox repository.OutboxRepository, policy RetryPolicy, subscribers ...Subscriber) *Dispatcher {
+	d := &Dispatcher{
+		outbox:      outbox,
+		policy:      policy,
+		subscribers: subscribers,
+		wake:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// Notify makes the dispatcher check the outbox now instead of at the next poll
+func (d *Dispatcher) Notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops the dispatcher after the batch in progress. Undelivered
+// events stay in the outbox for the next start.
+func (d *Dispatcher) Close() {
+	select {
+	case <-d.stop:
+	default:
+		close(d.stop)
+	}
+	<-d.done
+}
+
+// run checks the outbox on every Notify and PollInterval until Close
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.dispatch()
+		select {
+		case <-d.stop:
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch delivers due events until none are left or Close is called
+func (d *Dispatcher) dispatch() {
+	ctx := context.Background()
+	for {
+		batch, err := d.outbox.Claim(ctx, ClaimBatch, ClaimLease)
+		if err != nil {
+			log.Printf("Failed to read outbox: %v", err)
+			return
+		}
+		for _, e := range batch {
+			d.deliver(ctx, e)
+		}
+		if len(batch) < ClaimBatch {
+			return
+		}
+		select {
+		case <-d.stop:
+			return
+		default:
+		}
+	}
+}
+
+// deliver sends e to the subscribers that do not have it yet, then removes
+// it from the outbox, schedules a retry or dead-letters it
+func (d *Dispatcher) deliver(ctx context.Context, e models.OutboxEvent) {
+	var failures []string
+	if e.Topic != TopicProgress {
+		failures = append(failures, fmt.Sprintf("unknown topic %q", e.Topic))
+	} else {
+		var update shared.ProgressUpdate
+		if err := json.Unmarshal(e.Payload, &update); err != nil {
+			failures = append(failures, fmt.Sprintf("invalid payload: %v", err))
+		} else {
+			update.EventID = e.ID
+			for _, s := range d.subscribers {
+				if delivered(e, s.Name()) {
+					continue
+				}
+				if err := s.Deliver(ctx, update); err != nil {
+					failures = append(failures, fmt.Sprintf("%s: %v", s.Name(), err))
+					continue
+				}
+				e.DeliveredTo = append(e.DeliveredTo, s.Name())
+			}
+		}
+	}
+
+	if len(failures) == 0 {
+		if err := d.outbox.Done(ctx, e.ID); err != nil {
+			log.Printf("Failed to remove delivered event %s from outbox: %v", e.ID, err)
+		}
+		return
+	}
+
+	e.Attempts++
+	e.LastError = strings.Join(failures, "; ")
+	if e.Attempts >= d.policy.MaxAttempts {
+		log.Printf("EVENT %s DEAD-LETTERED after %d attempts: %s", e.ID, e.Attempts, e.LastError)
+		if err := d.outbox.DeadLetter(ctx, e); err != nil {
+			log.Printf("Failed to dead-letter event %s: %v", e.ID, err)
+		}
+		return
+	}
+
+	wait := d.policy.Backoff(e.Attempts)
+	e.NextAttemptAt = time.Now().Add(wait)
+	log.Printf("Delivery of event %s failed (attempt %d/%d), retrying in %s: %s", e.ID, e.Attempts, d.policy.MaxAttempts, wait, e.LastError)
+	if err := d.outbox.Retry(ctx, e); err != nil {
+		log.Printf("Failed to schedule retry of event %s: %v", e.ID, err)
+	}
+}
+
+// delivered reports whether subscriber already received e
+func delivered(e models.OutboxEvent, subscriber string) bool {
+	for _, name := range e.DeliveredTo {
+		if name == subscriber {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"mangahub/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxBodySize bounds the body of a request to an internal endpoint, which
+// is read whole before its signature is checked
+const MaxBodySize = 64 << 10
+
+// Handler serves a hub's POST /internal/progress endpoint, the receiving end
+// of an HTTP subscriber. name describes the hub's clients in logs. Requests
+// without a valid signature, or repeating one already used, are refused with
+// 401, see SigningSecret; bodies over MaxBodySize with 413. An event that was
+// already broadcast is acknowledged without broadcasting it again.
+func Handler(name string, hub Broadcaster) gin.HandlerFunc {
+	seen := newDedup()
+	signatures := newDedup()
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxBodySize)
+		body, err := c.GetRawData()
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		err = verifySignature(c.Request.Header, body, time.Now())
+		if err == nil && !signatures.first(c.GetHeader(SignatureHeader)) {
+			err = errors.New("replayed request signature")
+		}
+		if err != nil {
+			log.Printf("UNAUTHORIZED PROGRESS REQUEST from %s: %v", c.ClientIP(), err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		var update shared.ProgressUpdate
+		if err := json.Unmarshal(body, &update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !seen.first(update.EventID) {
+			log.Printf("DUPLICATE EVENT %s ignored", update.EventID)
+			c.JSON(http.StatusOK, gin.H{"status": "duplicate"})
+			return
+		}
+
+		log.Printf("PROGRESS UPDATE RECEIVED → Broadcasting to %s", name)
+		log.Printf("   User: %s (ID: %s)", update.Username, update.UserID)
+		log.Printf("   Manga: %s → Chapter %d (%s)", update.MangaTitle, update.CurrentChapter, update.Status)
+
+		hub.BroadcastProgress(update)
+		log.Printf("BROADCAST SENT TO %d %s", hub.GetClientCount(), name)
+
+		c.JSON(http.StatusOK, gin.H{"status": "broadcasted"})
+	}
+}
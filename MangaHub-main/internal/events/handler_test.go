@@ -0,0 +1,154 @@
+package events
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mangahub/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testSecret = []byte("events-test-secret-0123456789abcdef")
+
+// recordingHub remembers the updates broadcast to it
+type recordingHub struct {
+	mu      sync.Mutex
+	updates []shared.ProgressUpdate
+}
+
+func (h *recordingHub) BroadcastProgress(update shared.ProgressUpdate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.updates = append(h.updates, update)
+}
+
+func (h *recordingHub) GetClientCount() int { return 0 }
+
+func (h *recordingHub) count() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.updates)
+}
+
+// newTestHandler serves Handler for a recordingHub, signing with testSecret
+func newTestHandler(t *testing.T) (*gin.Engine, *recordingHub) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	old := SigningSecret
+	SigningSecret = testSecret
+	t.Cleanup(func() { SigningSecret = old })
+
+	hub := &recordingHub{}
+	router := gin.New()
+	router.POST("/internal/progress", Handler("TEST CLIENT(S)", hub))
+	return router, hub
+}
+
+// post sends body with the given timestamp and signature headers
+func post(router http.Handler, body []byte, timestamp, signature string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/internal/progress", bytes.NewReader(body))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, signature)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// postSigned sends body signed with secret at time at
+func postSigned(router http.Handler, secret, body []byte, at time.Time) *httptest.ResponseRecorder {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return post(router, body, timestamp, Sign(secret, timestamp, body))
+}
+
+func TestHandlerAcceptsSignedRequest(t *testing.T) {
+	router, hub := newTestHandler(t)
+
+	w := postSigned(router, testSecret, []byte(`{"event_id":"evt_1","user_id":"usr_1"}`), time.Now())
+	if w.Code != http.StatusOK || hub.count() != 1 {
+		t.Fatalf("signed request: status %d, %d broadcasts; want 200, 1", w.Code, hub.count())
+	}
+	if hub.updates[0].UserID != "usr_1" {
+		t.Errorf("broadcast %+v, want user usr_1", hub.updates[0])
+	}
+
+	// A redelivery is signed afresh but carries the same event ID
+	w = postSigned(router, testSecret, []byte(`{"event_id":"evt_1","user_id":"usr_1"}`), time.Now().Add(time.Second))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "duplicate") || hub.count() != 1 {
+		t.Errorf("redelivery: status %d %s, %d broadcasts; want a duplicate not broadcast", w.Code, w.Body, hub.count())
+	}
+}
+
+func TestHandlerRejectsBadSignatures(t *testing.T) {
+	body := []byte(`{"event_id":"evt_1"}`)
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	tests := []struct {
+		name string
+		send func(router http.Handler) *httptest.ResponseRecorder
+	}{
+		{"unsigned", func(router http.Handler) *httptest.ResponseRecorder {
+			return post(router, body, "", "")
+		}},
+		{"wrong secret", func(router http.Handler) *httptest.ResponseRecorder {
+			return postSigned(router, []byte("some-other-secret-0123456789abcdef"), body, now)
+		}},
+		{"tampered body", func(router http.Handler) *httptest.ResponseRecorder {
+			return post(router, []byte(`{"event_id":"evt_2"}`), timestamp, Sign(testSecret, timestamp, body))
+		}},
+		{"malformed MAC", func(router http.Handler) *httptest.ResponseRecorder {
+			return post(router, body, timestamp, "sha256=not-hex")
+		}},
+		{"stale timestamp", func(router http.Handler) *httptest.ResponseRecorder {
+			return postSigned(router, testSecret, body, now.Add(-MaxClockSkew-time.Minute))
+		}},
+		{"future timestamp", func(router http.Handler) *httptest.ResponseRecorder {
+			return postSigned(router, testSecret, body, now.Add(MaxClockSkew+time.Minute))
+		}},
+		{"invalid timestamp", func(router http.Handler) *httptest.ResponseRecorder {
+			return post(router, body, "yesterday", Sign(testSecret, "yesterday", body))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, hub := newTestHandler(t)
+			if w := tt.send(router); w.Code != http.StatusUnauthorized || hub.count() != 0 {
+				t.Errorf("status %d, %d broadcasts; want 401, none", w.Code, hub.count())
+			}
+		})
+	}
+}
+
+func TestHandlerRejectsReplayedTimestamp(t *testing.T) {
+	router, hub := newTestHandler(t)
+	body := []byte(`{"event_id":"evt_1"}`)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := Sign(testSecret, timestamp, body)
+
+	if w := post(router, body, timestamp, signature); w.Code != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", w.Code)
+	}
+	w := post(router, body, timestamp, signature)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "replayed") {
+		t.Errorf("replay: status %d %s, want 401 replayed", w.Code, w.Body)
+	}
+	if hub.count() != 1 {
+		t.Errorf("%d broadcasts, want 1", hub.count())
+	}
+}
+
+func TestHandlerLimitsBodySize(t *testing.T) {
+	router, hub := newTestHandler(t)
+	body := []byte(`{"event_id":"evt_1","manga_title":"` + strings.Repeat("x", MaxBodySize) + `"}`)
+
+	if w := postSigned(router, testSecret, body, time.Now()); w.Code != http.StatusRequestEntityTooLarge || hub.count() != 0 {
+		t.Errorf("oversized body: status %d, %d broadcasts; want 413, none", w.Code, hub.count())
+	}
+}
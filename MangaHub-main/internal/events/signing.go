@@ -0,0 +1,76 @@
+package events
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Requests to the internal endpoints are signed with HMAC-SHA256 so that
+// only servers holding SigningSecret can trigger broadcasts. The signature
+// covers the timestamp and the body:
+//
+//	X-MangaHub-Timestamp: <unix seconds>
+//	X-MangaHub-Signature: sha256=<hex HMAC of "<timestamp>.<body>">
+//
+// Requests older than MaxClockSkew are refused, and so is a request repeating
+// the signature of one the receiver remembers (see DedupWindow). Any other
+// replay within that window carries an event ID the receiver has already seen
+// and is not broadcast again.
+const (
+	TimestampHeader = "X-MangaHub-Timestamp"
+	SignatureHeader = "X-MangaHub-Signature"
+	// MaxClockSkew is how far a request's timestamp may be from the receiver's clock
+	MaxClockSkew = 5 * time.Minute
+)
+
+// SigningSecret is the key shared by the servers that publish progress and
+// the real-time servers, set by config.RequireEventsSecret. While it is
+// empty, HTTP subscribers cannot deliver and Handler refuses every request.
+var SigningSecret []byte
+
+// Sign returns the signature header value for body sent at timestamp
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// signRequest adds the timestamp and signature headers for body to req
+func signRequest(req *http.Request, body []byte) error {
+	if len(SigningSecret) == 0 {
+		return errors.New("events signing secret is not configured")
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(SigningSecret, timestamp, body))
+	return nil
+}
+
+// verifySignature checks the headers of a request to an internal endpoint
+func verifySignature(header http.Header, body []byte, now time.Time) error {
+	if len(SigningSecret) == 0 {
+		return errors.New("events signing secret is not configured")
+	}
+	timestamp, signature := header.Get(TimestampHeader), header.Get(SignatureHeader)
+	if timestamp == "" || signature == "" {
+		return errors.New("missing request signature")
+	}
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid request timestamp")
+	}
+	if skew := now.Sub(time.Unix(sec, 0)); skew > MaxClockSkew || skew < -MaxClockSkew {
+		return errors.New("request timestamp outside the allowed clock skew")
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(SigningSecret, timestamp, body))) {
+		return errors.New("invalid request signature")
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"mangahub/internal/shared"
+)
+
+// Broadcaster is a hub that fans progress updates out to its clients.
+// tcp.Hub, udp.Hub and websocket.Hub implement it.
+type Broadcaster interface {
+	BroadcastProgress(update shared.ProgressUpdate)
+	GetClientCount() int
+}
+
+// localSubscriber hands updates straight to a hub in this process
+type localSubscriber struct {
+	name  string
+	hub   Broadcaster
+	dedup *dedup
+}
+
+// Local returns a subscriber for a hub running in the same process.
+// Redelivered events are not broadcast again.
+func Local(name string, hub Broadcaster) Subscriber {
+	return &localSubscriber{name: name, hub: hub, dedup: newDedup()}
+}
+
+func (s *localSubscriber) Name() string { return s.name }
+
+func (s *localSubscriber) Deliver(ctx context.Context, update shared.ProgressUpdate) error {
+	if s.dedup.first(update.EventID) {
+		s.hub.BroadcastProgress(update)
+	}
+	return nil
+}
+
+// httpSubscriber posts updates to a hub's internal progress endpoint
+type httpSubscriber struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+// HTTPTimeout bounds one delivery to an internal endpoint
+const HTTPTimeout = 5 * time.Second
+
+// HTTP returns a subscriber that posts each update as signed JSON to url,
+// the /internal/progress endpoint of a hub in another process (see Handler).
+// tlsConfig, if not nil, is used for https URLs. Any response other than
+// 200 OK counts as a failed delivery.
+func HTTP(name, url string, tlsConfig *tls.Config) Subscriber {
+	client := &http.Client{Timeout: HTTPTimeout}
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		client.Transport = transport
+	}
+	return &httpSubscriber{name: name, url: url, client: client}
+}
+
+func (s *httpSubscriber) Name() string { return s.name }
+
+func (s *httpSubscriber) Deliver(ctx context.Context, update shared.ProgressUpdate) error {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := signRequest(req, body); err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with status %d", s.url, resp.StatusCode)
+	}
+	return nil
+}
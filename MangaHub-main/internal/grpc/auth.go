@@ -0,0 +1,79 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"mangahub/internal/auth"
+	pb "mangahub/proto"
+
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// methodRoles lists the roles allowed to call each restricted RPC.
+// Methods not listed are open to every caller.
+var methodRoles = map[string][]string{
+	pb.MangaService_UpdateProgress_FullMethodName: auth.Roles, // Any signed-in user
+	pb.MangaService_CreateManga_FullMethodName:    {auth.RoleAdmin, auth.RoleModerator},
+	pb.MangaService_UpdateManga_FullMethodName:    {auth.RoleAdmin, auth.RoleModerator},
+	pb.MangaService_DeleteManga_FullMethodName:    {auth.RoleAdmin, auth.RoleModerator},
+	pb.MangaService_RestoreManga_FullMethodName:   {auth.RoleAdmin, auth.RoleModerator},
+}
+
+type claimsKey struct{}
+
+// ClaimsFromContext returns the caller's token claims, if a valid token was sent
+func ClaimsFromContext(ctx context.Context) (*auth.Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*auth.Claims)
+	return claims, ok
+}
+
+// AuthInterceptor validates the bearer token in the "authorization" metadata,
+// rejects tokens whose session was revoked and enforces methodRoles.
+// Valid claims are stored in the request context.
+func AuthInterceptor(sessions auth.Sessions) grpclib.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (interface{}, error) {
+		roles, restricted := methodRoles[info.FullMethod]
+
+		token := bearerToken(ctx)
+		if token == "" {
+			if restricted {
+				return nil, status.Error(codes.Unauthenticated, "authorization token required")
+			}
+			return handler(ctx, req)
+		}
+
+		claims, err := auth.Authenticate(ctx, sessions, token)
+		if errors.Is(err, auth.ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+		} else if errors.Is(err, auth.ErrSessionRevoked) {
+			return nil, status.Error(codes.Unauthenticated, "session has been revoked")
+		} else if err != nil {
+			log.Printf("Failed to check session: %v", err)
+			return nil, status.Error(codes.Internal, "failed to check session")
+		}
+		if restricted && !auth.HasRole(claims.Role, roles...) {
+			return nil, status.Errorf(codes.PermissionDenied, "%s requires one of the roles %s", info.FullMethod, strings.Join(roles, ", "))
+		}
+
+		return handler(context.WithValue(ctx, claimsKey{}, claims), req)
+	}
+}
+
+// bearerToken reads the token from "authorization: Bearer <token>" metadata
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(values[0], "Bearer ")
+}
@@ -2,10 +2,16 @@ package grpc
 
 import (
 	"context"
-	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"strings"
 
+	"mangahub/internal/auth"
+	"mangahub/internal/events"
+	"mangahub/internal/progress"
+	"mangahub/internal/repository"
+	"mangahub/pkg/models"
 	pb "mangahub/proto"
 
 	"google.golang.org/grpc/codes"
@@ -15,128 +21,249 @@ import (
 // MangaServiceServer implements the gRPC service
 type MangaServiceServer struct {
 	pb.UnimplementedMangaServiceServer
-	db *sql.DB
+	manga   repository.MangaRepository
+	updates *progress.Service
 }
 
 // NewMangaServiceServer creates a new gRPC service implementation for manga operations
 // This server provides gRPC endpoints for getting, searching, and updating manga progress.
-func NewMangaServiceServer(db *sql.DB) *MangaServiceServer {
-	return &MangaServiceServer{db: db}
+// Progress updates are queued in the outbox and notifier is told about them,
+// like those made over REST.
+func NewMangaServiceServer(store *repository.Store, notifier events.Notifier) *MangaServiceServer {
+	return &MangaServiceServer{manga: store.Manga, updates: progress.NewService(store, notifier)}
 }
 
 // GetManga retrieves a manga by ID
 func (s *MangaServiceServer) GetManga(ctx context.Context, req *pb.GetMangaRequest) (*pb.GetMangaResponse, error) {
 	log.Printf("gRPC GetManga called: id=%s", req.Id)
 
-	var manga pb.Manga
-	var genresStr string
-
-	err := s.db.QueryRowContext(ctx,
-		"SELECT id, title, author, genres, status, total_chapters, description FROM manga WHERE id = ?",
-		req.Id,
-	).Scan(&manga.Id, &manga.Title, &manga.Author, &genresStr, &manga.Status, &manga.TotalChapters, &manga.Description)
-
-	if err == sql.ErrNoRows {
+	m, err := s.manga.Get(ctx, req.Id)
+	if errors.Is(err, repository.ErrNotFound) {
 		return nil, status.Errorf(codes.NotFound, "manga not found: id=%s", req.Id)
 	}
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "database error: %v", err)
 	}
 
-	// Convert genres string to array
-	if genresStr != "" {
-		manga.Genres = strings.Split(genresStr, ",")
-	}
-
-	return &pb.GetMangaResponse{Manga: &manga}, nil
+	return &pb.GetMangaResponse{Manga: toProtoManga(m)}, nil
 }
 
-// SearchManga searches for manga
+// SearchManga searches for manga.
+// A non-empty query runs a ranked full-text search over title, author and
+// description; genre filters on an exact genre name.
 func (s *MangaServiceServer) SearchManga(ctx context.Context, req *pb.SearchMangaRequest) (*pb.SearchMangaResponse, error) {
 	log.Printf("gRPC SearchManga called: query=%s, genre=%s", req.Query, req.Genre)
 
-	query := "SELECT id, title, author, genres, status, total_chapters, description FROM manga WHERE 1=1"
-	args := []interface{}{}
-
-	if req.Query != "" {
-		query += " AND title LIKE ?"
-		args = append(args, "%"+req.Query+"%")
+	query := models.MangaQuery{
+		Search: strings.TrimSpace(req.Query),
+		Limit:  20,
 	}
-
 	if req.Genre != "" {
-		query += " AND genres LIKE ?"
-		args = append(args, "%"+req.Genre+"%")
+		query.Genres = []string{req.Genre}
+	}
+	if query.Search != "" {
+		query.Sort = "relevance"
 	}
 
-	query += " LIMIT 20"
-
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	page, err := s.manga.List(ctx, query)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "search failed: %v", err)
 	}
-	defer rows.Close()
 
-	var mangas []*pb.Manga
-	for rows.Next() {
-		var manga pb.Manga
-		var genresStr string
+	resp := &pb.SearchMangaResponse{Count: int32(page.Count)}
+	for _, m := range page.Manga {
+		manga := toProtoManga(m)
+		resp.Mangas = append(resp.Mangas, manga)
 
-		err := rows.Scan(&manga.Id, &manga.Title, &manga.Author, &genresStr, &manga.Status, &manga.TotalChapters, &manga.Description)
-		if err != nil {
-			continue
+		if m.Match != nil {
+			resp.Results = append(resp.Results, &pb.SearchResult{
+				Manga:          manga,
+				Score:          m.Match.Score,
+				TitleHighlight: m.Match.Title,
+				Snippet:        m.Match.Snippet,
+			})
 		}
+	}
 
-		if genresStr != "" {
-			manga.Genres = strings.Split(genresStr, ",")
-		}
+	return resp, nil
+}
 
-		mangas = append(mangas, &manga)
+// UpdateProgress updates the caller's reading progress. It needs a token,
+// see AuthInterceptor; user_id may be left empty but must otherwise be the
+// caller's own.
+func (s *MangaServiceServer) UpdateProgress(ctx context.Context, req *pb.UpdateProgressRequest) (*pb.UpdateProgressResponse, error) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authorization token required")
 	}
+	if req.UserId != "" && req.UserId != claims.UserID {
+		return nil, status.Error(codes.PermissionDenied, "user_id does not match the token")
+	}
+	log.Printf("gRPC UpdateProgress called: user=%s, manga=%s, chapter=%d", claims.UserID, req.MangaId, req.CurrentChapter)
 
-	return &pb.SearchMangaResponse{
-		Mangas: mangas,
-		Count:  int32(len(mangas)),
+	// Validate and store the progress, queueing its broadcast
+	_, err := s.updates.Record(ctx, progress.Update{
+		UserID:         claims.UserID,
+		MangaID:        req.MangaId,
+		CurrentChapter: int(req.CurrentChapter),
+	})
+	if errors.Is(err, progress.ErrInvalid) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, progress.ErrMangaNotFound) {
+		return nil, status.Errorf(codes.NotFound, "manga not found: id=%s", req.MangaId)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update progress: %v", err)
+	}
+
+	return &pb.UpdateProgressResponse{
+		Success: true,
+		Message: "Progress updated successfully",
 	}, nil
 }
 
-// UpdateProgress updates reading progress
-func (s *MangaServiceServer) UpdateProgress(ctx context.Context, req *pb.UpdateProgressRequest) (*pb.UpdateProgressResponse, error) {
-	log.Printf("gRPC UpdateProgress called: user=%s, manga=%s, chapter=%d", req.UserId, req.MangaId, req.CurrentChapter)
+// CreateManga adds a manga to the catalog. Only admins and moderators may
+// call it, see AuthInterceptor.
+func (s *MangaServiceServer) CreateManga(ctx context.Context, req *pb.CreateMangaRequest) (*pb.CreateMangaResponse, error) {
+	if req.Manga == nil || strings.TrimSpace(req.Manga.Title) == "" {
+		return nil, status.Error(codes.InvalidArgument, "manga.title is required")
+	}
+	if req.Manga.TotalChapters < 0 {
+		return nil, status.Error(codes.InvalidArgument, "manga.total_chapters must be non-negative")
+	}
 
-	// Validate input
-	if req.UserId == "" || req.MangaId == "" {
-		return nil, status.Error(codes.InvalidArgument, "user_id and manga_id are required")
+	m := models.Manga{
+		ID:            auth.GenerateID("mng"),
+		Title:         req.Manga.Title,
+		Author:        req.Manga.Author,
+		Genres:        models.NormalizeGenres(req.Manga.Genres),
+		Status:        req.Manga.Status,
+		TotalChapters: int(req.Manga.TotalChapters),
+		Description:   req.Manga.Description,
+	}
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		log.Printf("gRPC CreateManga called: title=%s, by=%s", m.Title, claims.Username)
 	}
 
-	if req.CurrentChapter < 0 {
-		return nil, status.Error(codes.InvalidArgument, "current_chapter must be non-negative")
+	if err := s.manga.Create(actorContext(ctx), m); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create manga: %v", err)
 	}
 
-	// Check if manga exists
-	var exists bool
-	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM manga WHERE id = ?)", req.MangaId).Scan(&exists)
+	return &pb.CreateMangaResponse{Manga: toProtoManga(m)}, nil
+}
+
+// UpdateManga changes the fields of a manga named in update_mask, or all of
+// them when the mask is empty. Only admins and moderators may call it.
+func (s *MangaServiceServer) UpdateManga(ctx context.Context, req *pb.UpdateMangaRequest) (*pb.UpdateMangaResponse, error) {
+	log.Printf("gRPC UpdateManga called: id=%s, mask=%v", req.Id, req.UpdateMask)
+
+	if req.Id == "" || req.Manga == nil {
+		return nil, status.Error(codes.InvalidArgument, "id and manga are required")
+	}
+	patch, err := patchFromMask(req.Manga, req.UpdateMask)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "database error: %v", err)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	if !exists {
-		return nil, status.Errorf(codes.NotFound, "manga not found: id=%s", req.MangaId)
+	if patch.Title != nil && strings.TrimSpace(*patch.Title) == "" {
+		return nil, status.Error(codes.InvalidArgument, "manga.title must not be blank")
+	}
+	if patch.TotalChapters != nil && *patch.TotalChapters < 0 {
+		return nil, status.Error(codes.InvalidArgument, "manga.total_chapters must be non-negative")
+	}
+	if patch.Status != nil && !models.ValidMangaStatus(*patch.Status) {
+		return nil, status.Error(codes.InvalidArgument, "manga.status must be ongoing, completed or hiatus")
 	}
 
-	// Update or insert progress
-	_, err = s.db.ExecContext(ctx,
-		`INSERT INTO user_progress (user_id, manga_id, current_chapter, updated_at)
-		 VALUES (?, ?, ?, CURRENT_TIMESTAMP)
-		 ON CONFLICT(user_id, manga_id) 
-		 DO UPDATE SET current_chapter = ?, updated_at = CURRENT_TIMESTAMP`,
-		req.UserId, req.MangaId, req.CurrentChapter, req.CurrentChapter,
-	)
+	m, err := s.manga.Update(actorContext(ctx), req.Id, patch)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "manga not found: id=%s", req.Id)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update manga: %v", err)
+	}
 
+	return &pb.UpdateMangaResponse{Manga: toProtoManga(m)}, nil
+}
+
+// DeleteManga soft-deletes a manga, keeping user progress.
+// Only admins and moderators may call it.
+func (s *MangaServiceServer) DeleteManga(ctx context.Context, req *pb.DeleteMangaRequest) (*pb.DeleteMangaResponse, error) {
+	log.Printf("gRPC DeleteManga called: id=%s", req.Id)
+
+	err := s.manga.Delete(actorContext(ctx), req.Id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "manga not found: id=%s", req.Id)
+	}
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to update progress: %v", err)
+		return nil, status.Errorf(codes.Internal, "failed to delete manga: %v", err)
 	}
 
-	return &pb.UpdateProgressResponse{
-		Success: true,
-		Message: "Progress updated successfully",
-	}, nil
-}
\ No newline at end of file
+	return &pb.DeleteMangaResponse{Success: true, Message: "Manga deleted"}, nil
+}
+
+// RestoreManga brings back a deleted manga. Only admins and moderators may call it.
+func (s *MangaServiceServer) RestoreManga(ctx context.Context, req *pb.RestoreMangaRequest) (*pb.RestoreMangaResponse, error) {
+	log.Printf("gRPC RestoreManga called: id=%s", req.Id)
+
+	m, err := s.manga.Restore(actorContext(ctx), req.Id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "manga not found: id=%s", req.Id)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to restore manga: %v", err)
+	}
+
+	return &pb.RestoreMangaResponse{Manga: toProtoManga(m)}, nil
+}
+
+// patchFromMask builds a patch from the masked fields of m
+func patchFromMask(m *pb.Manga, mask []string) (models.MangaPatch, error) {
+	if len(mask) == 0 {
+		mask = []string{"title", "author", "genres", "status", "total_chapters", "description"}
+	}
+
+	var patch models.MangaPatch
+	for _, field := range mask {
+		switch field {
+		case "title":
+			patch.Title = &m.Title
+		case "author":
+			patch.Author = &m.Author
+		case "genres":
+			genres := m.Genres
+			patch.Genres = &genres
+		case "status":
+			patch.Status = &m.Status
+		case "total_chapters":
+			n := int(m.TotalChapters)
+			patch.TotalChapters = &n
+		case "description":
+			patch.Description = &m.Description
+		default:
+			return patch, fmt.Errorf("unknown field in update_mask: %q", field)
+		}
+	}
+	return patch, nil
+}
+
+// actorContext attributes catalog writes to the authenticated caller
+func actorContext(ctx context.Context) context.Context {
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		return repository.WithActor(ctx, claims.UserID)
+	}
+	return ctx
+}
+
+// toProtoManga converts a catalog model to its protobuf message
+func toProtoManga(m models.Manga) *pb.Manga {
+	return &pb.Manga{
+		Id:            m.ID,
+		Title:         m.Title,
+		Author:        m.Author,
+		Genres:        m.Genres,
+		Status:        m.Status,
+		TotalChapters: int32(m.TotalChapters),
+		Description:   m.Description,
+	}
+}
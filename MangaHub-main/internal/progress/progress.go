@@ -0,0 +1,118 @@
+// Package progress records users' reading progress. The REST, gRPC and TCP
+// servers all go through Service, so progress is validated, stored and
+// broadcast the same way whichever protocol it arrives on.
+package progress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"mangahub/internal/events"
+	"mangahub/internal/repository"
+	"mangahub/internal/shared"
+	"mangahub/pkg/models"
+)
+
+// Statuses a library entry can have
+var Statuses = []string{"reading", "completed", "plan_to_read"}
+
+// Errors returned by Service.Record
+var (
+	// ErrInvalid wraps a description of a rejected update
+	ErrInvalid = errors.New("invalid progress")
+	// ErrMangaNotFound is returned for a missing or deleted manga
+	ErrMangaNotFound = errors.New("manga not found")
+)
+
+// Update is progress submitted by a user. An empty Status keeps the stored one.
+type Update struct {
+	UserID         string
+	Username       string // Looked up when empty
+	MangaID        string
+	CurrentChapter int
+	Status         string
+}
+
+// Service validates and stores progress updates and queues their broadcast
+type Service struct {
+	manga    repository.MangaRepository
+	users    repository.UserRepository
+	progress repository.ProgressRepository
+	events   events.Notifier
+}
+
+// NewService returns a Service writing to store and telling notifier about
+// the broadcasts it queues
+func NewService(store *repository.Store, notifier events.Notifier) *Service {
+	return &Service{manga: store.Manga, users: store.Users, progress: store.Progress, events: notifier}
+}
+
+// Record validates u, stores it together with its broadcast in the outbox
+// and returns the broadcast
+func (s *Service) Record(ctx context.Context, u Update) (shared.ProgressUpdate, error) {
+	if u.UserID == "" || u.MangaID == "" {
+		return shared.ProgressUpdate{}, fmt.Errorf("%w: user and manga are required", ErrInvalid)
+	}
+	if u.CurrentChapter < 0 {
+		return shared.ProgressUpdate{}, fmt.Errorf("%w: chapter must not be negative", ErrInvalid)
+	}
+	if u.Status != "" && !ValidStatus(u.Status) {
+		return shared.ProgressUpdate{}, fmt.Errorf("%w: status must be one of reading, completed, plan_to_read", ErrInvalid)
+	}
+
+	m, err := s.manga.Get(ctx, u.MangaID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return shared.ProgressUpdate{}, ErrMangaNotFound
+	} else if err != nil {
+		return shared.ProgressUpdate{}, err
+	}
+
+	username := u.Username
+	if username == "" {
+		username = "Unknown User"
+		if user, err := s.users.GetByID(ctx, u.UserID); err == nil {
+			username = user.Username
+		}
+	}
+
+	update := shared.ProgressUpdate{
+		UserID:         u.UserID,
+		Username:       username,
+		MangaID:        u.MangaID,
+		MangaTitle:     m.Title,
+		CurrentChapter: u.CurrentChapter,
+		Status:         u.Status,
+		Timestamp:      time.Now().Unix(),
+	}
+	event, err := events.NewProgressEvent(update)
+	if err != nil {
+		return shared.ProgressUpdate{}, err
+	}
+
+	// The progress and its broadcast are committed together
+	err = s.progress.UpdateProgress(ctx, models.UserProgress{
+		UserID:         u.UserID,
+		MangaID:        u.MangaID,
+		CurrentChapter: u.CurrentChapter,
+		Status:         u.Status,
+	}, event)
+	if err != nil {
+		return shared.ProgressUpdate{}, err
+	}
+	s.events.Notify()
+
+	update.EventID = event.ID
+	return update, nil
+}
+
+// ValidStatus reports whether status is one of Statuses
+func ValidStatus(status string) bool {
+	for _, s := range Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
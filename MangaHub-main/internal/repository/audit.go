@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"mangahub/pkg/models"
+)
+
+// SystemActor is recorded in the audit log for changes made without a user,
+// such as seeding
+const SystemActor = "system"
+
+type actorKey struct{}
+
+// WithActor returns a context whose catalog writes are attributed to userID
+func WithActor(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+// Actor returns the user a write is attributed to, or SystemActor
+func Actor(ctx context.Context) string {
+	if id, ok := ctx.Value(actorKey{}).(string); ok && id != "" {
+		return id
+	}
+	return SystemActor
+}
+
+// ApplyPatch applies p to m and returns the result with the fields that
+// actually changed. Genres are normalized before comparison.
+func ApplyPatch(m models.Manga, p models.MangaPatch) (models.Manga, map[string]models.FieldChange) {
+	changes := map[string]models.FieldChange{}
+	setString := func(field string, dst *string, v *string) {
+		if v != nil && *v != *dst {
+			changes[field] = models.FieldChange{Old: *dst, New: *v}
+			*dst = *v
+		}
+	}
+
+	setString("title", &m.Title, p.Title)
+	setString("author", &m.Author, p.Author)
+	setString("status", &m.Status, p.Status)
+	setString("description", &m.Description, p.Description)
+	if p.TotalChapters != nil && *p.TotalChapters != m.TotalChapters {
+		changes["total_chapters"] = models.FieldChange{Old: m.TotalChapters, New: *p.TotalChapters}
+		m.TotalChapters = *p.TotalChapters
+	}
+	if p.Genres != nil {
+		genres := models.NormalizeGenres(*p.Genres)
+		if genres == nil {
+			genres = []string{}
+		}
+		if !sameGenres(m.Genres, genres) {
+			old := m.Genres
+			if old == nil {
+				old = []string{}
+			}
+			changes["genres"] = models.FieldChange{Old: old, New: genres}
+			m.Genres = genres
+		}
+	}
+	return m, changes
+}
+
+// CreateChanges describes a new manga as an audit change set
+func CreateChanges(m models.Manga) map[string]models.FieldChange {
+	genres := m.Genres
+	if genres == nil {
+		genres = []string{}
+	}
+	return map[string]models.FieldChange{
+		"title":          {New: m.Title},
+		"author":         {New: m.Author},
+		"genres":         {New: genres},
+		"status":         {New: m.Status},
+		"total_chapters": {New: m.TotalChapters},
+		"description":    {New: m.Description},
+	}
+}
+
+// sameGenres compares genre lists in order; renaming the case of a genre
+// counts as no change because genre names are case-insensitive
+func sameGenres(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
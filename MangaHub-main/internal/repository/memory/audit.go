@@ -0,0 +1,26 @@
+package memory
+
+import (
+	"context"
+
+	"mangahub/pkg/models"
+)
+
+// AuditRepository implements repository.AuditRepository in memory
+type AuditRepository struct {
+	s *state
+}
+
+// List returns the changes to one entity, oldest first
+func (r *AuditRepository) List(ctx context.Context, entity, entityID string) ([]models.AuditEntry, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	entries := []models.AuditEntry{}
+	for _, e := range r.s.audit {
+		if e.Entity == entity && e.EntityID == entityID {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
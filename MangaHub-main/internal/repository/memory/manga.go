@@ -0,0 +1,341 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"mangahub/internal/repository"
+	"mangahub/pkg/models"
+)
+
+// MangaRepository implements repository.MangaRepository in memory
+type MangaRepository struct {
+	s *state
+}
+
+// Get returns a copy of a manga by ID
+func (r *MangaRepository) Get(ctx context.Context, id string) (models.Manga, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	m, ok := r.s.live(id)
+	if !ok {
+		return models.Manga{}, repository.ErrNotFound
+	}
+	return cloneManga(m), nil
+}
+
+// Exists reports whether a manga with the given ID exists
+func (r *MangaRepository) Exists(ctx context.Context, id string) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	_, ok := r.s.live(id)
+	return ok, nil
+}
+
+// Create adds a manga, registering any new genres
+func (r *MangaRepository) Create(ctx context.Context, m models.Manga) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.manga[m.ID]; ok {
+		return repository.ErrDuplicate
+	}
+
+	genres := []string{}
+	for _, name := range models.NormalizeGenres(m.Genres) {
+		genres = append(genres, r.s.genres[r.s.genreID(name)-1])
+	}
+	m.Genres = genres
+	m.GenresString = ""
+	m.Match = nil
+	r.s.manga[m.ID] = m
+	r.s.recordAudit(ctx, m.ID, models.AuditCreate, repository.CreateChanges(m))
+	return nil
+}
+
+// Update applies patch to a manga and records the changed fields
+func (r *MangaRepository) Update(ctx context.Context, id string, patch models.MangaPatch) (models.Manga, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	current, ok := r.s.live(id)
+	if !ok {
+		return models.Manga{}, repository.ErrNotFound
+	}
+	m, changes := repository.ApplyPatch(cloneManga(current), patch)
+	if len(changes) == 0 {
+		return cloneManga(current), nil
+	}
+
+	if _, ok := changes["genres"]; ok {
+		genres := []string{}
+		for _, name := range m.Genres {
+			genres = append(genres, r.s.genres[r.s.genreID(name)-1])
+		}
+		m.Genres = genres
+	}
+	r.s.manga[id] = m
+	r.s.recordAudit(ctx, id, models.AuditUpdate, changes)
+	return cloneManga(m), nil
+}
+
+// Delete marks a manga as deleted. Its user progress is kept.
+func (r *MangaRepository) Delete(ctx context.Context, id string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.live(id); !ok {
+		return repository.ErrNotFound
+	}
+	r.s.deleted[id] = time.Now().UTC()
+	r.s.recordAudit(ctx, id, models.AuditDelete, nil)
+	return nil
+}
+
+// Restore clears the deleted mark of a manga
+func (r *MangaRepository) Restore(ctx context.Context, id string) (models.Manga, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	m, ok := r.s.manga[id]
+	if !ok {
+		return models.Manga{}, repository.ErrNotFound
+	}
+	if _, deleted := r.s.deleted[id]; deleted {
+		delete(r.s.deleted, id)
+		r.s.recordAudit(ctx, id, models.AuditRestore, nil)
+	}
+	return cloneManga(m), nil
+}
+
+// Genres returns every genre with the number of manga tagged with it
+func (r *MangaRepository) Genres(ctx context.Context) ([]models.Genre, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	counts := map[string]int{}
+	for id, m := range r.s.manga {
+		if _, deleted := r.s.deleted[id]; deleted {
+			continue
+		}
+		for _, g := range m.Genres {
+			counts[g]++
+		}
+	}
+
+	genres := []models.Genre{}
+	for i, name := range r.s.genres {
+		genres = append(genres, models.Genre{ID: i + 1, Name: name, MangaCount: counts[name]})
+	}
+	sort.Slice(genres, func(i, j int) bool { return genres[i].Name < genres[j].Name })
+	return genres, nil
+}
+
+// listRow is a manga that passed the filters, with its sort position
+type listRow struct {
+	manga models.Manga
+	value interface{} // Sort value, typed as DecodeCursor returns it
+}
+
+// List returns one page of the catalog matching q, together with the total
+// match count and genre/status facet counts over the whole filtered set.
+func (r *MangaRepository) List(ctx context.Context, q models.MangaQuery) (*models.MangaPage, error) {
+	q = repository.NormalizeQuery(q)
+	if !repository.ValidMangaSort(q.Sort) {
+		return nil, fmt.Errorf("unknown sort key %q", q.Sort)
+	}
+
+	var (
+		cursorValue interface{}
+		cursorID    string
+	)
+	if q.Cursor != "" {
+		var err error
+		if cursorValue, cursorID, err = repository.DecodeCursor(q); err != nil {
+			return nil, err
+		}
+	}
+
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	page := &models.MangaPage{
+		Manga:  []models.Manga{},
+		Facets: models.MangaFacets{Genres: []models.FacetCount{}, Statuses: []models.FacetCount{}},
+	}
+
+	var search *matcher
+	if q.Search != "" {
+		if search = r.newMatcher(q.Search); search == nil {
+			return page, nil
+		}
+	}
+
+	popularity := r.s.popularity()
+	var rows []listRow
+	for id, m := range r.s.manga {
+		if _, deleted := r.s.deleted[id]; deleted || !matchesFilter(m, q) {
+			continue
+		}
+		m = cloneManga(m)
+		if search != nil {
+			if m.Match = search.match(m); m.Match == nil {
+				continue
+			}
+		}
+
+		row := listRow{manga: m}
+		switch q.Sort {
+		case "title":
+			row.value = m.Title
+		case "total_chapters":
+			row.value = int64(m.TotalChapters)
+		case "popularity":
+			row.value = int64(popularity[m.ID])
+		case "relevance":
+			// Same sign as SQLite's bm25(): ascending puts the best match first
+			row.value = -m.Match.Score
+		}
+		rows = append(rows, row)
+	}
+
+	page.Total = len(rows)
+	page.Facets = facets(rows)
+
+	sort.Slice(rows, func(i, j int) bool {
+		c := compareRows(rows[i].value, rows[i].manga.ID, rows[j].value, rows[j].manga.ID)
+		if q.Desc {
+			return c > 0
+		}
+		return c < 0
+	})
+
+	var last listRow
+	for _, row := range rows {
+		if q.Cursor != "" {
+			c := compareRows(row.value, row.manga.ID, cursorValue, cursorID)
+			if (!q.Desc && c <= 0) || (q.Desc && c >= 0) {
+				continue
+			}
+		}
+		if len(page.Manga) == q.Limit {
+			page.NextCursor = repository.EncodeCursor(q, last.value, last.manga.ID)
+			break
+		}
+		page.Manga = append(page.Manga, row.manga)
+		last = row
+	}
+	page.Count = len(page.Manga)
+
+	return page, nil
+}
+
+// matchesFilter applies every MangaQuery filter except the full-text search
+func matchesFilter(m models.Manga, q models.MangaQuery) bool {
+	if q.Title != "" && !strings.Contains(strings.ToLower(m.Title), strings.ToLower(q.Title)) {
+		return false
+	}
+	if q.Author != "" && !strings.Contains(strings.ToLower(m.Author), strings.ToLower(q.Author)) {
+		return false
+	}
+	for _, want := range q.Genres {
+		found := false
+		for _, g := range m.Genres {
+			if strings.EqualFold(g, strings.TrimSpace(want)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(q.Statuses) > 0 {
+		found := false
+		for _, s := range q.Statuses {
+			if strings.EqualFold(m.Status, s) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.MinChapters > 0 && m.TotalChapters < q.MinChapters {
+		return false
+	}
+	if q.MaxChapters > 0 && m.TotalChapters > q.MaxChapters {
+		return false
+	}
+	return true
+}
+
+// compareRows orders rows by sort value, then by ID
+func compareRows(av interface{}, aID string, bv interface{}, bID string) int {
+	var c int
+	switch a := av.(type) {
+	case string:
+		c = strings.Compare(a, bv.(string))
+	case int64:
+		b := bv.(int64)
+		c = compareOrdered(a, b)
+	case float64:
+		b := bv.(float64)
+		c = compareOrdered(a, b)
+	}
+	if c == 0 {
+		c = strings.Compare(aID, bID)
+	}
+	return c
+}
+
+func compareOrdered[T int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// facets counts genres and statuses across rows,
+// ordered by descending count and then by name
+func facets(rows []listRow) models.MangaFacets {
+	genres, statuses := map[string]int{}, map[string]int{}
+	for _, row := range rows {
+		for _, g := range row.manga.Genres {
+			genres[g]++
+		}
+		if row.manga.Status != "" {
+			statuses[row.manga.Status]++
+		}
+	}
+	return models.MangaFacets{Genres: facetCounts(genres), Statuses: facetCounts(statuses)}
+}
+
+func facetCounts(counts map[string]int) []models.FacetCount {
+	out := []models.FacetCount{}
+	for value, n := range counts {
+		out = append(out, models.FacetCount{Value: value, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out
+}
+
+// cloneManga copies m so callers cannot modify stored genre slices
+func cloneManga(m models.Manga) models.Manga {
+	m.Genres = append([]string{}, m.Genres...)
+	return m
+}
@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"mangahub/internal/repository"
+	"mangahub/pkg/models"
+)
+
+// OutboxRepository implements repository.OutboxRepository in memory
+type OutboxRepository struct {
+	s *state
+}
+
+// queueEvent adds an event to the outbox, due immediately.
+// The caller must hold the write lock.
+func (s *state) queueEvent(e models.OutboxEvent) {
+	now := time.Now().UTC()
+	e.Attempts = 0
+	e.DeliveredTo = []string{}
+	e.LastError = ""
+	e.NextAttemptAt = now
+	e.CreatedAt = now
+	e.FailedAt = nil
+	s.outbox[e.ID] = e
+}
+
+// Claim pushes the next attempt of due events back by lease and returns them
+func (r *OutboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := time.Now().UTC()
+	due := []models.OutboxEvent{}
+	for _, e := range r.s.outbox {
+		if !e.NextAttemptAt.After(now) {
+			due = append(due, e)
+		}
+	}
+	repository.SortOutbox(due)
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for i := range due {
+		due[i].NextAttemptAt = now.Add(lease)
+		e := due[i]
+		e.DeliveredTo = append([]string{}, e.DeliveredTo...)
+		r.s.outbox[e.ID] = e
+	}
+	return due, nil
+}
+
+// Retry records a failed delivery attempt
+func (r *OutboxRepository) Retry(ctx context.Context, e models.OutboxEvent) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.outbox[e.ID]
+	if !ok {
+		return nil
+	}
+	stored.Attempts = e.Attempts
+	stored.DeliveredTo = append([]string{}, e.DeliveredTo...)
+	stored.LastError = e.LastError
+	stored.NextAttemptAt = e.NextAttemptAt.UTC()
+	r.s.outbox[e.ID] = stored
+	return nil
+}
+
+// Done removes a delivered event
+func (r *OutboxRepository) Done(ctx context.Context, id string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.outbox, id)
+	return nil
+}
+
+// DeadLetter moves an event to the dead letters
+func (r *OutboxRepository) DeadLetter(ctx context.Context, e models.OutboxEvent) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	failedAt := time.Now().UTC()
+	e.DeliveredTo = append([]string{}, e.DeliveredTo...)
+	e.FailedAt = &failedAt
+	r.s.dead[e.ID] = e
+	delete(r.s.outbox, e.ID)
+	return nil
+}
+
+// DeadLetters lists dead letters, most recently failed first
+func (r *OutboxRepository) DeadLetters(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	events := []models.OutboxEvent{}
+	for _, e := range r.s.dead {
+		events = append(events, e)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].FailedAt.Equal(*events[j].FailedAt) {
+			return events[i].FailedAt.After(*events[j].FailedAt)
+		}
+		return events[i].ID < events[j].ID
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+// Requeue moves a dead letter back into the outbox, due immediately.
+// Subscribers that already received it are not sent it again.
+func (r *OutboxRepository) Requeue(ctx context.Context, id string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	e, ok := r.s.dead[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.s.dead, id)
+	e.Attempts = 0
+	e.NextAttemptAt = time.Now().UTC()
+	e.FailedAt = nil
+	r.s.outbox[id] = e
+	return nil
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"mangahub/internal/repository"
+	"mangahub/pkg/models"
+)
+
+// ProgressRepository implements repository.ProgressRepository in memory
+type ProgressRepository struct {
+	s *state
+}
+
+// AddToLibrary inserts the library entry or updates its status
+func (r *ProgressRepository) AddToLibrary(ctx context.Context, userID, mangaID, status string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	key := progressKey{userID, mangaID}
+	p, ok := r.s.progress[key]
+	if !ok {
+		p = models.UserProgress{UserID: userID, MangaID: mangaID, UpdatedAt: time.Now().UTC()}
+	}
+	p.Status = status
+	r.s.progress[key] = p
+	return nil
+}
+
+// Library lists a user's library, most recently updated first
+func (r *ProgressRepository) Library(ctx context.Context, userID string) ([]models.LibraryEntry, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	library := []models.LibraryEntry{}
+	for k, p := range r.s.progress {
+		m, ok := r.s.manga[k.mangaID]
+		if k.userID != userID || !ok {
+			continue
+		}
+		library = append(library, models.LibraryEntry{
+			MangaID:        m.ID,
+			Title:          m.Title,
+			CurrentChapter: p.CurrentChapter,
+			Status:         p.Status,
+			UpdatedAt:      p.UpdatedAt,
+			Deleted:        !r.s.deleted[k.mangaID].IsZero(),
+		})
+	}
+	sort.SliceStable(library, func(i, j int) bool {
+		if !library[i].UpdatedAt.Equal(library[j].UpdatedAt) {
+			return library[i].UpdatedAt.After(library[j].UpdatedAt)
+		}
+		return library[i].MangaID < library[j].MangaID
+	})
+	return library, nil
+}
+
+// UpdateProgress upserts the user's current chapter and, if given, status,
+// and queues event under the same lock
+func (r *ProgressRepository) UpdateProgress(ctx context.Context, p models.UserProgress, event models.OutboxEvent) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.outbox[event.ID]; ok {
+		return repository.ErrDuplicate
+	}
+
+	key := progressKey{p.UserID, p.MangaID}
+	stored, ok := r.s.progress[key]
+	if !ok {
+		stored = models.UserProgress{UserID: p.UserID, MangaID: p.MangaID, Status: "reading"}
+	}
+	stored.CurrentChapter = p.CurrentChapter
+	if p.Status != "" {
+		stored.Status = p.Status
+	}
+	stored.UpdatedAt = time.Now().UTC()
+	r.s.progress[key] = stored
+	r.s.queueEvent(event)
+	return nil
+}
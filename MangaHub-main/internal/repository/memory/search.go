@@ -0,0 +1,182 @@
+package memory
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"mangahub/internal/repository"
+	"mangahub/pkg/models"
+)
+
+// searchTerm is one word of a search query. A prefix term matches any word
+// starting with it; a corrected term must match a word exactly.
+type searchTerm struct {
+	text   string
+	prefix bool
+}
+
+// matcher scores manga against a parsed search query
+type matcher struct {
+	terms []searchTerm
+}
+
+// newMatcher parses a free-text query the same way the SQLite repository
+// does: every term must match, as a prefix, and a term that matches no word
+// in the catalog is replaced by the closest word within the typo distance.
+// It returns nil when the query has no searchable terms.
+// The caller must hold the read lock.
+func (r *MangaRepository) newMatcher(input string) *matcher {
+	vocab := map[string]int{}
+	for id, m := range r.s.manga {
+		if _, deleted := r.s.deleted[id]; deleted {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, field := range []string{m.Title, m.Author, m.Description} {
+			for _, w := range repository.SearchTerms(field) {
+				if !seen[w] {
+					seen[w] = true
+					vocab[w]++
+				}
+			}
+		}
+	}
+
+	var terms []searchTerm
+	for _, term := range repository.SearchTerms(input) {
+		if !hasPrefix(vocab, term) {
+			if fix := closestWord(vocab, term); fix != "" {
+				terms = append(terms, searchTerm{text: fix})
+				continue
+			}
+		}
+		terms = append(terms, searchTerm{text: term, prefix: true})
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	return &matcher{terms: terms}
+}
+
+// hasPrefix reports whether any word in vocab starts with term
+func hasPrefix(vocab map[string]int, term string) bool {
+	for w := range vocab {
+		if strings.HasPrefix(w, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// closestWord finds the word nearest to term by edit distance among words
+// with the same first letter, preferring words found in more manga
+func closestWord(vocab map[string]int, term string) string {
+	maxDist := repository.MaxTypoDistance(term)
+	if maxDist == 0 {
+		return ""
+	}
+	first, _ := utf8.DecodeRuneInString(term)
+	n := utf8.RuneCountInString(term)
+
+	best, bestDist, bestDocs := "", maxDist+1, 0
+	for w, docs := range vocab {
+		wFirst, _ := utf8.DecodeRuneInString(w)
+		wn := utf8.RuneCountInString(w)
+		if wFirst != first || wn < n-maxDist || wn > n+maxDist {
+			continue
+		}
+		d := repository.EditDistance(term, w)
+		if d < bestDist || (d == bestDist && (docs > bestDocs || (docs == bestDocs && w < best))) {
+			best, bestDist, bestDocs = w, d, docs
+		}
+	}
+	return best
+}
+
+// matches reports whether word satisfies t
+func (t searchTerm) matches(word string) bool {
+	if t.prefix {
+		return strings.HasPrefix(word, t.text)
+	}
+	return word == t.text
+}
+
+// match scores m, or returns nil unless every term matches somewhere.
+// Each term adds the weights of the fields it occurs in.
+func (mt *matcher) match(m models.Manga) *models.SearchMatch {
+	fields := []struct {
+		text   string
+		weight float64
+	}{
+		{m.Title, repository.TitleWeight},
+		{m.Author, repository.AuthorWeight},
+		{m.Description, repository.DescriptionWeight},
+	}
+
+	var score float64
+	for _, t := range mt.terms {
+		found := false
+		for _, f := range fields {
+			for _, w := range repository.SearchTerms(f.text) {
+				if t.matches(w) {
+					score += f.weight
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+
+	match := &models.SearchMatch{Score: score, Title: mt.highlight(m.Title)}
+	for _, f := range fields {
+		if h := mt.highlight(f.text); h != f.text {
+			match.Snippet = h
+			break
+		}
+	}
+	return match
+}
+
+// highlight wraps every word of text that matches a term in highlight markers
+func (mt *matcher) highlight(text string) string {
+	var b strings.Builder
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !isWord(r) {
+			b.WriteString(text[i : i+size])
+			i += size
+			continue
+		}
+		j := i
+		for j < len(text) {
+			r, size := utf8.DecodeRuneInString(text[j:])
+			if !isWord(r) {
+				break
+			}
+			j += size
+		}
+		word := text[i:j]
+		if mt.matchesAny(strings.ToLower(word)) {
+			b.WriteString(repository.HighlightOpen + word + repository.HighlightClose)
+		} else {
+			b.WriteString(word)
+		}
+		i = j
+	}
+	return b.String()
+}
+
+func (mt *matcher) matchesAny(word string) bool {
+	for _, t := range mt.terms {
+		if t.matches(word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"mangahub/internal/repository"
+	"mangahub/pkg/models"
+)
+
+// session is a stored session with its refresh token hashes
+type session struct {
+	models.Session
+	refreshHash  string
+	previousHash string
+	revoked      bool
+}
+
+// active reports whether the session can still be used at now
+func (s *session) active(now time.Time) bool {
+	return !s.revoked && s.ExpiresAt.After(now)
+}
+
+// SessionRepository implements repository.SessionRepository in memory
+type SessionRepository struct {
+	s *state
+}
+
+// Create starts a session
+func (r *SessionRepository) Create(ctx context.Context, s models.Session, refreshHash string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.sessions[s.ID]; ok {
+		return repository.ErrDuplicate
+	}
+	for _, existing := range r.s.sessions {
+		if existing.refreshHash == refreshHash {
+			return repository.ErrDuplicate
+		}
+	}
+	now := time.Now().UTC()
+	s.CreatedAt, s.LastUsedAt, s.Current = now, now, false
+	r.s.sessions[s.ID] = &session{Session: s, refreshHash: refreshHash}
+	return nil
+}
+
+// Rotate swaps the refresh token of an active session
+func (r *SessionRepository) Rotate(ctx context.Context, refreshHash, newHash string, expiresAt time.Time) (models.Session, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, s := range r.s.sessions {
+		if s.refreshHash == refreshHash && s.active(now) {
+			s.previousHash, s.refreshHash = s.refreshHash, newHash
+			s.LastUsedAt, s.ExpiresAt = now, expiresAt
+			return s.Session, nil
+		}
+	}
+	for _, s := range r.s.sessions {
+		if s.previousHash == refreshHash {
+			s.revoked = true
+		}
+	}
+	return models.Session{}, repository.ErrNotFound
+}
+
+// Active reports whether a session is neither revoked nor expired
+func (r *SessionRepository) Active(ctx context.Context, id string) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	s, ok := r.s.sessions[id]
+	return ok && s.active(time.Now()), nil
+}
+
+// List returns a user's active sessions
+func (r *SessionRepository) List(ctx context.Context, userID string) ([]models.Session, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	now := time.Now()
+	sessions := []models.Session{}
+	for _, s := range r.s.sessions {
+		if s.UserID == userID && s.active(now) {
+			sessions = append(sessions, s.Session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastUsedAt.Equal(sessions[j].LastUsedAt) {
+			return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions, nil
+}
+
+// Revoke ends one of a user's sessions
+func (r *SessionRepository) Revoke(ctx context.Context, userID, id string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	s, ok := r.s.sessions[id]
+	if !ok || s.UserID != userID || !s.active(time.Now()) {
+		return repository.ErrNotFound
+	}
+	s.revoked = true
+	return nil
+}
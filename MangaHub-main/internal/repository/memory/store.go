@@ -0,0 +1,108 @@
+// Package memory provides repositories that keep everything in process memory.
+// They behave like the SQL-backed ones and are meant for development, demos
+// and tests; nothing survives a restart.
+package memory
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"mangahub/internal/repository"
+	"mangahub/pkg/models"
+)
+
+// progressKey identifies one library entry
+type progressKey struct {
+	userID  string
+	mangaID string
+}
+
+// state is the data shared by the repositories of one store
+type state struct {
+	mu sync.RWMutex
+
+	manga      map[string]models.Manga
+	deleted    map[string]time.Time // Soft-deleted manga, still present in manga
+	genres     []string             // Genre names indexed by ID-1, in creation order
+	genreIndex map[string]int       // Lowercased genre name to ID
+	users      map[string]models.User
+	progress   map[progressKey]models.UserProgress
+	audit      []models.AuditEntry
+	sessions   map[string]*session
+	outbox     map[string]models.OutboxEvent
+	dead       map[string]models.OutboxEvent // Dead letters
+}
+
+// NewStore returns empty in-memory repositories sharing one data set
+func NewStore() *repository.Store {
+	s := &state{
+		manga:      map[string]models.Manga{},
+		deleted:    map[string]time.Time{},
+		genreIndex: map[string]int{},
+		users:      map[string]models.User{},
+		progress:   map[progressKey]models.UserProgress{},
+		sessions:   map[string]*session{},
+		outbox:     map[string]models.OutboxEvent{},
+		dead:       map[string]models.OutboxEvent{},
+	}
+	return &repository.Store{
+		Manga:    &MangaRepository{s},
+		Users:    &UserRepository{s},
+		Progress: &ProgressRepository{s},
+		Audit:    &AuditRepository{s},
+		Sessions: &SessionRepository{s},
+		Outbox:   &OutboxRepository{s},
+	}
+}
+
+// genreID returns the ID of a genre, registering it if needed.
+// Names compare case-insensitively; the first spelling seen is kept.
+// The caller must hold the write lock.
+func (s *state) genreID(name string) int {
+	key := strings.ToLower(name)
+	if id, ok := s.genreIndex[key]; ok {
+		return id
+	}
+	s.genres = append(s.genres, name)
+	s.genreIndex[key] = len(s.genres)
+	return len(s.genres)
+}
+
+// live returns a manga unless it is missing or deleted.
+// The caller must hold the read lock.
+func (s *state) live(id string) (models.Manga, bool) {
+	m, ok := s.manga[id]
+	if _, deleted := s.deleted[id]; deleted {
+		return m, false
+	}
+	return m, ok
+}
+
+// recordAudit appends a manga change attributed to the context's actor.
+// The caller must hold the write lock.
+func (s *state) recordAudit(ctx context.Context, mangaID, action string, changes map[string]models.FieldChange) {
+	if len(changes) == 0 {
+		changes = nil
+	}
+	s.audit = append(s.audit, models.AuditEntry{
+		ID:        int64(len(s.audit) + 1),
+		Entity:    models.AuditEntityManga,
+		EntityID:  mangaID,
+		Action:    action,
+		ActorID:   repository.Actor(ctx),
+		Changes:   changes,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+// popularity counts the library entries per manga.
+// The caller must hold the read lock.
+func (s *state) popularity() map[string]int {
+	counts := map[string]int{}
+	for k := range s.progress {
+		counts[k.mangaID]++
+	}
+	return counts
+}
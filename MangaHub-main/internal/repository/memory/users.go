@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"mangahub/internal/repository"
+	"mangahub/pkg/models"
+)
+
+// UserRepository implements repository.UserRepository in memory
+type UserRepository struct {
+	s *state
+}
+
+// Create adds a user account
+func (r *UserRepository) Create(ctx context.Context, u models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, existing := range r.s.users {
+		if existing.ID == u.ID || existing.Username == u.Username || existing.Email == u.Email {
+			return repository.ErrDuplicate
+		}
+	}
+	if u.Role == "" {
+		u.Role = "user"
+	}
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now().UTC()
+	}
+	r.s.users[u.ID] = u
+	return nil
+}
+
+// GetByID returns a user by ID
+func (r *UserRepository) GetByID(ctx context.Context, id string) (models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	u, ok := r.s.users[id]
+	if !ok {
+		return u, repository.ErrNotFound
+	}
+	return u, nil
+}
+
+// GetByUsername returns a user by username
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, u := range r.s.users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	return models.User{}, repository.ErrNotFound
+}
+
+// SetRole changes a user's role
+func (r *UserRepository) SetRole(ctx context.Context, id, role string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	u, ok := r.s.users[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	u.Role = role
+	r.s.users[id] = u
+	return nil
+}
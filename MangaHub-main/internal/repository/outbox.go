@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+
+	"mangahub/pkg/models"
+)
+
+// JoinSubscribers encodes OutboxEvent.DeliveredTo for the delivered_to column
+func JoinSubscribers(names []string) string {
+	return strings.Join(names, ",")
+}
+
+// SplitSubscribers decodes the delivered_to column
+func SplitSubscribers(column string) []string {
+	if column == "" {
+		return []string{}
+	}
+	return strings.Split(column, ",")
+}
+
+// SortOutbox orders events oldest first, the order they are delivered in
+func SortOutbox(events []models.OutboxEvent) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].CreatedAt.Equal(events[j].CreatedAt) {
+			return events[i].CreatedAt.Before(events[j].CreatedAt)
+		}
+		return events[i].ID < events[j].ID
+	})
+}
@@ -0,0 +1,436 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"mangahub/internal/repository"
+	"mangahub/pkg/models"
+)
+
+// MangaRepository implements repository.MangaRepository on PostgreSQL
+type MangaRepository struct {
+	db *sql.DB
+}
+
+// mangaSortColumns maps public sort keys to SQL expressions over manga m.
+// Titles compare bytewise like SQLite, and relevance is negated so that
+// ascending order puts the best match first, as with SQLite's bm25().
+var mangaSortColumns = map[string]string{
+	"title":          `m.title COLLATE "C"`,
+	"total_chapters": "COALESCE(m.total_chapters, 0)",
+	"popularity":     "(SELECT COUNT(*) FROM user_progress up WHERE up.manga_id = m.id)",
+	"relevance":      "-" + rankExpr,
+}
+
+// mangaGenresColumn selects the genres of manga m comma-joined in display order,
+// ready to be scanned into Manga.GenresString
+const mangaGenresColumn = `(SELECT string_agg(g.name, ',' ORDER BY mg.position)
+	FROM manga_genres mg JOIN genres g ON g.id = mg.genre_id
+	WHERE mg.manga_id = m.id)`
+
+// mangaColumns is the column list scanned by scanManga
+var mangaColumns = "m.id, m.title, m.author, COALESCE(" + mangaGenresColumn + ", ''), m.status, m.total_chapters, m.description"
+
+// scanManga reads one row selected with mangaColumns followed by any extra destinations
+func scanManga(row interface{ Scan(...interface{}) error }, m *models.Manga, extra ...interface{}) error {
+	dest := append([]interface{}{&m.ID, &m.Title, &m.Author, &m.GenresString, &m.Status, &m.TotalChapters, &m.Description}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	m.PostScan()
+	return nil
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Get loads a single manga with its genres
+func (r *MangaRepository) Get(ctx context.Context, id string) (models.Manga, error) {
+	return getManga(ctx, r.db, id)
+}
+
+// getManga loads a manga that is not deleted
+func getManga(ctx context.Context, q queryer, id string) (models.Manga, error) {
+	var m models.Manga
+	err := scanManga(q.QueryRowContext(ctx, "SELECT "+mangaColumns+" FROM manga m WHERE m.id = $1 AND m.deleted_at IS NULL", id), &m)
+	if err == sql.ErrNoRows {
+		return m, repository.ErrNotFound
+	}
+	return m, err
+}
+
+// Exists reports whether a manga with the given ID exists and is not deleted
+func (r *MangaRepository) Exists(ctx context.Context, id string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM manga WHERE id = $1 AND deleted_at IS NULL)", id).Scan(&exists)
+	return exists, err
+}
+
+// Create inserts a manga and links its genres in one transaction
+func (r *MangaRepository) Create(ctx context.Context, m models.Manga) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO manga (id, title, author, status, total_chapters, description) VALUES ($1, $2, $3, $4, $5, $6)",
+		m.ID, m.Title, m.Author, m.Status, m.TotalChapters, m.Description,
+	)
+	if isUniqueViolation(err) {
+		return repository.ErrDuplicate
+	} else if err != nil {
+		return err
+	}
+	if err := setMangaGenres(ctx, tx, m.ID, m.Genres); err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, tx, m.ID, models.AuditCreate, repository.CreateChanges(m)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Update applies patch to a manga and records the changed fields
+func (r *MangaRepository) Update(ctx context.Context, id string, patch models.MangaPatch) (models.Manga, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Manga{}, err
+	}
+	defer tx.Rollback()
+
+	current, err := getManga(ctx, tx, id)
+	if err != nil {
+		return current, err
+	}
+	m, changes := repository.ApplyPatch(current, patch)
+	if len(changes) == 0 {
+		return current, nil
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE manga SET title = $1, author = $2, status = $3, total_chapters = $4, description = $5 WHERE id = $6",
+		m.Title, m.Author, m.Status, m.TotalChapters, m.Description, id,
+	)
+	if err != nil {
+		return current, err
+	}
+	if _, ok := changes["genres"]; ok {
+		if err := setMangaGenres(ctx, tx, id, m.Genres); err != nil {
+			return current, err
+		}
+	}
+	if err := recordAudit(ctx, tx, id, models.AuditUpdate, changes); err != nil {
+		return current, err
+	}
+	if err := tx.Commit(); err != nil {
+		return current, err
+	}
+	return r.Get(ctx, id)
+}
+
+// Delete marks a manga as deleted. Its genres and user progress are kept.
+func (r *MangaRepository) Delete(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE manga SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrNotFound
+	}
+	if err := recordAudit(ctx, tx, id, models.AuditDelete, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Restore clears the deleted mark of a manga. Restoring a manga that is not
+// deleted changes nothing.
+func (r *MangaRepository) Restore(ctx context.Context, id string) (models.Manga, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Manga{}, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE manga SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return models.Manga{}, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return models.Manga{}, err
+	} else if n > 0 {
+		if err := recordAudit(ctx, tx, id, models.AuditRestore, nil); err != nil {
+			return models.Manga{}, err
+		}
+	}
+	m, err := getManga(ctx, tx, id)
+	if err != nil {
+		return m, err
+	}
+	return m, tx.Commit()
+}
+
+// setMangaGenres replaces the genres linked to a manga, creating any genre
+// that does not exist yet. Order is kept for display; duplicates are dropped.
+func setMangaGenres(ctx context.Context, tx *sql.Tx, mangaID string, genres []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM manga_genres WHERE manga_id = $1", mangaID); err != nil {
+		return err
+	}
+
+	for i, name := range models.NormalizeGenres(genres) {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO genres (name) SELECT $1::text
+			WHERE NOT EXISTS (SELECT 1 FROM genres WHERE LOWER(name) = LOWER($1))
+			ON CONFLICT DO NOTHING
+		`, name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO manga_genres (manga_id, genre_id, position)
+			SELECT $1::text, id, $2::int FROM genres WHERE LOWER(name) = LOWER($3)
+		`, mangaID, i, name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Genres returns every genre with the number of manga tagged with it
+func (r *MangaRepository) Genres(ctx context.Context) ([]models.Genre, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT g.id, g.name, COUNT(m.id)
+		FROM genres g
+		LEFT JOIN manga_genres mg ON mg.genre_id = g.id
+		LEFT JOIN manga m ON m.id = mg.manga_id AND m.deleted_at IS NULL
+		GROUP BY g.id
+		ORDER BY LOWER(g.name) COLLATE "C"
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	genres := []models.Genre{}
+	for rows.Next() {
+		var g models.Genre
+		if err := rows.Scan(&g.ID, &g.Name, &g.MangaCount); err != nil {
+			return nil, err
+		}
+		genres = append(genres, g)
+	}
+	return genres, rows.Err()
+}
+
+// likeEscape escapes LIKE wildcards so user input is matched literally
+func likeEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
+
+// mangaSelection is the FROM and WHERE clause shared by the page, count and
+// facet queries of one listing
+type mangaSelection struct {
+	from  string
+	where string
+	args  queryArgs
+}
+
+// mangaFilter builds the selection for q. ok is false when q.Search has no
+// searchable terms, in which case nothing can match.
+func (r *MangaRepository) mangaFilter(ctx context.Context, q models.MangaQuery) (sel mangaSelection, ok bool, err error) {
+	sel.from = "manga m"
+	conds := []string{"m.deleted_at IS NULL"}
+
+	if q.Search != "" {
+		tsquery, err := buildTSQuery(ctx, r.db, q.Search)
+		if err != nil || tsquery == "" {
+			return sel, false, err
+		}
+		sel.from = "manga m CROSS JOIN to_tsquery('simple', " + sel.args.add(tsquery) + ") AS query"
+		conds = append(conds, "m.search_vector @@ query")
+	}
+	if q.Title != "" {
+		conds = append(conds, `LOWER(m.title) LIKE LOWER(`+sel.args.add("%"+likeEscape(q.Title)+"%")+`) ESCAPE '\'`)
+	}
+	if q.Author != "" {
+		conds = append(conds, `LOWER(m.author) LIKE LOWER(`+sel.args.add("%"+likeEscape(q.Author)+"%")+`) ESCAPE '\'`)
+	}
+	// Genre names compare case-insensitively but must match exactly
+	for _, g := range q.Genres {
+		conds = append(conds, `EXISTS (SELECT 1 FROM manga_genres mg JOIN genres g ON g.id = mg.genre_id
+			WHERE mg.manga_id = m.id AND LOWER(g.name) = LOWER(`+sel.args.add(strings.TrimSpace(g))+`))`)
+	}
+	if len(q.Statuses) > 0 {
+		var placeholders []string
+		for _, s := range q.Statuses {
+			placeholders = append(placeholders, sel.args.add(strings.ToLower(s)))
+		}
+		conds = append(conds, "LOWER(m.status) IN ("+strings.Join(placeholders, ",")+")")
+	}
+	if q.MinChapters > 0 {
+		conds = append(conds, "COALESCE(m.total_chapters, 0) >= "+sel.args.add(q.MinChapters))
+	}
+	if q.MaxChapters > 0 {
+		conds = append(conds, "COALESCE(m.total_chapters, 0) <= "+sel.args.add(q.MaxChapters))
+	}
+
+	sel.where = strings.Join(conds, " AND ")
+	return sel, true, nil
+}
+
+// List returns one page of the catalog matching q, together with the
+// total match count and genre/status facet counts over the whole filtered set.
+// When q.Search is set, results carry a SearchMatch ranked by ts_rank.
+// Pagination is keyset based on (sort value, id).
+func (r *MangaRepository) List(ctx context.Context, q models.MangaQuery) (*models.MangaPage, error) {
+	q = repository.NormalizeQuery(q)
+	sortExpr, ok := mangaSortColumns[q.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort key %q", q.Sort)
+	}
+
+	page := &models.MangaPage{
+		Manga:  []models.Manga{},
+		Facets: models.MangaFacets{Genres: []models.FacetCount{}, Statuses: []models.FacetCount{}},
+	}
+
+	sel, ok, err := r.mangaFilter(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return page, nil
+	}
+
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+sel.from+" WHERE "+sel.where, sel.args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	if page.Facets, err = r.facets(ctx, sel); err != nil {
+		return nil, err
+	}
+
+	cmp, dir := ">", "ASC"
+	if q.Desc {
+		cmp, dir = "<", "DESC"
+	}
+
+	pageWhere, pageArgs := sel.where, append(queryArgs{}, sel.args...)
+	if q.Cursor != "" {
+		value, id, err := repository.DecodeCursor(q)
+		if err != nil {
+			return nil, err
+		}
+		v, idArg := pageArgs.add(value), pageArgs.add(id)
+		pageWhere += fmt.Sprintf(` AND (%[1]s %[2]s %[3]s OR (%[1]s = %[3]s AND m.id COLLATE "C" %[2]s %[4]s))`, sortExpr, cmp, v, idArg)
+	}
+
+	columns := mangaColumns + ", " + sortExpr
+	if q.Search != "" {
+		columns += ", " + searchColumns
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE %s
+		ORDER BY %s %s, m.id COLLATE "C" %[5]s
+		LIMIT %s`, columns, sel.from, pageWhere, sortExpr, dir, pageArgs.add(q.Limit+1))
+
+	rows, err := r.db.QueryContext(ctx, query, pageArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sortValues []interface{}
+	for rows.Next() {
+		var m models.Manga
+		var sortValue interface{}
+		extra := []interface{}{&sortValue}
+		if q.Search != "" {
+			m.Match = &models.SearchMatch{}
+			extra = append(extra, &m.Match.Score, &m.Match.Title, &m.Match.Snippet)
+		}
+		if err := scanManga(rows, &m, extra...); err != nil {
+			return nil, err
+		}
+		page.Manga = append(page.Manga, m)
+		sortValues = append(sortValues, sortValue)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Manga) > q.Limit {
+		page.Manga = page.Manga[:q.Limit]
+		page.NextCursor = repository.EncodeCursor(q, sortValues[q.Limit-1], page.Manga[q.Limit-1].ID)
+	}
+	page.Count = len(page.Manga)
+
+	return page, nil
+}
+
+// facets counts genres and statuses across every manga in sel,
+// ordered by descending count and then by name
+func (r *MangaRepository) facets(ctx context.Context, sel mangaSelection) (models.MangaFacets, error) {
+	facets := models.MangaFacets{Genres: []models.FacetCount{}, Statuses: []models.FacetCount{}}
+
+	var err error
+	facets.Genres, err = r.facetCounts(ctx, `
+		SELECT g.name, COUNT(*)
+		FROM `+sel.from+`
+		JOIN manga_genres mg ON mg.manga_id = m.id
+		JOIN genres g ON g.id = mg.genre_id
+		WHERE `+sel.where+`
+		GROUP BY g.id
+		ORDER BY COUNT(*) DESC, LOWER(g.name) COLLATE "C"`, sel.args)
+	if err != nil {
+		return facets, err
+	}
+
+	facets.Statuses, err = r.facetCounts(ctx, `
+		SELECT m.status, COUNT(*)
+		FROM `+sel.from+`
+		WHERE `+sel.where+` AND COALESCE(m.status, '') <> ''
+		GROUP BY m.status
+		ORDER BY COUNT(*) DESC, m.status COLLATE "C"`, sel.args)
+	return facets, err
+}
+
+// facetCounts runs a (value, count) aggregate query
+func (r *MangaRepository) facetCounts(ctx context.Context, query string, args []interface{}) ([]models.FacetCount, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.FacetCount{}
+	for rows.Next() {
+		var f models.FacetCount
+		if err := rows.Scan(&f.Value, &f.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, f)
+	}
+	return counts, rows.Err()
+}
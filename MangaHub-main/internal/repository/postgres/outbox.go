@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"mangahub/internal/repository"
+	"mangahub/pkg/models"
+)
+
+// OutboxRepository implements repository.OutboxRepository on PostgreSQL
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+// insertOutbox queues an event inside tx, due immediately
+func insertOutbox(ctx context.Context, tx *sql.Tx, e models.OutboxEvent) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO outbox (id, topic, payload, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+	`, e.ID, e.Topic, string(e.Payload))
+	if isUniqueViolation(err) {
+		return repository.ErrDuplicate
+	}
+	return err
+}
+
+// Claim pushes the next attempt of due events back by lease and returns
+// them. SKIP LOCKED lets several dispatchers claim concurrently.
+func (r *OutboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH due AS (
+			SELECT id FROM outbox WHERE next_attempt_at <= NOW()
+			ORDER BY created_at, id LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE outbox SET next_attempt_at = NOW() + $1::bigint * INTERVAL '1 millisecond'
+		FROM due WHERE outbox.id = due.id
+		RETURNING outbox.id, outbox.topic, outbox.payload, outbox.attempts, outbox.delivered_to,
+			COALESCE(outbox.last_error, ''), outbox.next_attempt_at, outbox.created_at
+	`, lease.Milliseconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	for rows.Next() {
+		var e models.OutboxEvent
+		var payload []byte
+		var delivered string
+		if err := rows.Scan(&e.ID, &e.Topic, &payload, &e.Attempts, &delivered, &e.LastError, &e.NextAttemptAt, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Payload = payload
+		e.DeliveredTo = repository.SplitSubscribers(delivered)
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// RETURNING does not follow the CTE's order
+	repository.SortOutbox(events)
+	return events, nil
+}
+
+// Retry records a failed delivery attempt
+func (r *OutboxRepository) Retry(ctx context.Context, e models.OutboxEvent) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE outbox SET attempts = $1, delivered_to = $2, last_error = $3, next_attempt_at = $4
+		WHERE id = $5
+	`, e.Attempts, repository.JoinSubscribers(e.DeliveredTo), e.LastError, e.NextAttemptAt, e.ID)
+	return err
+}
+
+// Done deletes a delivered event
+func (r *OutboxRepository) Done(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM outbox WHERE id = $1", id)
+	return err
+}
+
+// DeadLetter moves an event to outbox_dead_letters in one transaction
+func (r *OutboxRepository) DeadLetter(ctx context.Context, e models.OutboxEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO outbox_dead_letters (id, topic, payload, attempts, delivered_to, last_error, created_at, failed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (id) DO UPDATE SET
+			attempts = excluded.attempts,
+			delivered_to = excluded.delivered_to,
+			last_error = excluded.last_error,
+			failed_at = excluded.failed_at
+	`, e.ID, e.Topic, string(e.Payload), e.Attempts, repository.JoinSubscribers(e.DeliveredTo), e.LastError, e.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM outbox WHERE id = $1", e.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeadLetters lists dead letters, most recently failed first
+func (r *OutboxRepository) DeadLetters(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, topic, payload, attempts, delivered_to, COALESCE(last_error, ''), created_at, failed_at
+		FROM outbox_dead_letters
+		ORDER BY failed_at DESC, id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	for rows.Next() {
+		var e models.OutboxEvent
+		var payload []byte
+		var delivered string
+		var failedAt time.Time
+		if err := rows.Scan(&e.ID, &e.Topic, &payload, &e.Attempts, &delivered, &e.LastError, &e.CreatedAt, &failedAt); err != nil {
+			return nil, err
+		}
+		e.Payload = payload
+		e.DeliveredTo = repository.SplitSubscribers(delivered)
+		e.FailedAt = &failedAt
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// Requeue moves a dead letter back into the outbox, due immediately.
+// Subscribers that already received it are not sent it again.
+func (r *OutboxRepository) Requeue(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO outbox (id, topic, payload, attempts, delivered_to, last_error, next_attempt_at, created_at)
+		SELECT id, topic, payload, 0, delivered_to, last_error, NOW(), created_at
+		FROM outbox_dead_letters WHERE id = $1
+	`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrNotFound
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM outbox_dead_letters WHERE id = $1", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"mangahub/pkg/models"
+)
+
+// ProgressRepository implements repository.ProgressRepository on PostgreSQL
+type ProgressRepository struct {
+	db *sql.DB
+}
+
+// AddToLibrary inserts the library entry or updates its status
+func (r *ProgressRepository) AddToLibrary(ctx context.Context, userID, mangaID, status string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO user_progress (user_id, manga_id, current_chapter, status)
+		VALUES ($1, $2, 0, $3)
+		ON CONFLICT (user_id, manga_id) DO UPDATE SET status = excluded.status
+	`, userID, mangaID, status)
+	return err
+}
+
+// Library lists a user's library joined with manga titles
+func (r *ProgressRepository) Library(ctx context.Context, userID string) ([]models.LibraryEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT m.id, m.title, up.current_chapter, up.status, up.updated_at, m.deleted_at IS NOT NULL
+		FROM user_progress up
+		JOIN manga m ON up.manga_id = m.id
+		WHERE up.user_id = $1
+		ORDER BY up.updated_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	library := []models.LibraryEntry{}
+	for rows.Next() {
+		var e models.LibraryEntry
+		if err := rows.Scan(&e.MangaID, &e.Title, &e.CurrentChapter, &e.Status, &e.UpdatedAt, &e.Deleted); err != nil {
+			return nil, err
+		}
+		library = append(library, e)
+	}
+	return library, rows.Err()
+}
+
+// UpdateProgress upserts the user's current chapter and, if given, status,
+// and queues event in the same transaction
+func (r *ProgressRepository) UpdateProgress(ctx context.Context, p models.UserProgress, event models.OutboxEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO user_progress (user_id, manga_id, current_chapter, status, updated_at)
+		VALUES ($1, $2, $3, COALESCE(NULLIF($4, ''), 'reading'), CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id, manga_id) DO UPDATE SET
+			current_chapter = excluded.current_chapter,
+			status = COALESCE(NULLIF($4, ''), user_progress.status),
+			updated_at = CURRENT_TIMESTAMP
+	`, p.UserID, p.MangaID, p.CurrentChapter, p.Status)
+	if err != nil {
+		return err
+	}
+	if err := insertOutbox(ctx, tx, event); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
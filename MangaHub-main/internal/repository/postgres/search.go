@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"mangahub/internal/repository"
+)
+
+// rankWeights are the ts_rank weights for the {D, C, B, A} labels; the search
+// vector labels title A, author B and description C
+var rankWeights = fmt.Sprintf("'{0, %.2f, %.2f, %.2f}'",
+	repository.DescriptionWeight/repository.TitleWeight,
+	repository.AuthorWeight/repository.TitleWeight,
+	1.0)
+
+// rankExpr scores m against the search query joined as query
+var rankExpr = "ts_rank(" + rankWeights + ", m.search_vector, query)::float8"
+
+// headlineOptions wraps matches in the shared highlight markers
+const headlineOptions = "StartSel=" + repository.HighlightOpen + ", StopSel=" + repository.HighlightClose
+
+// searchColumns are the extra result columns selected when a listing is a search
+var searchColumns = rankExpr + `,
+	ts_headline('simple', m.title, query, '` + headlineOptions + `, HighlightAll=true'),
+	ts_headline('simple', concat_ws(' ', m.title, m.author, m.description), query,
+		'` + headlineOptions + `, MaxWords=16, MinWords=6, MaxFragments=1, FragmentDelimiter=…')`
+
+// searchVocab lists every indexed word with the number of manga containing it
+const searchVocab = `ts_stat('SELECT search_vector FROM manga')`
+
+// buildTSQuery turns free text into a to_tsquery expression.
+// Every term is matched as a prefix, and all terms must match. A term that
+// matches nothing in the index is replaced by the closest indexed word, so
+// small typos ("narto", "attak") still find results. It returns "" when the
+// input contains no searchable terms.
+func buildTSQuery(ctx context.Context, db *sql.DB, input string) (string, error) {
+	var parts []string
+	for _, term := range repository.SearchTerms(input) {
+		found, err := hasIndexedPrefix(ctx, db, term)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			if fix, err := closestIndexedTerm(ctx, db, term); err != nil {
+				return "", err
+			} else if fix != "" {
+				parts = append(parts, fix)
+				continue
+			}
+		}
+		parts = append(parts, term+":*")
+	}
+	return strings.Join(parts, " & "), nil
+}
+
+// hasIndexedPrefix reports whether any indexed word starts with term
+func hasIndexedPrefix(ctx context.Context, db *sql.DB, term string) (bool, error) {
+	var found bool
+	err := db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM `+searchVocab+` WHERE starts_with(word, $1))`, term,
+	).Scan(&found)
+	return found, err
+}
+
+// closestIndexedTerm finds the indexed word nearest to term by edit distance.
+// Candidates share the first letter and have a similar length; typos in the
+// first letter are not corrected.
+func closestIndexedTerm(ctx context.Context, db *sql.DB, term string) (string, error) {
+	maxDist := repository.MaxTypoDistance(term)
+	if maxDist == 0 {
+		return "", nil
+	}
+
+	first, _ := utf8.DecodeRuneInString(term)
+	n := utf8.RuneCountInString(term)
+	rows, err := db.QueryContext(ctx,
+		`SELECT word FROM `+searchVocab+`
+		 WHERE left(word, 1) = $1 AND char_length(word) BETWEEN $2 AND $3
+		 ORDER BY ndoc DESC, word`,
+		string(first), n-maxDist, n+maxDist,
+	)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	best, bestDist := "", maxDist+1
+	for rows.Next() {
+		var candidate string
+		if err := rows.Scan(&candidate); err != nil {
+			return "", err
+		}
+		if d := repository.EditDistance(term, candidate); d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+	return best, rows.Err()
+}
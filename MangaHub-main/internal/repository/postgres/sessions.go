@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"mangahub/internal/repository"
+	"mangahub/pkg/models"
+)
+
+// SessionRepository implements repository.SessionRepository on PostgreSQL
+type SessionRepository struct {
+	db *sql.DB
+}
+
+const sessionColumns = "id, user_id, COALESCE(user_agent, ''), COALESCE(ip_address, ''), created_at, last_used_at, expires_at"
+
+// Create starts a session
+func (r *SessionRepository) Create(ctx context.Context, s models.Session, refreshHash string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO sessions (id, user_id, refresh_hash, user_agent, ip_address, created_at, last_used_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW(), $6)
+	`, s.ID, s.UserID, refreshHash, s.UserAgent, s.IPAddress, s.ExpiresAt)
+	if isUniqueViolation(err) {
+		return repository.ErrDuplicate
+	}
+	return err
+}
+
+// Rotate swaps the refresh token of an active session
+func (r *SessionRepository) Rotate(ctx context.Context, refreshHash, newHash string, expiresAt time.Time) (models.Session, error) {
+	var s models.Session
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE sessions
+		SET previous_hash = refresh_hash, refresh_hash = $1, last_used_at = NOW(), expires_at = $2
+		WHERE refresh_hash = $3 AND revoked_at IS NULL AND expires_at > NOW()
+		RETURNING `+sessionColumns,
+		newHash, expiresAt, refreshHash).
+		Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt)
+	if err == sql.ErrNoRows {
+		_, err := r.db.ExecContext(ctx, "UPDATE sessions SET revoked_at = NOW() WHERE previous_hash = $1 AND revoked_at IS NULL", refreshHash)
+		if err != nil {
+			return s, err
+		}
+		return s, repository.ErrNotFound
+	}
+	return s, err
+}
+
+// Active reports whether a session is neither revoked nor expired
+func (r *SessionRepository) Active(ctx context.Context, id string) (bool, error) {
+	var active bool
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM sessions WHERE id = $1 AND revoked_at IS NULL AND expires_at > NOW()", id).
+		Scan(&active)
+	return active, err
+}
+
+// List returns a user's active sessions
+func (r *SessionRepository) List(ctx context.Context, userID string) ([]models.Session, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+sessionColumns+` FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC, id COLLATE "C"
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// Revoke ends one of a user's sessions
+func (r *SessionRepository) Revoke(ctx context.Context, userID, id string) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > NOW()",
+		id, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+// Package postgres implements the repositories on PostgreSQL through lib/pq.
+// Queries mirror the sqlite package; differences are limited to placeholder
+// syntax, string aggregation, collation and the full-text search engine.
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"mangahub/internal/repository"
+
+	"github.com/lib/pq"
+)
+
+// NewStore returns PostgreSQL-backed repositories sharing db.
+// The schema must already be migrated, see database.Open.
+func NewStore(db *sql.DB) *repository.Store {
+	return &repository.Store{
+		Manga:    &MangaRepository{db: db},
+		Users:    &UserRepository{db: db},
+		Progress: &ProgressRepository{db: db},
+		Audit:    &AuditRepository{db: db},
+		Sessions: &SessionRepository{db: db},
+		Outbox:   &OutboxRepository{db: db},
+	}
+}
+
+// isUniqueViolation reports whether err is a unique_violation (SQLSTATE 23505)
+func isUniqueViolation(err error) bool {
+	var pe *pq.Error
+	return errors.As(err, &pe) && pe.Code == "23505"
+}
+
+// queryArgs collects query arguments and hands out their $n placeholders
+type queryArgs []interface{}
+
+// add appends v and returns its placeholder
+func (a *queryArgs) add(v interface{}) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
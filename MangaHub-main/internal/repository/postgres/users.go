@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"mangahub/internal/repository"
+	"mangahub/pkg/models"
+)
+
+// UserRepository implements repository.UserRepository on PostgreSQL
+type UserRepository struct {
+	db *sql.DB
+}
+
+// Create adds a user account
+func (r *UserRepository) Create(ctx context.Context, u models.User) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO users (id, username, email, password_hash, role) VALUES ($1, $2, $3, $4, COALESCE(NULLIF($5, ''), 'user'))`,
+		u.ID, u.Username, u.Email, u.PasswordHash, u.Role)
+	if isUniqueViolation(err) {
+		return repository.ErrDuplicate
+	}
+	return err
+}
+
+// GetByID returns a user by ID
+func (r *UserRepository) GetByID(ctx context.Context, id string) (models.User, error) {
+	return r.get(ctx, "id", id)
+}
+
+// GetByUsername returns a user by username
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (models.User, error) {
+	return r.get(ctx, "username", username)
+}
+
+// SetRole changes a user's role
+func (r *UserRepository) SetRole(ctx context.Context, id, role string) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE users SET role = $1 WHERE id = $2", role, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepository) get(ctx context.Context, column, value string) (models.User, error) {
+	var u models.User
+	err := r.db.QueryRowContext(ctx, "SELECT id, username, email, password_hash, role, created_at FROM users WHERE "+column+" = $1", value).
+		Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.Role, &u.CreatedAt)
+	if err == sql.ErrNoRows {
+		return u, repository.ErrNotFound
+	}
+	return u, err
+}
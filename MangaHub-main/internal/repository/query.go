@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"mangahub/pkg/models"
+)
+
+// Page size bounds for catalog listings
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ErrInvalidCursor is returned when a listing cursor cannot be decoded
+// or was issued for a different sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SortKeys are the accepted values of MangaQuery.Sort.
+// relevance only applies to searches and orders best matches first.
+var SortKeys = []string{"relevance", "title", "total_chapters", "popularity"}
+
+// ValidMangaSort reports whether key can be used as MangaQuery.Sort
+func ValidMangaSort(key string) bool {
+	for _, k := range SortKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeQuery fills in the default sort and page size and clamps the limit
+func NormalizeQuery(q models.MangaQuery) models.MangaQuery {
+	if q.Sort == "" || (q.Sort == "relevance" && q.Search == "") {
+		q.Sort = "title"
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultPageSize
+	}
+	if q.Limit > MaxPageSize {
+		q.Limit = MaxPageSize
+	}
+	return q
+}
+
+// Cursor is the decoded form of MangaPage.NextCursor.
+// It records the sort position of the last row of the previous page.
+type Cursor struct {
+	Sort  string          `json:"s"`
+	Desc  bool            `json:"d"`
+	Value json.RawMessage `json:"v"`
+	ID    string          `json:"id"`
+}
+
+// EncodeCursor returns an opaque cursor positioned after the row with the
+// given sort value and ID
+func EncodeCursor(q models.MangaQuery, value interface{}, id string) string {
+	v, _ := json.Marshal(value)
+	data, _ := json.Marshal(Cursor{Sort: q.Sort, Desc: q.Desc, Value: v, ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses q.Cursor and checks it was issued for q's sort order.
+// The sort value is returned as a string for title, float64 for relevance
+// and int64 for the numeric sorts.
+func DecodeCursor(q models.MangaQuery) (value interface{}, id string, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, "", ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" || c.Sort != q.Sort || c.Desc != q.Desc {
+		return nil, "", ErrInvalidCursor
+	}
+
+	switch q.Sort {
+	case "title":
+		var s string
+		err = json.Unmarshal(c.Value, &s)
+		value = s
+	case "relevance":
+		var f float64
+		err = json.Unmarshal(c.Value, &f)
+		value = f
+	default:
+		var n int64
+		err = json.Unmarshal(c.Value, &n)
+		value = n
+	}
+	if err != nil {
+		return nil, "", ErrInvalidCursor
+	}
+	return value, c.ID, nil
+}
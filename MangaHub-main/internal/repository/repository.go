@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"mangahub/pkg/models"
+)
+
+// Errors returned by every repository implementation
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrDuplicate = errors.New("already exists")
+)
+
+// MangaRepository stores the manga catalog.
+// Deleted manga are hidden from every read until restored. Writes are
+// recorded in the audit log under the context's Actor.
+type MangaRepository interface {
+	// Get returns a manga by ID, or ErrNotFound
+	Get(ctx context.Context, id string) (models.Manga, error)
+	// List returns one page of manga matching q with facet counts.
+	// It returns ErrInvalidCursor for a cursor it did not issue.
+	List(ctx context.Context, q models.MangaQuery) (*models.MangaPage, error)
+	// Create adds a manga with its genres, or returns ErrDuplicate if the ID is taken
+	Create(ctx context.Context, m models.Manga) error
+	// Update applies a partial update and returns the updated manga,
+	// or ErrNotFound. A patch that changes nothing is not audited.
+	Update(ctx context.Context, id string, patch models.MangaPatch) (models.Manga, error)
+	// Delete soft-deletes a manga, keeping user progress, or returns ErrNotFound
+	Delete(ctx context.Context, id string) error
+	// Restore undoes Delete and returns the manga, or ErrNotFound if it never existed
+	Restore(ctx context.Context, id string) (models.Manga, error)
+	// Exists reports whether a manga with the given ID exists
+	Exists(ctx context.Context, id string) (bool, error)
+	// Genres returns every genre with the number of manga tagged with it
+	Genres(ctx context.Context) ([]models.Genre, error)
+}
+
+// UserRepository stores user accounts
+type UserRepository interface {
+	// Create adds a user, or returns ErrDuplicate if the username or email is taken
+	Create(ctx context.Context, u models.User) error
+	// GetByID returns a user by ID, or ErrNotFound
+	GetByID(ctx context.Context, id string) (models.User, error)
+	// GetByUsername returns a user by username, or ErrNotFound
+	GetByUsername(ctx context.Context, username string) (models.User, error)
+	// SetRole changes a user's role, or returns ErrNotFound
+	SetRole(ctx context.Context, id, role string) error
+}
+
+// ProgressRepository stores each user's library and reading progress
+type ProgressRepository interface {
+	// AddToLibrary puts a manga in the user's library with the given status,
+	// keeping the current chapter if it is already there
+	AddToLibrary(ctx context.Context, userID, mangaID, status string) error
+	// Library lists the manga in a user's library with their progress,
+	// including deleted manga marked as such
+	Library(ctx context.Context, userID string) ([]models.LibraryEntry, error)
+	// UpdateProgress records the chapter a user has reached, adding the manga to
+	// their library if needed, and queues event in the outbox in the same
+	// transaction. An empty Status leaves the stored status unchanged.
+	UpdateProgress(ctx context.Context, p models.UserProgress, event models.OutboxEvent) error
+}
+
+// AuditRepository reads the audit log written by catalog changes
+type AuditRepository interface {
+	// List returns the changes to one entity, oldest first
+	List(ctx context.Context, entity, entityID string) ([]models.AuditEntry, error)
+}
+
+// SessionRepository stores login sessions. Only hashes of refresh tokens are
+// kept. A session is active until it is revoked or its ExpiresAt passes.
+type SessionRepository interface {
+	// Create starts a session whose refresh token has the given hash
+	Create(ctx context.Context, s models.Session, refreshHash string) error
+	// Rotate replaces the refresh token of the active session holding
+	// refreshHash, extends it to expiresAt and returns it, or ErrNotFound.
+	// Presenting a refresh token that was already rotated revokes its
+	// session, since the token must have been copied.
+	Rotate(ctx context.Context, refreshHash, newHash string, expiresAt time.Time) (models.Session, error)
+	// Active reports whether a session exists and is still active
+	Active(ctx context.Context, id string) (bool, error)
+	// List returns a user's active sessions, most recently used first
+	List(ctx context.Context, userID string) ([]models.Session, error)
+	// Revoke ends one of a user's active sessions, or returns ErrNotFound
+	Revoke(ctx context.Context, userID, id string) error
+}
+
+// OutboxRepository holds events waiting for delivery and the dead letters
+// that ran out of attempts. Events are written by the repositories whose
+// changes they describe, see ProgressRepository.UpdateProgress.
+type OutboxRepository interface {
+	// Claim returns up to limit events that are due, oldest first, and
+	// hides them from other claims until lease has passed
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error)
+	// Retry stores the Attempts, DeliveredTo, LastError and NextAttemptAt
+	// of an event whose delivery failed
+	Retry(ctx context.Context, e models.OutboxEvent) error
+	// Done removes a delivered event
+	Done(ctx context.Context, id string) error
+	// DeadLetter moves an event that ran out of attempts to the dead letters
+	DeadLetter(ctx context.Context, e models.OutboxEvent) error
+	// DeadLetters returns up to limit dead letters, most recent first
+	DeadLetters(ctx context.Context, limit int) ([]models.OutboxEvent, error)
+	// Requeue moves a dead letter back into the outbox with no attempts
+	// made, or returns ErrNotFound
+	Requeue(ctx context.Context, id string) error
+}
+
+// Store bundles the repositories of one storage backend
+type Store struct {
+	Manga    MangaRepository
+	Users    UserRepository
+	Progress ProgressRepository
+	Audit    AuditRepository
+	Sessions SessionRepository
+	Outbox   OutboxRepository
+}
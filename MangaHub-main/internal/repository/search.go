@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Search highlight markers wrapped around matched terms
+const (
+	HighlightOpen  = "<mark>"
+	HighlightClose = "</mark>"
+)
+
+// Field weights for ranking search matches in title, author and description
+const (
+	TitleWeight       = 10.0
+	AuthorWeight      = 5.0
+	DescriptionWeight = 1.0
+)
+
+// SearchTerms splits free text into lowercase word tokens
+func SearchTerms(input string) []string {
+	return strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// MaxTypoDistance is how many edits a search term may be from an indexed
+// word and still match it. Short terms must be spelled correctly.
+func MaxTypoDistance(term string) int {
+	switch n := utf8.RuneCountInString(term); {
+	case n < 4:
+		return 0
+	case n < 7:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// EditDistance is the Levenshtein distance between a and b
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"mangahub/internal/repository"
+	"mangahub/pkg/models"
+)
+
+// AuditRepository implements repository.AuditRepository on SQLite
+type AuditRepository struct {
+	db *sql.DB
+}
+
+// recordAudit appends a manga change to the audit log inside tx,
+// attributed to the context's actor
+func recordAudit(ctx context.Context, tx *sql.Tx, mangaID, action string, changes map[string]models.FieldChange) error {
+	var data interface{}
+	if len(changes) > 0 {
+		b, err := json.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO audit_log (entity, entity_id, action, actor_id, changes) VALUES (?, ?, ?, ?, ?)",
+		models.AuditEntityManga, mangaID, action, repository.Actor(ctx), data,
+	)
+	return err
+}
+
+// List returns the changes to one entity, oldest first
+func (r *AuditRepository) List(ctx context.Context, entity, entityID string) ([]models.AuditEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, entity, entity_id, action, actor_id, COALESCE(changes, ''), created_at
+		FROM audit_log
+		WHERE entity = ? AND entity_id = ?
+		ORDER BY id
+	`, entity, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		var changes string
+		if err := rows.Scan(&e.ID, &e.Entity, &e.EntityID, &e.Action, &e.ActorID, &changes, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if changes != "" {
+			if err := json.Unmarshal([]byte(changes), &e.Changes); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
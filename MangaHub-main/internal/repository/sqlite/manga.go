@@ -0,0 +1,437 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"mangahub/internal/repository"
+	"mangahub/pkg/models"
+)
+
+// MangaRepository implements repository.MangaRepository on SQLite
+type MangaRepository struct {
+	db *sql.DB
+}
+
+// mangaSortColumns maps public sort keys to SQL expressions over manga m
+var mangaSortColumns = map[string]string{
+	"title":          "m.title",
+	"total_chapters": "COALESCE(m.total_chapters, 0)",
+	"popularity":     "(SELECT COUNT(*) FROM user_progress up WHERE up.manga_id = m.id)",
+	"relevance":      "bm25(manga_fts, " + searchWeights + ")",
+}
+
+// mangaGenresColumn selects the genres of manga m comma-joined in display order,
+// ready to be scanned into Manga.GenresString
+const mangaGenresColumn = `(SELECT group_concat(g.name, ',' ORDER BY mg.position)
+	FROM manga_genres mg JOIN genres g ON g.id = mg.genre_id
+	WHERE mg.manga_id = m.id)`
+
+// mangaColumns is the column list scanned by scanManga
+var mangaColumns = "m.id, m.title, m.author, COALESCE(" + mangaGenresColumn + ", ''), m.status, m.total_chapters, m.description"
+
+// scanManga reads one row selected with mangaColumns followed by any extra destinations
+func scanManga(row interface{ Scan(...interface{}) error }, m *models.Manga, extra ...interface{}) error {
+	dest := append([]interface{}{&m.ID, &m.Title, &m.Author, &m.GenresString, &m.Status, &m.TotalChapters, &m.Description}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	m.PostScan()
+	return nil
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Get loads a single manga with its genres
+func (r *MangaRepository) Get(ctx context.Context, id string) (models.Manga, error) {
+	return getManga(ctx, r.db, id)
+}
+
+// getManga loads a manga that is not deleted
+func getManga(ctx context.Context, q queryer, id string) (models.Manga, error) {
+	var m models.Manga
+	err := scanManga(q.QueryRowContext(ctx, "SELECT "+mangaColumns+" FROM manga m WHERE m.id = ? AND m.deleted_at IS NULL", id), &m)
+	if err == sql.ErrNoRows {
+		return m, repository.ErrNotFound
+	}
+	return m, err
+}
+
+// Exists reports whether a manga with the given ID exists and is not deleted
+func (r *MangaRepository) Exists(ctx context.Context, id string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM manga WHERE id = ? AND deleted_at IS NULL)", id).Scan(&exists)
+	return exists, err
+}
+
+// Create inserts a manga and links its genres in one transaction
+func (r *MangaRepository) Create(ctx context.Context, m models.Manga) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO manga (id, title, author, status, total_chapters, description) VALUES (?, ?, ?, ?, ?, ?)",
+		m.ID, m.Title, m.Author, m.Status, m.TotalChapters, m.Description,
+	)
+	if isUniqueViolation(err) {
+		return repository.ErrDuplicate
+	} else if err != nil {
+		return err
+	}
+	if err := setMangaGenres(ctx, tx, m.ID, m.Genres); err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, tx, m.ID, models.AuditCreate, repository.CreateChanges(m)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Update applies patch to a manga and records the changed fields
+func (r *MangaRepository) Update(ctx context.Context, id string, patch models.MangaPatch) (models.Manga, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Manga{}, err
+	}
+	defer tx.Rollback()
+
+	current, err := getManga(ctx, tx, id)
+	if err != nil {
+		return current, err
+	}
+	m, changes := repository.ApplyPatch(current, patch)
+	if len(changes) == 0 {
+		return current, nil
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE manga SET title = ?, author = ?, status = ?, total_chapters = ?, description = ? WHERE id = ?",
+		m.Title, m.Author, m.Status, m.TotalChapters, m.Description, id,
+	)
+	if err != nil {
+		return current, err
+	}
+	if _, ok := changes["genres"]; ok {
+		if err := setMangaGenres(ctx, tx, id, m.Genres); err != nil {
+			return current, err
+		}
+	}
+	if err := recordAudit(ctx, tx, id, models.AuditUpdate, changes); err != nil {
+		return current, err
+	}
+	if err := tx.Commit(); err != nil {
+		return current, err
+	}
+	return r.Get(ctx, id)
+}
+
+// Delete marks a manga as deleted. Its genres and user progress are kept.
+func (r *MangaRepository) Delete(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE manga SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrNotFound
+	}
+	if err := recordAudit(ctx, tx, id, models.AuditDelete, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Restore clears the deleted mark of a manga. Restoring a manga that is not
+// deleted changes nothing.
+func (r *MangaRepository) Restore(ctx context.Context, id string) (models.Manga, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Manga{}, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE manga SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return models.Manga{}, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return models.Manga{}, err
+	} else if n > 0 {
+		if err := recordAudit(ctx, tx, id, models.AuditRestore, nil); err != nil {
+			return models.Manga{}, err
+		}
+	}
+	m, err := getManga(ctx, tx, id)
+	if err != nil {
+		return m, err
+	}
+	return m, tx.Commit()
+}
+
+// setMangaGenres replaces the genres linked to a manga, creating any genre
+// that does not exist yet. Order is kept for display; duplicates are dropped.
+func setMangaGenres(ctx context.Context, tx *sql.Tx, mangaID string, genres []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM manga_genres WHERE manga_id = ?", mangaID); err != nil {
+		return err
+	}
+
+	for i, name := range models.NormalizeGenres(genres) {
+		_, err := tx.ExecContext(ctx, "INSERT INTO genres (name) SELECT ? WHERE NOT EXISTS (SELECT 1 FROM genres WHERE name = ?)", name, name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO manga_genres (manga_id, genre_id, position)
+			SELECT ?, id, ? FROM genres WHERE name = ?
+		`, mangaID, i, name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Genres returns every genre with the number of manga tagged with it
+func (r *MangaRepository) Genres(ctx context.Context) ([]models.Genre, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT g.id, g.name, COUNT(m.id)
+		FROM genres g
+		LEFT JOIN manga_genres mg ON mg.genre_id = g.id
+		LEFT JOIN manga m ON m.id = mg.manga_id AND m.deleted_at IS NULL
+		GROUP BY g.id
+		ORDER BY g.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	genres := []models.Genre{}
+	for rows.Next() {
+		var g models.Genre
+		if err := rows.Scan(&g.ID, &g.Name, &g.MangaCount); err != nil {
+			return nil, err
+		}
+		genres = append(genres, g)
+	}
+	return genres, rows.Err()
+}
+
+// likeEscape escapes LIKE wildcards so user input is matched literally
+func likeEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
+
+// mangaSelection is the FROM and WHERE clause shared by the page, count and
+// facet queries of one listing
+type mangaSelection struct {
+	from  string
+	where string
+	args  []interface{}
+}
+
+// mangaFilter builds the selection for q. ok is false when q.Search has no
+// searchable terms, in which case nothing can match.
+func (r *MangaRepository) mangaFilter(ctx context.Context, q models.MangaQuery) (sel mangaSelection, ok bool, err error) {
+	sel.from = "manga m"
+	conds := []string{"m.deleted_at IS NULL"}
+
+	if q.Search != "" {
+		match, err := buildMatchQuery(ctx, r.db, q.Search)
+		if err != nil || match == "" {
+			return sel, false, err
+		}
+		sel.from = "manga m JOIN manga_fts ON manga_fts.rowid = m.rowid"
+		conds = append(conds, "manga_fts MATCH ?")
+		sel.args = append(sel.args, match)
+	}
+	if q.Title != "" {
+		conds = append(conds, `LOWER(m.title) LIKE LOWER(?) ESCAPE '\'`)
+		sel.args = append(sel.args, "%"+likeEscape(q.Title)+"%")
+	}
+	if q.Author != "" {
+		conds = append(conds, `LOWER(m.author) LIKE LOWER(?) ESCAPE '\'`)
+		sel.args = append(sel.args, "%"+likeEscape(q.Author)+"%")
+	}
+	// Genre names compare case-insensitively but must match exactly
+	for _, g := range q.Genres {
+		conds = append(conds, `EXISTS (SELECT 1 FROM manga_genres mg JOIN genres g ON g.id = mg.genre_id
+			WHERE mg.manga_id = m.id AND g.name = ?)`)
+		sel.args = append(sel.args, strings.TrimSpace(g))
+	}
+	if len(q.Statuses) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(q.Statuses)), ",")
+		conds = append(conds, "LOWER(m.status) IN ("+placeholders+")")
+		for _, s := range q.Statuses {
+			sel.args = append(sel.args, strings.ToLower(s))
+		}
+	}
+	if q.MinChapters > 0 {
+		conds = append(conds, "COALESCE(m.total_chapters, 0) >= ?")
+		sel.args = append(sel.args, q.MinChapters)
+	}
+	if q.MaxChapters > 0 {
+		conds = append(conds, "COALESCE(m.total_chapters, 0) <= ?")
+		sel.args = append(sel.args, q.MaxChapters)
+	}
+
+	sel.where = strings.Join(conds, " AND ")
+	return sel, true, nil
+}
+
+// List returns one page of the catalog matching q, together with the
+// total match count and genre/status facet counts over the whole filtered set.
+// When q.Search is set, results carry a SearchMatch ranked by BM25.
+// Pagination is keyset based on (sort value, id).
+func (r *MangaRepository) List(ctx context.Context, q models.MangaQuery) (*models.MangaPage, error) {
+	q = repository.NormalizeQuery(q)
+	sortExpr, ok := mangaSortColumns[q.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort key %q", q.Sort)
+	}
+
+	page := &models.MangaPage{
+		Manga:  []models.Manga{},
+		Facets: models.MangaFacets{Genres: []models.FacetCount{}, Statuses: []models.FacetCount{}},
+	}
+
+	sel, ok, err := r.mangaFilter(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return page, nil
+	}
+
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+sel.from+" WHERE "+sel.where, sel.args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	if page.Facets, err = r.facets(ctx, sel); err != nil {
+		return nil, err
+	}
+
+	cmp, dir := ">", "ASC"
+	if q.Desc {
+		cmp, dir = "<", "DESC"
+	}
+
+	pageWhere, pageArgs := sel.where, append([]interface{}{}, sel.args...)
+	if q.Cursor != "" {
+		value, id, err := repository.DecodeCursor(q)
+		if err != nil {
+			return nil, err
+		}
+		pageWhere += fmt.Sprintf(" AND (%[1]s %[2]s ? OR (%[1]s = ? AND m.id %[2]s ?))", sortExpr, cmp)
+		pageArgs = append(pageArgs, value, value, id)
+	}
+
+	columns := mangaColumns + ", " + sortExpr
+	if q.Search != "" {
+		columns += ", " + searchColumns
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM %s
+		WHERE %s
+		ORDER BY %s %s, m.id %[5]s
+		LIMIT ?`, columns, sel.from, pageWhere, sortExpr, dir)
+	pageArgs = append(pageArgs, q.Limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, pageArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sortValues []interface{}
+	for rows.Next() {
+		var m models.Manga
+		var sortValue interface{}
+		extra := []interface{}{&sortValue}
+		if q.Search != "" {
+			m.Match = &models.SearchMatch{}
+			extra = append(extra, &m.Match.Score, &m.Match.Title, &m.Match.Snippet)
+		}
+		if err := scanManga(rows, &m, extra...); err != nil {
+			return nil, err
+		}
+		page.Manga = append(page.Manga, m)
+		sortValues = append(sortValues, sortValue)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Manga) > q.Limit {
+		page.Manga = page.Manga[:q.Limit]
+		page.NextCursor = repository.EncodeCursor(q, sortValues[q.Limit-1], page.Manga[q.Limit-1].ID)
+	}
+	page.Count = len(page.Manga)
+
+	return page, nil
+}
+
+// facets counts genres and statuses across every manga in sel,
+// ordered by descending count and then by name
+func (r *MangaRepository) facets(ctx context.Context, sel mangaSelection) (models.MangaFacets, error) {
+	facets := models.MangaFacets{Genres: []models.FacetCount{}, Statuses: []models.FacetCount{}}
+
+	var err error
+	facets.Genres, err = r.facetCounts(ctx, `
+		SELECT g.name, COUNT(*)
+		FROM `+sel.from+`
+		JOIN manga_genres mg ON mg.manga_id = m.id
+		JOIN genres g ON g.id = mg.genre_id
+		WHERE `+sel.where+`
+		GROUP BY g.id
+		ORDER BY COUNT(*) DESC, g.name`, sel.args)
+	if err != nil {
+		return facets, err
+	}
+
+	facets.Statuses, err = r.facetCounts(ctx, `
+		SELECT m.status, COUNT(*)
+		FROM `+sel.from+`
+		WHERE `+sel.where+` AND COALESCE(m.status, '') <> ''
+		GROUP BY m.status
+		ORDER BY COUNT(*) DESC, m.status`, sel.args)
+	return facets, err
+}
+
+// facetCounts runs a (value, count) aggregate query
+func (r *MangaRepository) facetCounts(ctx context.Context, query string, args []interface{}) ([]models.FacetCount, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.FacetCount{}
+	for rows.Next() {
+		var f models.FacetCount
+		if err := rows.Scan(&f.Value, &f.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, f)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,161 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"mangahub/internal/repository"
+	"mangahub/pkg/models"
+)
+
+// OutboxRepository implements repository.OutboxRepository on SQLite.
+// Times are stored with dbTime, like session times.
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+const outboxColumns = "id, topic, payload, attempts, delivered_to, COALESCE(last_error, ''), next_attempt_at, created_at"
+
+// insertOutbox queues an event inside tx, due immediately
+func insertOutbox(ctx context.Context, tx *sql.Tx, e models.OutboxEvent) error {
+	now := dbTime(time.Now())
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO outbox (id, topic, payload, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, e.ID, e.Topic, string(e.Payload), now, now)
+	if isUniqueViolation(err) {
+		return repository.ErrDuplicate
+	}
+	return err
+}
+
+// Claim pushes the next attempt of due events back by lease and returns them
+func (r *OutboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	now := dbTime(time.Now())
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE outbox SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM outbox WHERE next_attempt_at <= ?
+			ORDER BY created_at, id LIMIT ?
+		)
+		RETURNING `+outboxColumns,
+		dbTime(now.Add(lease)), now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	for rows.Next() {
+		var e models.OutboxEvent
+		var payload, delivered string
+		if err := rows.Scan(&e.ID, &e.Topic, &payload, &e.Attempts, &delivered, &e.LastError, &e.NextAttemptAt, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Payload = []byte(payload)
+		e.DeliveredTo = repository.SplitSubscribers(delivered)
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// RETURNING does not follow the subquery's order
+	repository.SortOutbox(events)
+	return events, nil
+}
+
+// Retry records a failed delivery attempt
+func (r *OutboxRepository) Retry(ctx context.Context, e models.OutboxEvent) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE outbox SET attempts = ?, delivered_to = ?, last_error = ?, next_attempt_at = ?
+		WHERE id = ?
+	`, e.Attempts, repository.JoinSubscribers(e.DeliveredTo), e.LastError, dbTime(e.NextAttemptAt), e.ID)
+	return err
+}
+
+// Done deletes a delivered event
+func (r *OutboxRepository) Done(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM outbox WHERE id = ?", id)
+	return err
+}
+
+// DeadLetter moves an event to outbox_dead_letters in one transaction
+func (r *OutboxRepository) DeadLetter(ctx context.Context, e models.OutboxEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO outbox_dead_letters (id, topic, payload, attempts, delivered_to, last_error, created_at, failed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, e.ID, e.Topic, string(e.Payload), e.Attempts, repository.JoinSubscribers(e.DeliveredTo), e.LastError,
+		dbTime(e.CreatedAt), dbTime(time.Now()))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM outbox WHERE id = ?", e.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeadLetters lists dead letters, most recently failed first
+func (r *OutboxRepository) DeadLetters(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, topic, payload, attempts, delivered_to, COALESCE(last_error, ''), created_at, failed_at
+		FROM outbox_dead_letters
+		ORDER BY failed_at DESC, id
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	for rows.Next() {
+		var e models.OutboxEvent
+		var payload, delivered string
+		var failedAt time.Time
+		if err := rows.Scan(&e.ID, &e.Topic, &payload, &e.Attempts, &delivered, &e.LastError, &e.CreatedAt, &failedAt); err != nil {
+			return nil, err
+		}
+		e.Payload = []byte(payload)
+		e.DeliveredTo = repository.SplitSubscribers(delivered)
+		e.FailedAt = &failedAt
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// Requeue moves a dead letter back into the outbox, due immediately.
+// Subscribers that already received it are not sent it again.
+func (r *OutboxRepository) Requeue(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO outbox (id, topic, payload, attempts, delivered_to, last_error, next_attempt_at, created_at)
+		SELECT id, topic, payload, 0, delivered_to, last_error, ?, created_at
+		FROM outbox_dead_letters WHERE id = ?
+	`, dbTime(time.Now()), id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrNotFound
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM outbox_dead_letters WHERE id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
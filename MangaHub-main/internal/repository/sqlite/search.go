@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"mangahub/internal/repository"
+)
+
+// searchWeights are the BM25 column weights for title, author and description
+var searchWeights = fmt.Sprintf("%.1f, %.1f, %.1f",
+	repository.TitleWeight, repository.AuthorWeight, repository.DescriptionWeight)
+
+// searchColumns are the extra result columns selected when a listing is a search
+var searchColumns = fmt.Sprintf(
+	"-bm25(manga_fts, %s), highlight(manga_fts, 0, '%s', '%s'), snippet(manga_fts, -1, '%s', '%s', '…', 16)",
+	searchWeights,
+	repository.HighlightOpen, repository.HighlightClose,
+	repository.HighlightOpen, repository.HighlightClose,
+)
+
+// buildMatchQuery turns free text into an FTS5 MATCH expression.
+// Every term is matched as a prefix, and all terms must match. A term that
+// matches nothing in the index is replaced by the closest indexed word, so
+// small typos ("narto", "attak") still find results. It returns "" when the
+// input contains no searchable terms.
+func buildMatchQuery(ctx context.Context, db *sql.DB, input string) (string, error) {
+	var parts []string
+	for _, term := range repository.SearchTerms(input) {
+		found, err := hasIndexedPrefix(ctx, db, term)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			if fix, err := closestIndexedTerm(ctx, db, term); err != nil {
+				return "", err
+			} else if fix != "" {
+				parts = append(parts, `"`+fix+`"`)
+				continue
+			}
+		}
+		parts = append(parts, `"`+term+`"*`)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// hasIndexedPrefix reports whether any indexed word starts with term
+func hasIndexedPrefix(ctx context.Context, db *sql.DB, term string) (bool, error) {
+	var found bool
+	err := db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM manga_fts_vocab WHERE term >= ? AND substr(term, 1, ?) = ?)`,
+		term, utf8.RuneCountInString(term), term,
+	).Scan(&found)
+	return found, err
+}
+
+// closestIndexedTerm finds the indexed word nearest to term by edit distance.
+// Candidates share the first letter and have a similar length, which keeps the
+// vocabulary scan small; typos in the first letter are not corrected.
+func closestIndexedTerm(ctx context.Context, db *sql.DB, term string) (string, error) {
+	maxDist := repository.MaxTypoDistance(term)
+	if maxDist == 0 {
+		return "", nil
+	}
+
+	first, _ := utf8.DecodeRuneInString(term)
+	n := utf8.RuneCountInString(term)
+	rows, err := db.QueryContext(ctx,
+		`SELECT term FROM manga_fts_vocab
+		 WHERE substr(term, 1, 1) = ? AND length(term) BETWEEN ? AND ?
+		 ORDER BY doc DESC`,
+		string(first), n-maxDist, n+maxDist,
+	)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	best, bestDist := "", maxDist+1
+	for rows.Next() {
+		var candidate string
+		if err := rows.Scan(&candidate); err != nil {
+			return "", err
+		}
+		if d := repository.EditDistance(term, candidate); d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+	return best, rows.Err()
+}
@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"mangahub/internal/repository"
+	"mangahub/pkg/models"
+)
+
+// SessionRepository implements repository.SessionRepository on SQLite.
+// Session times are written by Go in UTC at whole seconds, so their text
+// form has a fixed layout and compares correctly as a string.
+type SessionRepository struct {
+	db *sql.DB
+}
+
+const sessionColumns = "id, user_id, COALESCE(user_agent, ''), COALESCE(ip_address, ''), created_at, last_used_at, expires_at"
+
+// Create starts a session
+func (r *SessionRepository) Create(ctx context.Context, s models.Session, refreshHash string) error {
+	now := dbTime(time.Now())
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO sessions (id, user_id, refresh_hash, user_agent, ip_address, created_at, last_used_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, s.ID, s.UserID, refreshHash, s.UserAgent, s.IPAddress, now, now, dbTime(s.ExpiresAt))
+	if isUniqueViolation(err) {
+		return repository.ErrDuplicate
+	}
+	return err
+}
+
+// Rotate swaps the refresh token of an active session
+func (r *SessionRepository) Rotate(ctx context.Context, refreshHash, newHash string, expiresAt time.Time) (models.Session, error) {
+	now := dbTime(time.Now())
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE sessions
+		SET previous_hash = refresh_hash, refresh_hash = ?, last_used_at = ?, expires_at = ?
+		WHERE refresh_hash = ? AND revoked_at IS NULL AND expires_at > ?
+	`, newHash, now, dbTime(expiresAt), refreshHash, now)
+	if err != nil {
+		return models.Session{}, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return models.Session{}, err
+	} else if n == 0 {
+		_, err := r.db.ExecContext(ctx, "UPDATE sessions SET revoked_at = ? WHERE previous_hash = ? AND revoked_at IS NULL", now, refreshHash)
+		if err != nil {
+			return models.Session{}, err
+		}
+		return models.Session{}, repository.ErrNotFound
+	}
+
+	var s models.Session
+	err = r.db.QueryRowContext(ctx, "SELECT "+sessionColumns+" FROM sessions WHERE refresh_hash = ?", newHash).
+		Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt)
+	if err == sql.ErrNoRows {
+		// Rotated again or revoked by a concurrent request
+		return s, repository.ErrNotFound
+	}
+	return s, err
+}
+
+// Active reports whether a session is neither revoked nor expired
+func (r *SessionRepository) Active(ctx context.Context, id string) (bool, error) {
+	var active bool
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM sessions WHERE id = ? AND revoked_at IS NULL AND expires_at > ?",
+		id, dbTime(time.Now())).Scan(&active)
+	return active, err
+}
+
+// List returns a user's active sessions
+func (r *SessionRepository) List(ctx context.Context, userID string) ([]models.Session, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+sessionColumns+` FROM sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY last_used_at DESC, id
+	`, userID, dbTime(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// Revoke ends one of a user's sessions
+func (r *SessionRepository) Revoke(ctx context.Context, userID, id string) error {
+	now := dbTime(time.Now())
+	res, err := r.db.ExecContext(ctx, "UPDATE sessions SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?",
+		now, id, userID, now)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
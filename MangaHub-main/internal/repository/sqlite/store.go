@@ -0,0 +1,40 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"mangahub/internal/repository"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// NewStore returns SQLite-backed repositories sharing db.
+// The schema must already be migrated, see database.Open.
+func NewStore(db *sql.DB) *repository.Store {
+	return &repository.Store{
+		Manga:    &MangaRepository{db: db},
+		Users:    &UserRepository{db: db},
+		Progress: &ProgressRepository{db: db},
+		Audit:    &AuditRepository{db: db},
+		Sessions: &SessionRepository{db: db},
+		Outbox:   &OutboxRepository{db: db},
+	}
+}
+
+// dbTime normalizes a time before it is written or compared. Times stored
+// this way share one text layout, so SQLite can compare them as strings.
+func dbTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Second)
+}
+
+// isUniqueViolation reports whether err is a UNIQUE or PRIMARY KEY constraint failure
+func isUniqueViolation(err error) bool {
+	var se *sqlite.Error
+	if !errors.As(err, &se) {
+		return false
+	}
+	return se.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || se.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
@@ -0,0 +1,419 @@
+package repository_test
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mangahub/internal/database"
+	"mangahub/internal/repository"
+	"mangahub/internal/repository/memory"
+	"mangahub/pkg/models"
+)
+
+// The suite runs against every backend. PostgreSQL uses the server at
+// MANGAHUB_TEST_POSTGRES_URL, or one launched with initdb and pg_ctl from
+// PATH, and is skipped when neither is available.
+
+// backend opens an empty store
+type backend struct {
+	name string
+	open func(t *testing.T) *repository.Store
+}
+
+var backends = []backend{
+	{"memory", func(t *testing.T) *repository.Store { return memory.NewStore() }},
+	{"sqlite", openSQLite},
+	{"postgres", openPostgres},
+}
+
+// forEachBackend runs test on a fresh store of every backend
+func forEachBackend(t *testing.T, test func(t *testing.T, store *repository.Store)) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			test(t, b.open(t))
+		})
+	}
+}
+
+func openSQLite(t *testing.T) *repository.Store {
+	db, err := database.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db.Store()
+}
+
+// openPostgres migrates a schema of its own, dropped after the test
+func openPostgres(t *testing.T) *repository.Store {
+	dsn := postgresURL(t)
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	schema := "test_" + randomHex(t, 6)
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if admin, err := sql.Open("postgres", dsn); err == nil {
+			admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+			admin.Close()
+		}
+	})
+
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	db, err := database.Open(dsn + sep + "search_path=" + schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db.Store()
+}
+
+var (
+	launchOnce  sync.Once
+	launchedDSN string
+	launchErr   error
+	stopCluster func()
+)
+
+// postgresURL returns the server to test against, launching one on first
+// use, or skips the test
+func postgresURL(t *testing.T) string {
+	if dsn := os.Getenv("MANGAHUB_TEST_POSTGRES_URL"); dsn != "" {
+		return dsn
+	}
+	launchOnce.Do(func() { launchedDSN, launchErr = launchPostgres() })
+	if launchErr != nil {
+		t.Skipf("no PostgreSQL: set MANGAHUB_TEST_POSTGRES_URL or put initdb and pg_ctl on PATH (%v)", launchErr)
+	}
+	return launchedDSN
+}
+
+// launchPostgres starts a throwaway cluster listening on a free local port
+func launchPostgres() (string, error) {
+	for _, bin := range []string{"initdb", "pg_ctl"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			return "", err
+		}
+	}
+	dir, err := os.MkdirTemp("", "mangahub-pg")
+	if err != nil {
+		return "", err
+	}
+	data := filepath.Join(dir, "data")
+	if out, err := exec.Command("initdb", "-D", data, "-U", "postgres", "--auth=trust").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("initdb: %v: %s", err, out)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	options := fmt.Sprintf("-p %d -h 127.0.0.1 -k %s", port, dir)
+	if out, err := exec.Command("pg_ctl", "-D", data, "-o", options, "-l", filepath.Join(dir, "log"), "-w", "start").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("pg_ctl start: %v: %s", err, out)
+	}
+	stopCluster = func() {
+		exec.Command("pg_ctl", "-D", data, "-m", "fast", "-w", "stop").Run()
+		os.RemoveAll(dir)
+	}
+	return fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port), nil
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if stopCluster != nil {
+		stopCluster()
+	}
+	os.Exit(code)
+}
+
+func randomHex(t *testing.T, n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// fixtures
+
+var (
+	onePiece = models.Manga{ID: "one-piece", Title: "One Piece", Author: "Eiichiro Oda", Genres: []string{"Action", "Adventure"}, Status: "ongoing", TotalChapters: 1100, Description: "Pirate adventure"}
+	naruto   = models.Manga{ID: "naruto", Title: "Naruto", Author: "Masashi Kishimoto", Genres: []string{"Action"}, Status: "completed", TotalChapters: 700, Description: "Ninja story"}
+	alice    = models.User{ID: "user_alice", Username: "alice", Email: "alice@example.com", PasswordHash: "hash"}
+)
+
+func createAll(t *testing.T, store *repository.Store) {
+	t.Helper()
+	ctx := context.Background()
+	for _, m := range []models.Manga{onePiece, naruto} {
+		if err := store.Manga.Create(ctx, m); err != nil {
+			t.Fatalf("create %s: %v", m.ID, err)
+		}
+	}
+	if err := store.Users.Create(ctx, alice); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+}
+
+func progressEvent(id string) models.OutboxEvent {
+	return models.OutboxEvent{ID: id, Topic: "progress", Payload: json.RawMessage(`{"manga_id":"naruto"}`)}
+}
+
+func TestMangaCatalog(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *repository.Store) {
+		ctx := repository.WithActor(context.Background(), alice.ID)
+		createAll(t, store)
+
+		if err := store.Manga.Create(ctx, onePiece); !errors.Is(err, repository.ErrDuplicate) {
+			t.Errorf("create twice: got %v, want ErrDuplicate", err)
+		}
+		got, err := store.Manga.Get(ctx, "one-piece")
+		if err != nil || got.Title != onePiece.Title || got.TotalChapters != 1100 || len(got.Genres) != 2 {
+			t.Errorf("get: %+v, %v", got, err)
+		}
+		if _, err := store.Manga.Get(ctx, "missing"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("get missing: got %v, want ErrNotFound", err)
+		}
+
+		page, err := store.Manga.List(ctx, models.MangaQuery{Search: "pirate", Limit: 10})
+		if err != nil || len(page.Manga) != 1 || page.Manga[0].ID != "one-piece" || page.Manga[0].Match == nil {
+			t.Errorf("search: %+v, %v", page, err)
+		}
+		page, err = store.Manga.List(ctx, models.MangaQuery{Genres: []string{"action"}, Sort: "title", Limit: 1})
+		if err != nil || page.Total != 2 || len(page.Manga) != 1 || page.Manga[0].ID != "naruto" || page.NextCursor == "" {
+			t.Fatalf("first page: %+v, %v", page, err)
+		}
+		page, err = store.Manga.List(ctx, models.MangaQuery{Genres: []string{"action"}, Sort: "title", Limit: 1, Cursor: page.NextCursor})
+		if err != nil || len(page.Manga) != 1 || page.Manga[0].ID != "one-piece" || page.NextCursor != "" {
+			t.Errorf("second page: %+v, %v", page, err)
+		}
+
+		title := "One Piece (Colored)"
+		updated, err := store.Manga.Update(ctx, "one-piece", models.MangaPatch{Title: &title})
+		if err != nil || updated.Title != title {
+			t.Errorf("update: %+v, %v", updated, err)
+		}
+
+		if err := store.Manga.Delete(ctx, "one-piece"); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := store.Manga.Get(ctx, "one-piece"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("get deleted: got %v, want ErrNotFound", err)
+		}
+		if err := store.Manga.Delete(ctx, "one-piece"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("delete twice: got %v, want ErrNotFound", err)
+		}
+		genres, err := store.Manga.Genres(ctx)
+		if err != nil || genreCount(genres, "Adventure") != 0 || genreCount(genres, "Action") != 1 {
+			t.Errorf("genres after delete: %+v, %v", genres, err)
+		}
+		if restored, err := store.Manga.Restore(ctx, "one-piece"); err != nil || restored.Title != title {
+			t.Errorf("restore: %+v, %v", restored, err)
+		}
+
+		audit, err := store.Audit.List(ctx, models.AuditEntityManga, "one-piece")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actions []string
+		for _, e := range audit {
+			actions = append(actions, e.Action)
+			if e.Action != models.AuditCreate && e.ActorID != alice.ID {
+				t.Errorf("%s attributed to %q", e.Action, e.ActorID)
+			}
+		}
+		if want := "create update delete restore"; strings.Join(actions, " ") != want {
+			t.Errorf("audit actions %v, want %s", actions, want)
+		}
+	})
+}
+
+func genreCount(genres []models.Genre, name string) int {
+	for _, g := range genres {
+		if g.Name == name {
+			return g.MangaCount
+		}
+	}
+	return -1
+}
+
+func TestUsers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *repository.Store) {
+		ctx := context.Background()
+		createAll(t, store)
+
+		dup := alice
+		dup.ID = "user_other"
+		if err := store.Users.Create(ctx, dup); !errors.Is(err, repository.ErrDuplicate) {
+			t.Errorf("duplicate username: got %v, want ErrDuplicate", err)
+		}
+		u, err := store.Users.GetByUsername(ctx, "alice")
+		if err != nil || u.ID != alice.ID || u.Role != "user" {
+			t.Errorf("get by username: %+v, %v", u, err)
+		}
+		if err := store.Users.SetRole(ctx, alice.ID, "admin"); err != nil {
+			t.Fatal(err)
+		}
+		if u, err := store.Users.GetByID(ctx, alice.ID); err != nil || u.Role != "admin" {
+			t.Errorf("role after SetRole: %+v, %v", u, err)
+		}
+		if err := store.Users.SetRole(ctx, "missing", "admin"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("SetRole missing: got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestProgressQueuesEvent(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *repository.Store) {
+		ctx := context.Background()
+		createAll(t, store)
+
+		if err := store.Progress.AddToLibrary(ctx, alice.ID, "naruto", "plan_to_read"); err != nil {
+			t.Fatal(err)
+		}
+		p := models.UserProgress{UserID: alice.ID, MangaID: "naruto", CurrentChapter: 42}
+		if err := store.Progress.UpdateProgress(ctx, p, progressEvent("evt_1")); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Progress.UpdateProgress(ctx, p, progressEvent("evt_1")); !errors.Is(err, repository.ErrDuplicate) {
+			t.Errorf("reused event ID: got %v, want ErrDuplicate", err)
+		}
+
+		library, err := store.Progress.Library(ctx, alice.ID)
+		if err != nil || len(library) != 1 || library[0].CurrentChapter != 42 || library[0].Status != "plan_to_read" {
+			t.Errorf("library: %+v, %v", library, err)
+		}
+
+		events, err := store.Outbox.Claim(ctx, 10, time.Minute)
+		if err != nil || len(events) != 1 || events[0].ID != "evt_1" || string(events[0].Payload) != `{"manga_id":"naruto"}` {
+			t.Errorf("claim: %+v, %v", events, err)
+		}
+	})
+}
+
+func TestSessions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *repository.Store) {
+		ctx := context.Background()
+		createAll(t, store)
+
+		s := models.Session{ID: "sess_1", UserID: alice.ID, UserAgent: "test", ExpiresAt: time.Now().Add(time.Hour)}
+		if err := store.Sessions.Create(ctx, s, "hash1"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Sessions.Rotate(ctx, "hash1", "hash2", time.Now().Add(2*time.Hour)); err != nil {
+			t.Fatalf("rotate: %v", err)
+		}
+		if active, err := store.Sessions.Active(ctx, "sess_1"); err != nil || !active {
+			t.Errorf("active after rotate: %v, %v", active, err)
+		}
+		// Presenting the rotated token again means it was copied
+		if _, err := store.Sessions.Rotate(ctx, "hash1", "hash3", time.Now().Add(time.Hour)); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("reused refresh token: got %v, want ErrNotFound", err)
+		}
+		if active, _ := store.Sessions.Active(ctx, "sess_1"); active {
+			t.Error("session still active after its refresh token was reused")
+		}
+
+		s.ID = "sess_2"
+		if err := store.Sessions.Create(ctx, s, "hash4"); err != nil {
+			t.Fatal(err)
+		}
+		if list, err := store.Sessions.List(ctx, alice.ID); err != nil || len(list) != 1 || list[0].ID != "sess_2" {
+			t.Errorf("list: %+v, %v", list, err)
+		}
+		if err := store.Sessions.Revoke(ctx, alice.ID, "sess_2"); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Sessions.Revoke(ctx, alice.ID, "sess_2"); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("revoke twice: got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestOutboxLifecycle(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store *repository.Store) {
+		ctx := context.Background()
+		createAll(t, store)
+		p := models.UserProgress{UserID: alice.ID, MangaID: "naruto", CurrentChapter: 1}
+		for _, id := range []string{"evt_a", "evt_b"} {
+			if err := store.Progress.UpdateProgress(ctx, p, progressEvent(id)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		claimed, err := store.Outbox.Claim(ctx, 1, time.Minute)
+		if err != nil || len(claimed) != 1 {
+			t.Fatalf("claim one: %+v, %v", claimed, err)
+		}
+		rest, err := store.Outbox.Claim(ctx, 10, time.Minute)
+		if err != nil || len(rest) != 1 || rest[0].ID == claimed[0].ID {
+			t.Fatalf("claimed events are leased: %+v, %v", rest, err)
+		}
+
+		failed := claimed[0]
+		failed.Attempts, failed.DeliveredTo, failed.LastError = 1, []string{"tcp"}, "udp down"
+		failed.NextAttemptAt = time.Now().Add(-time.Second)
+		if err := store.Outbox.Retry(ctx, failed); err != nil {
+			t.Fatal(err)
+		}
+		again, err := store.Outbox.Claim(ctx, 10, time.Minute)
+		if err != nil || len(again) != 1 || again[0].Attempts != 1 || len(again[0].DeliveredTo) != 1 || again[0].DeliveredTo[0] != "tcp" {
+			t.Fatalf("claim after retry: %+v, %v", again, err)
+		}
+
+		if err := store.Outbox.DeadLetter(ctx, again[0]); err != nil {
+			t.Fatal(err)
+		}
+		dead, err := store.Outbox.DeadLetters(ctx, 10)
+		if err != nil || len(dead) != 1 || dead[0].ID != failed.ID || dead[0].FailedAt == nil {
+			t.Fatalf("dead letters: %+v, %v", dead, err)
+		}
+		if err := store.Outbox.Requeue(ctx, failed.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Outbox.Requeue(ctx, failed.ID); !errors.Is(err, repository.ErrNotFound) {
+			t.Errorf("requeue twice: got %v, want ErrNotFound", err)
+		}
+		requeued, err := store.Outbox.Claim(ctx, 10, time.Minute)
+		if err != nil || len(requeued) != 1 || requeued[0].Attempts != 0 || len(requeued[0].DeliveredTo) != 1 {
+			t.Fatalf("claim requeued: %+v, %v", requeued, err)
+		}
+
+		for _, e := range append(requeued, rest...) {
+			if err := store.Outbox.Done(ctx, e.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if dead, _ := store.Outbox.DeadLetters(ctx, 10); len(dead) != 0 {
+			t.Errorf("dead letters left: %+v", dead)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package servers
+
+import (
+	"log"
+	"net/http"
+
+	"mangahub/internal/auth"
+	"mangahub/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Inspector is a hub whose clients admins may list and disconnect.
+// tcp.Hub, udp.Hub and websocket.Hub implement it.
+type Inspector interface {
+	Clients() []shared.ClientInfo
+	Disconnect(id string) bool
+}
+
+// statsReporter is a hub that also counts traffic, like udp.Hub
+type statsReporter interface {
+	Stats() map[string]uint64
+}
+
+// adminRoutes adds the client endpoints of a real-time server, open to
+// admins only:
+//
+//	GET    /admin/clients      lists the connected clients
+//	DELETE /admin/clients/:id  disconnects one
+//	GET    /admin/stats        returns the hub's counters, if it has any
+func adminRoutes(router *gin.Engine, name string, hub Inspector, sessions auth.Sessions) {
+	admin := router.Group("/admin")
+	admin.Use(auth.Middleware(sessions), auth.RequireRole(auth.RoleAdmin))
+
+	admin.GET("/clients", func(c *gin.Context) {
+		clients := hub.Clients()
+		c.JSON(http.StatusOK, gin.H{"server": name, "clients": clients, "count": len(clients)})
+	})
+
+	admin.DELETE("/clients/:id", func(c *gin.Context) {
+		id := c.Param("id")
+		if !hub.Disconnect(id) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+			return
+		}
+		log.Printf("%s CLIENT %s DISCONNECTED BY ADMIN %s", name, id, c.GetString("username"))
+		c.JSON(http.StatusOK, gin.H{"message": "Client disconnected"})
+	})
+
+	if reporter, ok := hub.(statsReporter); ok {
+		admin.GET("/stats", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"server": name, "stats": reporter.Stats()})
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// Package servers starts the real-time servers. Each runs in its own binary
+// under cmd/, or inside the API server when events.mode is "inprocess", so
+// that one process can serve everything.
+package servers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log"
+	"os"
+
+	"mangahub/internal/auth"
+	"mangahub/internal/config"
+	"mangahub/internal/events"
+	"mangahub/internal/progress"
+	"mangahub/internal/repository"
+	"mangahub/internal/tcp"
+	"mangahub/internal/udp"
+	"mangahub/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StartTCP runs the TCP progress server and its internal HTTP endpoints in
+// the background; its hub is tcp.GlobalHub. Clients authenticate with access
+// tokens, so the JWT and events secrets must be installed first, see
+// config.RequireJWTSecret and config.RequireEventsSecret. Clients and admins
+// are checked against sessions, and progress clients submit is recorded
+// through updates.
+func StartTCP(cfg config.RealtimeConfig, updates *progress.Service, sessions auth.Sessions) {
+	go tcp.GlobalHub.Run() // Start the global TCP hub in a separate goroutine
+
+	go func() {
+		if err := tcp.ListenAndServe(cfg.Addr, serverTLS("TCP", cfg), updates, sessions); err != nil {
+			log.Fatal("Error starting TCP listener:", err)
+		}
+	}()
+	serveInternal("TCP", cfg, events.Handler("TCP CLIENT(S)", tcp.GlobalHub), tcp.GlobalHub, sessions)
+
+	log.Println("TCP Server running")
+	log.Printf(" - TCP clients on %s%s", cfg.Addr, tlsNote(cfg))
+	log.Printf(" - Internal HTTP for API on %s/internal/progress%s", cfg.InternalAddr, tlsNote(cfg))
+	log.Printf(" - Admin endpoints on %s/admin/clients%s", cfg.InternalAddr, tlsNote(cfg))
+}
+
+// StartUDP runs the UDP notification server and its internal HTTP endpoints
+// in the background and returns a subscriber feeding its hub directly.
+// Clients subscribe with access tokens, so the JWT and events secrets must
+// be installed first; clients and admins are checked against sessions.
+func StartUDP(cfg config.UDPConfig, sessions auth.Sessions) events.Subscriber {
+	udp.MTU = cfg.MTU
+	udp.CoalesceWindow = cfg.CoalesceWindow.Duration
+	udp.SubscriptionLifetime = cfg.SubscriptionLifetime.Duration
+	go udp.GlobalHub.Run() // Start the global UDP hub
+
+	udp.StartUDPListener(cfg.Addr, sessions) // UDP listener for subscribers
+	serveInternal("UDP", cfg.RealtimeConfig, events.Handler("UDP SUBSCRIBER(S)", udp.GlobalHub), udp.GlobalHub, sessions)
+
+	log.Println("UDP Server running")
+	log.Printf(" - UDP clients on %s (MTU %d, coalescing for %v)", cfg.Addr, cfg.MTU, cfg.CoalesceWindow.Duration)
+	log.Printf(" - Internal HTTP trigger on %s%s", cfg.InternalAddr, tlsNote(cfg.RealtimeConfig))
+	log.Printf(" - Admin endpoints on %s/admin/clients%s", cfg.InternalAddr, tlsNote(cfg.RealtimeConfig))
+	return events.Local("udp", udp.GlobalHub)
+}
+
+// StartWebSocket runs the WebSocket chat server and its internal HTTP
+// endpoints in the background and returns a subscriber feeding its hub
+// directly. The JWT and events secrets must be installed first; clients and
+// admins are checked against sessions.
+func StartWebSocket(cfg config.RealtimeConfig, sessions auth.Sessions) events.Subscriber {
+	hub := websocket.NewHub()
+	go hub.Run()
+
+	router := websocket.NewRouter(hub, sessions)
+	go func() {
+		if err := run(router, cfg.Addr, cfg); err != nil {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+	serveInternal("WebSocket", cfg, events.Handler("WEBSOCKET CLIENT(S)", hub), hub, sessions)
+
+	log.Printf("🚀 WebSocket Chat Server (Multiple Rooms) started on %s%s", cfg.Addr, tlsNote(cfg))
+	log.Printf(" - Internal HTTP trigger on %s%s", cfg.InternalAddr, tlsNote(cfg))
+	log.Printf(" - Admin endpoints on %s/admin/clients%s", cfg.InternalAddr, tlsNote(cfg))
+	return events.Local("websocket", hub)
+}
+
+// NewDispatcher returns the outbox dispatcher for cfg.Events.Mode. In
+// network mode it posts to the internal URLs of the real-time servers; in
+// inprocess mode it first starts those servers in this process. Only the
+// API server calls it, as their ports can be bound by one process only.
+func NewDispatcher(cfg *config.Config, store *repository.Store) *events.Dispatcher {
+	if cfg.Events.Mode != config.EventsInProcess {
+		return NetworkDispatcher(cfg, store.Outbox)
+	}
+
+	d := events.NewDispatcher(store.Outbox, retryPolicy(cfg),
+		events.Local("tcp", tcp.GlobalHub),
+		StartUDP(cfg.UDP, store.Sessions),
+		StartWebSocket(cfg.WebSocket, store.Sessions),
+	)
+	// The TCP server records progress through the dispatcher it feeds
+	StartTCP(cfg.TCP, progress.NewService(store, d), store.Sessions)
+	return d
+}
+
+// NetworkDispatcher returns an outbox dispatcher posting to the internal
+// URLs of the real-time servers, whatever cfg.Events.Mode says. The gRPC
+// server and the standalone TCP server use it.
+// Requests are signed with events.SigningSecret.
+func NetworkDispatcher(cfg *config.Config, outbox repository.OutboxRepository) *events.Dispatcher {
+	tlsConfig := clientTLS(cfg.Events)
+	return events.NewDispatcher(outbox, retryPolicy(cfg),
+		events.HTTP("tcp", cfg.TCP.InternalURL, tlsConfig),
+		events.HTTP("udp", cfg.UDP.InternalURL, tlsConfig),
+		events.HTTP("websocket", cfg.WebSocket.InternalURL, tlsConfig),
+	)
+}
+
+// retryPolicy returns the delivery settings of cfg.Events
+func retryPolicy(cfg *config.Config) events.RetryPolicy {
+	return events.RetryPolicy{
+		MaxAttempts:    cfg.Events.MaxAttempts,
+		InitialBackoff: cfg.Events.InitialBackoff.Duration,
+		MaxBackoff:     cfg.Events.MaxBackoff.Duration,
+	}
+}
+
+// serveInternal runs the /internal/progress endpoint of a real-time server
+// and the admin endpoints of its hub, over TLS if cfg has a certificate
+func serveInternal(name string, cfg config.RealtimeConfig, handler gin.HandlerFunc, hub Inspector, sessions auth.Sessions) {
+	router := gin.New()
+	router.POST("/internal/progress", handler) // Internal HTTP endpoint to receive progress updates
+	adminRoutes(router, name, hub, sessions)
+	go func() {
+		if err := run(router, cfg.InternalAddr, cfg); err != nil {
+			log.Fatalf("Failed to start %s internal HTTP: %v", name, err)
+		}
+	}()
+}
+
+// run serves router on addr, over TLS if cfg has a certificate
+func run(router *gin.Engine, addr string, cfg config.RealtimeConfig) error {
+	if cfg.TLSEnabled() {
+		return router.RunTLS(addr, cfg.TLSCert, cfg.TLSKey)
+	}
+	return router.Run(addr)
+}
+
+// serverTLS loads the certificate of cfg, or returns nil if it has none
+func serverTLS(name string, cfg config.RealtimeConfig) *tls.Config {
+	if !cfg.TLSEnabled() {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+	if err != nil {
+		log.Fatalf("Failed to load %s TLS certificate: %v", name, err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+}
+
+// clientTLS returns the TLS settings for posting to internal URLs, trusting
+// cfg.CAFile besides the system roots, or nil if no CA file is configured
+func clientTLS(cfg config.EventsConfig) *tls.Config {
+	if cfg.CAFile == "" {
+		return nil
+	}
+	pem, err := os.ReadFile(cfg.CAFile)
+	if err != nil {
+		log.Fatalf("Failed to read events CA file: %v", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		log.Fatalf("No certificates found in events CA file %s", cfg.CAFile)
+	}
+	return &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+}
+
+// tlsNote marks TLS listeners in the startup log
+func tlsNote(cfg config.RealtimeConfig) string {
+	if cfg.TLSEnabled() {
+		return " (TLS)"
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+package shared
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Topic prefixes of the TCP and UDP subscriptions. A client receives an
+// update if it is subscribed to the update's user or manga.
+const (
+	TopicUser  = "user:"
+	TopicManga = "manga:"
+	// TopicSelf is shorthand for the client's own user topic
+	TopicSelf = "self"
+)
+
+// maxTopicIDLength bounds the ID part of a topic
+const maxTopicIDLength = 64
+
+// ParseTopic validates a topic argument, expanding self to userID's topic
+func ParseTopic(arg, userID string) (string, error) {
+	if strings.EqualFold(arg, TopicSelf) {
+		return TopicUser + userID, nil
+	}
+	for _, prefix := range []string{TopicUser, TopicManga} {
+		if id := strings.TrimPrefix(arg, prefix); id != arg {
+			if id == "" || len(id) > maxTopicIDLength {
+				return "", fmt.Errorf("invalid topic %q", arg)
+			}
+			return arg, nil
+		}
+	}
+	return "", fmt.Errorf("unknown topic %q: use user:<id>, manga:<id> or self", arg)
+}
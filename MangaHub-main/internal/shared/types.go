@@ -1,18 +1,14 @@
 package shared
 
-import "time"
+import (
+	"time"
 
-// ProgressUpdate is the message format sent to TCP clients when reading progress changes
-type ProgressUpdate struct {
-	UserID         string `json:"user_id"`
-	Username       string `json:"username"`
-	MangaID        string `json:"manga_id"`
-	MangaTitle     string `json:"manga_title"`
-	CurrentChapter int    `json:"current_chapter"`
-	Status         string `json:"status"`
-	Timestamp      int64  `json:"timestamp"` // Unix timestamp
-	Type           string `json:"type"`
-}
+	"mangahub/pkg/models"
+)
+
+// ProgressUpdate is the message format sent to clients when reading
+// progress changes. It is defined in pkg/models for the client libraries.
+type ProgressUpdate = models.ProgressUpdate
 
 // Helper to create a new update
 func NewProgressUpdate(userID, username, mangaID, mangaTitle string, chapter int, status string) ProgressUpdate {
@@ -26,3 +22,16 @@ func NewProgressUpdate(userID, username, mangaID, mangaTitle string, chapter int
 		Timestamp:      time.Now().Unix(),
 	}
 }
+
+// ClientInfo describes a live connection to a real-time server, as listed
+// by the admin endpoints of the TCP, UDP and WebSocket servers
+type ClientInfo struct {
+	ID            string    `json:"id"`
+	UserID        string    `json:"user_id,omitempty"`
+	Username      string    `json:"username,omitempty"`
+	RemoteAddr    string    `json:"remote_addr"`
+	ConnectedAt   time.Time `json:"connected_at"`
+	LastActivity  time.Time `json:"last_activity"` // Last message received from the client
+	QueueDepth    int       `json:"queue_depth"`   // Messages waiting to be sent to it
+	Subscriptions []string  `json:"subscriptions"`
+}
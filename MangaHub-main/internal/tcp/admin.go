@@ -0,0 +1,69 @@
+package tcp
+
+import (
+	"sort"
+	"time"
+
+	"mangahub/internal/shared"
+)
+
+// kickRequest asks Run to disconnect the client with the given ID
+type kickRequest struct {
+	id    string
+	found chan bool
+}
+
+// touch records traffic from the client
+func (c *Client) touch() {
+	c.lastActive.Store(time.Now().UnixNano())
+}
+
+// Info describes the client for the admin endpoints
+func (c *Client) Info() shared.ClientInfo {
+	info := shared.ClientInfo{
+		ID:            c.ID,
+		UserID:        c.UserID,
+		Username:      c.Username,
+		RemoteAddr:    c.Conn.RemoteAddr().String(),
+		ConnectedAt:   c.ConnectedAt,
+		QueueDepth:    len(c.Send),
+		Subscriptions: c.Subscriptions(),
+	}
+	if ns := c.lastActive.Load(); ns != 0 {
+		info.LastActivity = time.Unix(0, ns)
+	}
+	return info
+}
+
+// Clients lists the connected clients, oldest first
+func (h *Hub) Clients() []shared.ClientInfo {
+	h.mu.RLock()
+	clients := make([]shared.ClientInfo, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client.Info())
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(clients, func(i, j int) bool { return clients[i].ConnectedAt.Before(clients[j].ConnectedAt) })
+	return clients
+}
+
+// Disconnect closes the connection with the given ID, sending
+// "BYE disconnected by admin". It reports whether the client was found.
+func (h *Hub) Disconnect(id string) bool {
+	req := kickRequest{id: id, found: make(chan bool, 1)}
+	h.kick <- req
+	return <-req.found
+}
+
+// find returns the client with the given ID, or nil. Only Run may call it.
+func (h *Hub) find(id string) *Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.clients {
+		if client.ID == id {
+			return client
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package tcp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"mangahub/internal/shared"
+	pb "mangahub/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Framings of the TCP progress protocol, chosen by the optional second
+// argument of AUTH. Text is the default.
+const (
+	FramingText     = "TEXT"
+	FramingProtobuf = "PROTOBUF"
+)
+
+// MaxFrameSize bounds a protobuf frame, as bufio.Scanner bounds a text line
+const MaxFrameSize = bufio.MaxScanTokenSize
+
+// errFrameTooLarge ends a connection whose client announces a frame over MaxFrameSize
+var errFrameTooLarge = errors.New("frame too large")
+
+// encodeFrame marshals frame behind its 4-byte big-endian length
+func encodeFrame(frame *pb.ProgressFrame) ([]byte, error) {
+	body, err := proto.Marshal(frame)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4, 4+len(body))
+	binary.BigEndian.PutUint32(buf, uint32(len(body)))
+	return append(buf, body...), nil
+}
+
+// readFrame reads one length-prefixed frame
+func readFrame(r *bufio.Reader) (*pb.ProgressFrame, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > MaxFrameSize {
+		return nil, errFrameTooLarge
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	frame := &pb.ProgressFrame{}
+	if err := proto.Unmarshal(body, frame); err != nil {
+		return nil, fmt.Errorf("invalid frame: %w", err)
+	}
+	return frame, nil
+}
+
+// updateFrame encodes a broadcast for protobuf clients
+func updateFrame(u shared.ProgressUpdate) ([]byte, error) {
+	return encodeFrame(&pb.ProgressFrame{Body: &pb.ProgressFrame_Update{Update: &pb.ProgressUpdate{
+		UserId:         u.UserID,
+		Username:       u.Username,
+		MangaId:        u.MangaID,
+		MangaTitle:     u.MangaTitle,
+		CurrentChapter: int32(u.CurrentChapter),
+		Status:         u.Status,
+		Timestamp:      u.Timestamp,
+		Type:           u.Type,
+		EventId:        u.EventID,
+		Seq:            u.Seq,
+	}}})
+}
+
+// wireLine encodes a protocol line in the client's framing
+func (c *Client) wireLine(line []byte) []byte {
+	if !c.protobuf {
+		buf := make([]byte, 0, len(line)+1)
+		return append(append(buf, line...), '\n')
+	}
+	// proto3 strings must be valid UTF-8, and replies may echo client input
+	text := strings.ToValidUTF8(string(line), "\uFFFD")
+	data, err := encodeFrame(&pb.ProgressFrame{Body: &pb.ProgressFrame_Line{Line: text}})
+	if err != nil {
+		log.Printf("Error encoding TCP frame: %v", err)
+	}
+	return data
+}
+
+// wireUpdate picks the encoding of a broadcast for the client's framing
+func (c *Client) wireUpdate(m broadcast) []byte {
+	if c.protobuf {
+		return m.frame
+	}
+	return m.line
+}
+
+// readFrames runs the commands of a protobuf client until the connection
+// fails, and returns the error
+func (c *Client) readFrames() error {
+	for {
+		frame, err := readFrame(c.reader)
+		if err != nil {
+			return err
+		}
+		c.refreshDeadline()
+		line, ok := frame.Body.(*pb.ProgressFrame_Line)
+		if !ok {
+			c.reply(errorLine("clients may only send line frames"))
+			continue
+		}
+		c.handleCommand(line.Line)
+	}
+}
@@ -0,0 +1,89 @@
+package tcp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Heartbeats. The server sends PING every PingInterval and the client
+// answers PONG; any other line counts as well. A client that sends nothing
+// for MaxMissedPings intervals is evicted, which also clears half-open
+// connections whose peer is gone.
+const (
+	PingInterval   = 30 * time.Second
+	MaxMissedPings = 3
+	// WriteTimeout bounds one write, so that a peer which stopped reading
+	// cannot block its WritePump forever
+	WriteTimeout = 10 * time.Second
+)
+
+// idleTimeout is how long a client may stay silent
+const idleTimeout = PingInterval * MaxMissedPings
+
+// Reasons a client is disconnected, reported in the log and in the final
+// "BYE <reason>" line
+const (
+	ReasonClosed        = "connection closed"
+	ReasonHeartbeat     = "heartbeat timeout"
+	ReasonSlowConsumer  = "slow consumer"
+	ReasonLineTooLong   = "line too long"
+	ReasonFrameTooLarge = "frame too large"
+	ReasonReadFailed    = "read failed"
+	ReasonWriteFailed   = "write failed"
+	ReasonKicked        = "disconnected by admin"
+)
+
+// refreshDeadline gives the client another idleTimeout to send something
+func (c *Client) refreshDeadline() {
+	c.touch()
+	c.Conn.SetReadDeadline(time.Now().Add(idleTimeout))
+}
+
+// write sends data encoded with wireLine or wireUpdate, giving up after
+// WriteTimeout
+func (c *Client) write(data []byte) error {
+	c.Conn.SetWriteDeadline(time.Now().Add(WriteTimeout))
+	_, err := c.Conn.Write(data)
+	return err
+}
+
+// setReason records why the client is disconnected. The first reason wins,
+// since later ones are usually consequences of it.
+func (c *Client) setReason(reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.reason == "" {
+		c.reason = reason
+	}
+}
+
+// disconnectReason returns the reason recorded by setReason
+func (c *Client) disconnectReason() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.reason == "" {
+		return ReasonClosed
+	}
+	return c.reason
+}
+
+// readReason describes the error that ended ReadPump
+func readReason(err error) string {
+	var netErr net.Error
+	switch {
+	case err == nil, errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, net.ErrClosed):
+		return ReasonClosed
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Sprintf("%s: missed %d pings", ReasonHeartbeat, MaxMissedPings)
+	case errors.Is(err, bufio.ErrTooLong):
+		return ReasonLineTooLong
+	case errors.Is(err, errFrameTooLarge):
+		return ReasonFrameTooLarge
+	default:
+		return fmt.Sprintf("%s: %v", ReasonReadFailed, err)
+	}
+}
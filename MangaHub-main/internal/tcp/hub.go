@@ -1,35 +1,69 @@
 package tcp
 
 import (
-	"encoding/json"
+	"bufio"
+	"fmt"
 	"log"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"mangahub/internal/progress"
 	"mangahub/internal/shared"
-	"mangahub/pkg/models"
 )
 
+// Client is an authenticated TCP connection. UserID and Username come from
+// the verified access token sent in the handshake.
 type Client struct {
-	Conn   net.Conn
-	UserID string
-	Send   chan []byte
+	ID          string // Identifies the connection in the admin endpoints
+	Conn        net.Conn
+	UserID      string
+	Username    string
+	Send        chan []byte
+	ConnectedAt time.Time
+
+	reader     *bufio.Reader     // Reads what follows the handshake
+	protobuf   bool              // Uses FramingProtobuf instead of lines
+	updates    *progress.Service // Records UPDATE commands, if supported
+	writerDone chan struct{}     // Closed when WritePump returns
+	lastActive atomic.Int64      // Unix nanoseconds of the last line or frame received
+
+	mu     sync.Mutex      // Guards subs and reason
+	subs   map[string]bool // Subscribed topics, see Subscriptions
+	reason string          // Why the client is disconnected, see setReason
 }
 
+// SendBuffer is the capacity of Client.Send, whose messages are encoded in
+// the client's framing. It holds a full replay of the
+// retained log on top of the live updates. A client with a full queue is
+// disconnected as a slow consumer.
+const SendBuffer = RetainedUpdates + 256
+
+// Hub routes progress updates to the clients subscribed to them. It numbers
+// every update and keeps the last RetainedUpdates for RESUME.
 type Hub struct {
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	broadcast  chan shared.ProgressUpdate
+	resume     chan resumeRequest
 	Register   chan *Client
 	Unregister chan *Client
+	kick       chan kickRequest
 	mu         sync.RWMutex
+
+	// Owned by Run
+	seq      uint64      // Sequence number of the last update
+	retained []broadcast // Last updates, oldest first
 }
 
 var GlobalHub = &Hub{
 	clients:    make(map[*Client]bool),
-	broadcast:  make(chan []byte),
+	broadcast:  make(chan shared.ProgressUpdate),
+	resume:     make(chan resumeRequest),
 	Register:   make(chan *Client),
 	Unregister: make(chan *Client),
+	kick:       make(chan kickRequest),
+	seq:        initialSeq(time.Now()),
 }
 
 func (h *Hub) Run() {
@@ -40,57 +74,81 @@ func (h *Hub) Run() {
 			h.clients[client] = true
 			count := len(h.clients)
 			h.mu.Unlock()
-			log.Printf("TCP CLIENT REGISTERED IN HUB: UserID %s from %s — Total clients: %d", client.UserID, client.Conn.RemoteAddr().String(), count)
+			// Completes the handshake. Nothing can be broadcast between the
+			// client learning the sequence number and joining the hub.
+			client.Send <- client.wireLine(okLine(fmt.Sprintf("%s %d", client.Username, h.seq)))
+			log.Printf("TCP CLIENT REGISTERED IN HUB: %s (UserID %s) from %s — Total clients: %d", client.Username, client.UserID, client.Conn.RemoteAddr().String(), count)
 
 		case client := <-h.Unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.Send)
-				log.Printf("TCP CLIENT DISCONNECTED: UserID %s from %s — Remaining clients: %d",
-					client.UserID, client.Conn.RemoteAddr(), len(h.clients))
+			h.remove(client)
+
+		case req := <-h.resume:
+			h.replay(req)
+
+		case req := <-h.kick:
+			client := h.find(req.id)
+			if client != nil {
+				client.setReason(ReasonKicked)
+				h.remove(client)
 			}
-			h.mu.Unlock()
+			req.found <- client != nil
 
-		case message := <-h.broadcast:
+		case update := <-h.broadcast:
+			message, ok := h.record(update)
+			if !ok {
+				continue
+			}
+			var slow []*Client
 			h.mu.RLock()
 			for client := range h.clients {
+				if !client.wants(message.update) {
+					continue
+				}
 				select {
-				case client.Send <- message:
+				case client.Send <- client.wireUpdate(message):
 				default:
-					close(client.Send)
-					delete(h.clients, client)
+					slow = append(slow, client)
 				}
 			}
 			h.mu.RUnlock()
+			// Dropping an update would leave a silent hole in the client's
+			// sequence; disconnecting lets it reconnect and RESUME instead
+			for _, client := range slow {
+				client.setReason(fmt.Sprintf("%s: %d updates queued", ReasonSlowConsumer, len(client.Send)))
+				h.remove(client)
+			}
 		}
 	}
 }
 
-// BroadcastProgress sends a reading progress update to all connected TCP clients.
-// Parameters:
-//   update      - the user's progress data (chapter, status)
-//   username    - display name of the user
-//   mangaTitle  - title of the manga being read
-// This is used when a user updates their reading progress via the API.
-func (h *Hub) BroadcastProgress(update models.UserProgress, username, mangaTitle string) {
-	msg := shared.ProgressUpdate{
-		UserID:         update.UserID,
-		Username:       username,
-		MangaID:        update.MangaID,
-		MangaTitle:     mangaTitle,
-		CurrentChapter: update.CurrentChapter,
-		Status:         update.Status,
-		Timestamp:      time.Now().Unix(),
+// remove takes a client out of the hub and closes its Send channel, which
+// makes its WritePump say BYE and close the connection. Only Run may call
+// it, so Send is closed exactly once.
+func (h *Hub) remove(client *Client) {
+	h.mu.Lock()
+	_, ok := h.clients[client]
+	if ok {
+		delete(h.clients, client)
+		close(client.Send)
 	}
+	count := len(h.clients)
+	h.mu.Unlock()
 
-	data, err := json.Marshal(msg)
-	if err != nil {
-		log.Println("Error marshaling progress update:", err)
-		return
+	if ok {
+		log.Printf("TCP CLIENT DISCONNECTED: UserID %s from %s (%s) — Remaining clients: %d",
+			client.UserID, client.Conn.RemoteAddr(), client.disconnectReason(), count)
+	}
+}
+
+// BroadcastProgress sends a reading progress update to the TCP clients
+// subscribed to its user or manga.
+// This is used when a user updates their reading progress via the API.
+func (h *Hub) BroadcastProgress(msg shared.ProgressUpdate) {
+	if msg.Timestamp == 0 {
+		msg.Timestamp = time.Now().Unix()
 	}
 
-	h.broadcast <- data
+	h.broadcast <- msg
 }
 
 func (h *Hub) GetClientCount() int {
@@ -0,0 +1,96 @@
+package tcp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The TCP progress protocol is line based. A client opens the connection
+// with the handshake
+//
+//	AUTH <access token>
+//
+// using the JWT returned by POST /auth/login. The server answers
+// "OK <username> <seq>", where seq is the sequence number of the last
+// update sent so far, and accepts the commands below; or it answers
+// "ERR <reason>" and closes the connection. Either side may send
+// PING at any time, answered by PONG. The server pings every PingInterval
+// and disconnects clients that send nothing, not even PONG, for
+// MaxMissedPings intervals.
+//
+// Progress updates arrive as JSON lines, but only for subscribed topics,
+// managed with
+//
+//	SUBSCRIBE user:<id> | manga:<id> | self
+//	UNSUBSCRIBE [topic]      (every topic if none is given)
+//	LIST                     (answered by "OK <topic> ...")
+//	RESUME <seq>             (answered by "OK <n>" and the n updates
+//	                          after seq that match the subscriptions)
+//
+// Clients may also submit their own progress with
+//
+//	UPDATE <manga_id> <chapter> [status]
+//
+// which is validated and stored like PUT /users/progress and answered by
+// "OK <event_id>". The update is then broadcast like any other, so a client
+// subscribed to itself receives it back with that event_id.
+//
+// Every update carries a seq. A reconnecting client subscribes again, then
+// sends RESUME with the highest seq it saw, or the one from the handshake
+// if it saw none. If the updates it missed are no longer retained the
+// answer is "ERR gap too large, resync", see ErrGap. The RESUME reply is
+// queued behind updates, so a client should wait for it before sending
+// other commands.
+//
+// A client whose queue of unsent updates fills up is disconnected as a slow
+// consumer and should reconnect and RESUME. The server ends every
+// connection it closes with "BYE <reason>".
+//
+// Each command except PING and PONG is answered by a line starting with
+// OK or ERR.
+//
+// AUTH takes an optional second argument choosing the framing of everything
+// after the handshake: TEXT, the default, or PROTOBUF. With PROTOBUF every
+// message in either direction is a ProgressFrame from proto/manga.proto,
+// preceded by its size as a 4-byte big-endian integer (at most
+// MaxFrameSize). Commands and replies travel as line frames holding the
+// text they would have in text framing, and updates as typed ProgressUpdate
+// frames. The welcome banner, the AUTH line and an ERR reply to it are
+// always text; "OK <username> <seq>" is the first frame. The package
+// mangahub/pkg/progressclient implements this framing for Go clients.
+const (
+	CmdAuth        = "AUTH"
+	CmdPing        = "PING"
+	CmdPong        = "PONG"
+	CmdSubscribe   = "SUBSCRIBE"
+	CmdUnsubscribe = "UNSUBSCRIBE"
+	CmdList        = "LIST"
+	CmdResume      = "RESUME"
+	CmdUpdate      = "UPDATE"
+
+	ReplyOK   = "OK"
+	ReplyErr  = "ERR"
+	ReplyPong = "PONG"
+)
+
+// errorLine formats an ERR reply, without the line break
+func errorLine(format string, args ...interface{}) []byte {
+	return []byte(ReplyErr + " " + fmt.Sprintf(format, args...))
+}
+
+// okLine formats an OK reply, without the line break
+func okLine(detail string) []byte {
+	if detail == "" {
+		return []byte(ReplyOK)
+	}
+	return []byte(ReplyOK + " " + detail)
+}
+
+// parseCommand splits a line into its upper-cased command and arguments
+func parseCommand(line string) (cmd string, args []string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return strings.ToUpper(fields[0]), fields[1:]
+}
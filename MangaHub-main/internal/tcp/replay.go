@@ -0,0 +1,105 @@
+package tcp
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"mangahub/internal/shared"
+)
+
+// RetainedUpdates is how many recent updates the hub keeps for RESUME
+const RetainedUpdates = 1024
+
+// ErrGap is the reply to a RESUME the hub cannot serve. The client should
+// reload its state, e.g. with GET /users/library, and carry on from the
+// sequence number of the next update it receives.
+const ErrGap = "gap too large, resync"
+
+// broadcast is a numbered progress update, encoded once for each framing
+type broadcast struct {
+	update shared.ProgressUpdate
+	line   []byte // JSON line for text clients
+	frame  []byte // Length-prefixed ProgressFrame for protobuf clients
+}
+
+// resumeRequest asks the hub to resend what a client missed after seq
+type resumeRequest struct {
+	client *Client
+	seq    uint64
+}
+
+// initialSeq derives the first sequence number from the start time, so that
+// a restarted hub numbers its updates above those of the one before it (as
+// long as that one sent fewer than 65536 updates a second on average). A
+// client resuming across a restart then gets ErrGap instead of a replay of
+// the wrong updates.
+func initialSeq(start time.Time) uint64 {
+	return uint64(start.Unix()) << 16
+}
+
+// record numbers an update, encodes it and adds it to the retained log.
+// Only Run may call it.
+func (h *Hub) record(update shared.ProgressUpdate) (broadcast, bool) {
+	update.Seq = h.seq + 1
+	data, err := json.Marshal(update)
+	if err != nil {
+		log.Println("Error marshaling progress update:", err)
+		return broadcast{}, false
+	}
+	frame, err := updateFrame(update)
+	if err != nil {
+		log.Println("Error encoding progress update frame:", err)
+		return broadcast{}, false
+	}
+	h.seq++
+
+	message := broadcast{update: update, line: append(data, '\n'), frame: frame}
+	h.retained = append(h.retained, message)
+	if len(h.retained) > RetainedUpdates {
+		h.retained = h.retained[1:]
+	}
+	return message, true
+}
+
+// replay queues the OK reply to RESUME and the updates the client missed
+// after req.seq, or ErrGap if some of them are no longer retained. Running
+// in Run keeps the replay in order with live updates. Only Run may call it.
+func (h *Hub) replay(req resumeRequest) {
+	c := req.client
+	if !h.clients[c] {
+		return // Disconnected meanwhile
+	}
+
+	oldest := h.seq + 1 - uint64(len(h.retained))
+	if req.seq > h.seq || req.seq+1 < oldest {
+		h.queueReply(c, errorLine(ErrGap))
+		return
+	}
+
+	var missed [][]byte
+	for _, m := range h.retained[req.seq+1-oldest:] {
+		if c.wants(m.update) {
+			missed = append(missed, c.wireUpdate(m))
+		}
+	}
+	if len(missed)+1 > cap(c.Send)-len(c.Send) {
+		h.queueReply(c, errorLine(ErrGap))
+		return
+	}
+
+	c.Send <- c.wireLine(okLine(strconv.Itoa(len(missed))))
+	for _, data := range missed {
+		c.Send <- data
+	}
+	log.Printf("TCP CLIENT RESUMED: UserID %s from seq %d — %d update(s) replayed", c.UserID, req.seq, len(missed))
+}
+
+// queueReply sends a reply through Send unless the client's queue is full
+func (h *Hub) queueReply(c *Client, line []byte) {
+	select {
+	case c.Send <- c.wireLine(line):
+	default:
+	}
+}
@@ -0,0 +1,132 @@
+package tcp
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"mangahub/internal/auth"
+	"mangahub/internal/progress"
+)
+
+// ListenAndServe accepts TCP progress clients on addr and registers them
+// with GlobalHub once they authenticate. GlobalHub.Run must be running and
+// auth.JWTSecret must be set. If tlsConfig is not nil, clients must connect
+// with TLS. Progress clients submit with UPDATE is recorded through updates;
+// if it is nil, UPDATE is refused. Tokens are checked against sessions.
+func ListenAndServe(addr string, tlsConfig *tls.Config, updates *progress.Service, sessions auth.Sessions) error {
+	listener, err := net.Listen("tcp", addr) // Open a TCP listener for clients
+	if err != nil {
+		return err
+	}
+	log.Printf("TCP listener started on %s", addr)
+	return Serve(listener, tlsConfig, updates, sessions)
+}
+
+// Serve is ListenAndServe on an open listener. It returns once listener is
+// closed.
+func Serve(listener net.Listener, tlsConfig *tls.Config, updates *progress.Service, sessions auth.Sessions) error {
+	defer listener.Close() // closes when function exits
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	for {
+		conn, err := listener.Accept() // Wait for a new client connection
+		if errors.Is(err, net.ErrClosed) {
+			return err
+		} else if err != nil {
+			log.Println("Error accepting connection:", err)
+			continue
+		}
+		go handleConnection(conn, updates, sessions)
+	}
+}
+
+// HandshakeTimeout bounds how long a new connection may take to complete
+// the TLS handshake, if any, and send AUTH
+const HandshakeTimeout = 10 * time.Second
+
+// handleConnection authenticates a client and serves it until it disconnects
+func handleConnection(conn net.Conn, updates *progress.Service, sessions auth.Sessions) {
+	defer conn.Close() // Close connection when function returns
+
+	remoteAddr := conn.RemoteAddr().String() // Get client IP and port
+	log.Printf("TCP CLIENT CONNECTED: %s", remoteAddr)
+
+	// The deadline also covers the TLS handshake, which runs on the first write
+	conn.SetDeadline(time.Now().Add(HandshakeTimeout))
+	if _, err := fmt.Fprintf(conn, "Welcome to MangaHub Progress!\nSend: AUTH <access token>\n"); err != nil {
+		log.Printf("TCP CLIENT DISCONNECTED (handshake failed): %s → %v", remoteAddr, err)
+		return
+	}
+
+	// The reader keeps whatever the client sends after AUTH for ReadPump
+	reader := bufio.NewReaderSize(conn, MaxFrameSize)
+	line, err := reader.ReadSlice('\n')
+	if err != nil && (err != io.EOF || len(line) == 0) {
+		log.Printf("TCP CLIENT DISCONNECTED (no AUTH sent): %s", remoteAddr)
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	claims, framing, err := authenticate(strings.TrimRight(string(line), "\r\n"), sessions)
+	if err != nil {
+		log.Printf("TCP CLIENT REJECTED: %s → %v", remoteAddr, err)
+		conn.Write(append(errorLine("%v", err), '\n'))
+		return
+	}
+	log.Printf("TCP CLIENT AUTHENTICATED: %s → UserID: %s (%s), %s framing", remoteAddr, claims.UserID, claims.Username, framing)
+
+	// Create a new TCP client object bound to the verified identity
+	client := &Client{
+		ID:          auth.GenerateID("tcp"),
+		ConnectedAt: time.Now(),
+		Conn:        conn,
+		UserID:      claims.UserID,
+		Username:    claims.Username,
+		Send:        make(chan []byte, SendBuffer),
+		reader:      reader,
+		protobuf:    framing == FramingProtobuf,
+		updates:     updates,
+		writerDone:  make(chan struct{}),
+	}
+
+	GlobalHub.Register <- client // Register client to the global hub, which sends the OK
+	// Start goroutine to send messages to client
+	go client.WritePump()
+	client.ReadPump() // Will trigger unregister on disconnect
+}
+
+// authenticate checks the handshake line and returns the token's claims and
+// the framing the client asked for. The token's session must be active.
+func authenticate(line string, sessions auth.Sessions) (*auth.Claims, string, error) {
+	cmd, args := parseCommand(line)
+	if cmd != CmdAuth || len(args) < 1 || len(args) > 2 {
+		return nil, "", errors.New("expected AUTH <access token> [TEXT|PROTOBUF]")
+	}
+	framing := FramingText
+	if len(args) == 2 {
+		framing = strings.ToUpper(args[1])
+		if framing != FramingText && framing != FramingProtobuf {
+			return nil, "", fmt.Errorf("unknown framing %q: use TEXT or PROTOBUF", args[1])
+		}
+	}
+	claims, err := auth.Authenticate(context.Background(), sessions, args[0])
+	if errors.Is(err, auth.ErrInvalidToken) {
+		return nil, "", errors.New("invalid or expired token")
+	} else if errors.Is(err, auth.ErrSessionRevoked) {
+		return nil, "", errors.New("session has been revoked")
+	} else if err != nil {
+		log.Printf("Failed to check session: %v", err)
+		return nil, "", errors.New("failed to check session")
+	}
+	return claims, framing, nil
+}
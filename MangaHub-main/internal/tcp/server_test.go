@@ -0,0 +1,127 @@
+package tcp
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"mangahub/internal/auth"
+)
+
+func TestMain(m *testing.M) {
+	auth.JWTSecret = []byte("tcp-test-secret-0123456789abcdef0123")
+	go GlobalHub.Run()
+	os.Exit(m.Run())
+}
+
+// selfSigned returns a certificate for localhost and 127.0.0.1 and a pool
+// trusting it
+func selfSigned(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+// serveTLS runs Serve over TLS on a loopback port and returns its address
+// and a pool trusting its certificate
+func serveTLS(t *testing.T) (string, *x509.CertPool) {
+	t.Helper()
+	cert, pool := selfSigned(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go Serve(listener, &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil, auth.AnySession)
+	return listener.Addr().String(), pool
+}
+
+func TestTLSListenerAuthenticates(t *testing.T) {
+	addr, pool := serveTLS(t)
+	token, err := auth.GenerateToken("usr_tls", "tls-user", auth.RoleUser, "ses_tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: pool, ServerName: "localhost"})
+	if err != nil {
+		t.Fatalf("TLS dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	reader := bufio.NewReader(conn)
+	for i := 0; i < 2; i++ { // Welcome banner
+		if _, err := reader.ReadString('\n'); err != nil {
+			t.Fatalf("reading banner: %v", err)
+		}
+	}
+	if _, err := conn.Write([]byte("AUTH " + token + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "OK tls-user ") {
+		t.Errorf("AUTH over TLS: got %q, %v; want OK tls-user <seq>", line, err)
+	}
+}
+
+func TestTLSListenerRejectsPlaintext(t *testing.T) {
+	addr, _ := serveTLS(t)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	conn.Write([]byte("AUTH token\n"))
+	line, _ := bufio.NewReader(conn).ReadString('\n')
+	if strings.Contains(line, "Welcome") || strings.HasPrefix(line, ReplyOK) {
+		t.Errorf("plaintext client got %q from the TLS listener", line)
+	}
+}
+
+func TestTLSListenerRejectsUntrustedCertificate(t *testing.T) {
+	addr, _ := serveTLS(t)
+	_, otherPool := selfSigned(t)
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: otherPool, ServerName: "localhost"})
+	if err == nil {
+		conn.Close()
+		t.Fatal("handshake succeeded with a certificate the client does not trust")
+	}
+}
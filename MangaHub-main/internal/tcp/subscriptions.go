@@ -0,0 +1,78 @@
+package tcp
+
+import (
+	"errors"
+	"sort"
+
+	"mangahub/internal/shared"
+)
+
+// Topic prefixes. A client receives an update if it is subscribed to the
+// update's user or manga.
+const (
+	TopicUser  = shared.TopicUser
+	TopicManga = shared.TopicManga
+	// TopicSelf is shorthand for the client's own user topic
+	TopicSelf = shared.TopicSelf
+)
+
+// MaxSubscriptions is how many topics one client may subscribe to
+const MaxSubscriptions = 64
+
+// parseTopic validates a topic argument, expanding self to the client's user
+func (c *Client) parseTopic(arg string) (string, error) {
+	return shared.ParseTopic(arg, c.UserID)
+}
+
+// subscribe adds a topic
+func (c *Client) subscribe(topic string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.subs[topic] {
+		return nil
+	}
+	if len(c.subs) >= MaxSubscriptions {
+		return errors.New("too many subscriptions")
+	}
+	if c.subs == nil {
+		c.subs = map[string]bool{}
+	}
+	c.subs[topic] = true
+	return nil
+}
+
+// unsubscribe removes a topic, or every topic if topic is empty.
+// It reports whether anything was removed.
+func (c *Client) unsubscribe(topic string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if topic == "" {
+		removed := len(c.subs) > 0
+		c.subs = nil
+		return removed
+	}
+	if !c.subs[topic] {
+		return false
+	}
+	delete(c.subs, topic)
+	return true
+}
+
+// Subscriptions returns the client's topics in sorted order
+func (c *Client) Subscriptions() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	topics := make([]string, 0, len(c.subs))
+	for t := range c.subs {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// wants reports whether the client is subscribed to the update's user or manga
+func (c *Client) wants(update shared.ProgressUpdate) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.subs[TopicUser+update.UserID] || c.subs[TopicManga+update.MangaID]
+}
@@ -2,46 +2,194 @@ package tcp
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
 	"time"
+
+	"mangahub/internal/progress"
 )
 
-// reads messages from the TCP client
+// reads messages from the TCP client until it disconnects or stays silent
+// for too long, see PingInterval
 func (c *Client) ReadPump() {
 	// Ensure client is unregistered and connection closed on exit
 	defer func() {
-		GlobalHub.Unregister <- c  // Remove client
+		GlobalHub.Unregister <- c // Remove client
+		<-c.writerDone            // Let WritePump say BYE first
 		c.Conn.Close()
 	}()
 
-	// Scanner reads input line-by-line
-	scanner := bufio.NewScanner(c.Conn)
+	if c.reader == nil {
+		c.reader = bufio.NewReader(c.Conn)
+	}
+	c.refreshDeadline()
+	var err error
+	if c.protobuf {
+		err = c.readFrames()
+	} else {
+		err = c.readLines()
+	}
+	c.setReason(readReason(err))
+}
+
+// readLines runs the commands of a text client until the connection fails,
+// and returns the error
+func (c *Client) readLines() error {
+	// Scanner reads input line-by-line, continuing after the handshake
+	scanner := bufio.NewScanner(c.reader)
 	for scanner.Scan() {
-		line := scanner.Text()
-		// Handle heartbeat response from client
-		if line == "PING" {
-			c.Conn.Write([]byte("PONG\n")) // Reply to keep connection alive
+		c.refreshDeadline() // Any line, usually PONG, shows the client is alive
+		c.handleCommand(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// reply writes a line straight to the connection, ahead of queued updates
+func (c *Client) reply(line []byte) {
+	c.write(c.wireLine(line))
+}
+
+// handleCommand runs one line sent by the client and writes its reply
+func (c *Client) handleCommand(line string) {
+	cmd, args := parseCommand(line)
+	switch cmd {
+	case "":
+		// Ignore blank lines
+	case CmdPing:
+		// Handle heartbeat from client
+		c.reply([]byte(ReplyPong)) // Reply to keep connection alive
+	case CmdPong:
+		// Answer to the server's heartbeat; reading it refreshed the deadline
+
+	case CmdSubscribe:
+		if len(args) != 1 {
+			c.reply(errorLine("usage: SUBSCRIBE user:<id> | manga:<id> | self"))
+			return
+		}
+		topic, err := c.parseTopic(args[0])
+		if err == nil {
+			err = c.subscribe(topic)
+		}
+		if err != nil {
+			c.reply(errorLine("%v", err))
+			return
+		}
+		c.reply(okLine("subscribed " + topic))
+
+	case CmdUnsubscribe:
+		if len(args) > 1 {
+			c.reply(errorLine("usage: UNSUBSCRIBE [topic]"))
+			return
+		}
+		topic := ""
+		if len(args) == 1 {
+			t, err := c.parseTopic(args[0])
+			if err != nil {
+				c.reply(errorLine("%v", err))
+				return
+			}
+			topic = t
+		}
+		if !c.unsubscribe(topic) && topic != "" {
+			c.reply(errorLine("not subscribed to %s", topic))
+			return
 		}
+		c.reply(okLine("unsubscribed"))
+
+	case CmdList:
+		c.reply(okLine(strings.Join(c.Subscriptions(), " ")))
+
+	case CmdResume:
+		var seq uint64
+		var err error
+		if len(args) == 1 {
+			seq, err = strconv.ParseUint(args[0], 10, 64)
+		}
+		if len(args) != 1 || err != nil {
+			c.reply(errorLine("usage: RESUME <seq>"))
+			return
+		}
+		// The hub replies, in order with the updates it replays
+		GlobalHub.resume <- resumeRequest{client: c, seq: seq}
+
+	case CmdUpdate:
+		c.reply(c.submitProgress(args))
+
+	default:
+		c.reply(errorLine("unknown command %s", cmd))
 	}
 }
 
-// sends messages to the TCP client
+// sends messages to the TCP client and pings it every PingInterval. When
+// the hub closes Send it says BYE with the reason and closes the connection,
+// which also ends ReadPump.
 func (c *Client) WritePump() {
-	// Create a ticker to send heartbeat every 30 seconds
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(PingInterval)
 	defer ticker.Stop()
+	defer close(c.writerDone)
+	defer c.Conn.Close()
 
 	for {
+		var err error
 		select {
 		// Send application messages to the client
 		case message, ok := <-c.Send:
 			if !ok {
-				c.Conn.Write([]byte("BYE\n"))
+				c.write(c.wireLine([]byte("BYE " + c.disconnectReason())))
 				return
 			}
-			c.Conn.Write(append(message, '\n'))
+			err = c.write(message)
 		// Periodic heartbeat to client
 		case <-ticker.C:
-			c.Conn.Write([]byte("PING\n"))
+			err = c.write(c.wireLine([]byte(CmdPing)))
+		}
+		if err != nil {
+			c.setReason(fmt.Sprintf("%s: %v", ReasonWriteFailed, err))
+			return
 		}
 	}
-}
\ No newline at end of file
+}
+
+// UpdateTimeout bounds the storing of one UPDATE
+const UpdateTimeout = 5 * time.Second
+
+// submitProgress records an UPDATE command and returns its reply
+func (c *Client) submitProgress(args []string) []byte {
+	if c.updates == nil {
+		return errorLine("updates are not supported by this server")
+	}
+	if len(args) < 2 || len(args) > 3 {
+		return errorLine("usage: UPDATE <manga_id> <chapter> [status]")
+	}
+	chapter, err := strconv.Atoi(args[1])
+	if err != nil {
+		return errorLine("chapter must be a number")
+	}
+	status := ""
+	if len(args) == 3 {
+		status = args[2]
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), UpdateTimeout)
+	defer cancel()
+	update, err := c.updates.Record(ctx, progress.Update{
+		UserID:         c.UserID,
+		Username:       c.Username,
+		MangaID:        args[0],
+		CurrentChapter: chapter,
+		Status:         status,
+	})
+	if errors.Is(err, progress.ErrInvalid) || errors.Is(err, progress.ErrMangaNotFound) {
+		return errorLine("%v", err)
+	} else if err != nil {
+		log.Printf("TCP UPDATE FAILED: UserID %s, manga %s: %v", c.UserID, args[0], err)
+		return errorLine("failed to update progress")
+	}
+
+	log.Printf("TCP PROGRESS UPDATE: %s → %s chapter %d", c.Username, update.MangaTitle, chapter)
+	return okLine(update.EventID)
+}
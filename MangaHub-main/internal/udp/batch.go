@@ -0,0 +1,124 @@
+package udp
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// Batching and fragmentation. Updates for a subscriber are collected for
+// CoalesceWindow and then sent one per line, as many to a datagram as fit
+// in MTU bytes. A line is a JSON update or, for reliable subscribers, a
+// DATA line. A line longer than MTU is split into fragments, each in a
+// datagram of its own:
+//
+//	FRAG <message id> <index>/<count> <bytes>
+//
+// with index counting from 0. The client joins the bytes of the count
+// fragments of a message id in index order and handles the result like a
+// datagram. Message IDs count from 1 for each subscription, and a reliable
+// update is retransmitted with the same one, so fragments of different
+// sends combine. Updates that would need more than MaxFragments are not
+// sent.
+const (
+	CmdFrag      = "FRAG"
+	MaxFragments = 64
+)
+
+// MTU and CoalesceWindow are set by servers.StartUDP from the configuration
+// before the hub runs
+var (
+	MTU            = 1200
+	CoalesceWindow = 20 * time.Millisecond
+)
+
+// maxFragHeader is the longest "FRAG <message id> <index>/<count> " prefix
+const maxFragHeader = len(CmdFrag + " 4294967295 64/64 ")
+
+// maxDataHeader is the longest "DATA <seq> " prefix of a reliable update
+const maxDataHeader = len(ReplyData + " 18446744073709551615 ")
+
+// fits reports whether an update, before its line break, can be sent in at
+// most MaxFragments
+func fits(data []byte) bool {
+	return maxDataHeader+len(data)+1 <= MaxFragments*(MTU-maxFragHeader)
+}
+
+// line is a message waiting for the next datagram to a subscriber
+type line struct {
+	data []byte
+	frag uint32 // Message id of its fragments, if longer than MTU
+}
+
+// queue adds a line to the subscriber's next datagram. The hub's lock must
+// be held.
+func (c *ClientAddr) queue(data []byte, frag uint32) {
+	c.queued = append(c.queued, line{data: data, frag: frag})
+	GlobalHub.counters.updatesSent.Add(1)
+}
+
+// fragID returns a new message id if data needs fragments, or 0. The hub's
+// lock must be held.
+func (c *ClientAddr) fragID(data []byte) uint32 {
+	if len(data) <= MTU {
+		return 0
+	}
+	c.lastFrag++
+	return c.lastFrag
+}
+
+// flush sends the queued lines in as few datagrams as MTU allows. The
+// hub's lock must be held.
+func (c *ClientAddr) flush() {
+	var datagram []byte
+	send := func() {
+		if len(datagram) == 0 {
+			return
+		}
+		if _, err := udpConn.WriteToUDP(datagram, c.Addr); err != nil {
+			log.Printf("UDP send failed to %s: %v", c.Addr, err)
+		}
+		GlobalHub.counters.datagramsSent.Add(1)
+		datagram = datagram[:0]
+	}
+
+	for _, l := range c.queued {
+		if len(l.data) > MTU {
+			send()
+			c.sendFragments(l)
+			continue
+		}
+		if len(datagram)+len(l.data) > MTU {
+			send()
+		}
+		datagram = append(datagram, l.data...)
+	}
+	send()
+
+	clear(c.queued)
+	c.queued = c.queued[:0]
+}
+
+// sendFragments sends a line longer than MTU as FRAG datagrams
+func (c *ClientAddr) sendFragments(l line) {
+	size := MTU - maxFragHeader
+	count := (len(l.data) + size - 1) / size
+	for i := 0; i < count; i++ {
+		chunk := l.data[i*size : min((i+1)*size, len(l.data))]
+		datagram := append([]byte(fmt.Sprintf("%s %d %d/%d ", CmdFrag, l.frag, i, count)), chunk...)
+		if _, err := udpConn.WriteToUDP(datagram, c.Addr); err != nil {
+			log.Printf("UDP send failed to %s: %v", c.Addr, err)
+		}
+		GlobalHub.counters.datagramsSent.Add(1)
+	}
+	GlobalHub.counters.fragmented.Add(1)
+}
+
+// flush sends what is queued for every subscriber
+func (h *Hub) flush() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, client := range h.clients {
+		client.flush()
+	}
+}
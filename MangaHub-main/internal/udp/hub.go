@@ -2,32 +2,53 @@ package udp
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net"
+	"sort"
 	"sync"
 	"time"
 
 	"mangahub/internal/shared"
-	"mangahub/pkg/models"
 )
 
+// ClientAddr is a subscription made with SUB
 type ClientAddr struct {
-	Addr     *net.UDPAddr
-	LastSeen time.Time // Last activity time (heartbeat)
+	ID          string // Signed into the subscription ID, see signSubscription
+	UserID      string
+	Username    string
+	Addr        *net.UDPAddr
+	Topics      map[string]bool // Replaced when the subscription is renewed
+	LastSeen    time.Time       // Last datagram received from the client
+	ConnectedAt time.Time
+	Expires     time.Time // When the subscription ends unless renewed, see SubscriptionLifetime
+	Reliable    bool      // Updates are numbered and acknowledged, see CmdAck
+
+	lastSeq  uint64              // Seq of the last update sent, if Reliable
+	pending  map[uint64]*pending // Updates not yet acknowledged, by seq
+	queued   []line              // Lines for the next datagram, see CoalesceWindow
+	lastFrag uint32              // Message id of the last fragmented line
+}
+
+// message is an update and its JSON encoding
+type message struct {
+	update shared.ProgressUpdate
+	data   []byte
 }
 
 // Hub manages all UDP subscribers and broadcasts
 type Hub struct {
 	clients   map[string]*ClientAddr
-	broadcast chan []byte // Channel for outgoing messages
-	Register  chan *ClientAddr // Channel for new/updated clients
+	broadcast chan message     // Channel for outgoing messages
+	Register  chan *ClientAddr // Channel for new subscriptions
 	mu        sync.RWMutex
+	counters  counters
 }
 
 var GlobalHub = &Hub{
 	// Initialize client storage, chanel
 	clients:   make(map[string]*ClientAddr),
-	broadcast: make(chan []byte),
+	broadcast: make(chan message),
 	Register:  make(chan *ClientAddr),
 }
 
@@ -35,45 +56,68 @@ func (h *Hub) Run() {
 	// Ticker for cleaning up inactive clients
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
+	// Ticker for retransmitting to reliable subscribers
+	retransmit := time.NewTicker(retransmitTick)
+	defer retransmit.Stop()
+	// Fires CoalesceWindow after the first update was queued, nil until then
+	var flush <-chan time.Time
 
 	for {
 		select {
-		// Handle new client registration
+		// Handle new subscriptions, replacing any earlier one of the address
 		case client := <-h.Register:
 			key := client.Addr.String()
 			h.mu.Lock()
-			if old, exists := h.clients[key]; exists {
-				old.LastSeen = time.Now() // Update heartbeat if client already exists
-			} else {
-				// Register new UDP subscriber
-				h.clients[key] = client
-				log.Printf("UDP CLIENT SUBSCRIBED: %s (Total: %d)", key, len(h.clients))
-			}
+			h.clients[key] = client
+			log.Printf("UDP CLIENT SUBSCRIBED: %s as %s to %v (Total: %d)", key, client.Username, client.subscriptions(), len(h.clients))
 			h.mu.Unlock()
 
-		// Broadcast message to all registered clients
-		case message := <-h.broadcast:
-			h.mu.RLock()
+		// Queue message for the clients subscribed to it
+		case m := <-h.broadcast:
+			h.mu.Lock()
+			now := time.Now()
 			for key, client := range h.clients {
-				// Send UDP packet to client address
-				_, err := udpConn.WriteToUDP(message, client.Addr)
-				if err != nil {
-					log.Printf("UDP send failed to %s: %v", key, err)
-				} else {
-					client.LastSeen = time.Now() // Update activity timestamp
+				if !client.wants(m.update) {
+					continue
 				}
+				if client.Reliable {
+					if !client.sendReliable(m.data, now) {
+						delete(h.clients, key)
+						udpConn.WriteToUDP([]byte(fmt.Sprintf("%s slow consumer: %d updates unacknowledged\n", ReplyBye, MaxUnacked)), client.Addr)
+						log.Printf("UDP CLIENT DROPPED (slow consumer): %s (Remaining: %d)", key, len(h.clients))
+					}
+					continue
+				}
+				client.queue(m.data, client.fragID(m.data))
+			}
+			h.mu.Unlock()
+			if CoalesceWindow <= 0 {
+				h.flush()
+			} else if flush == nil {
+				flush = time.After(CoalesceWindow)
 			}
-			h.mu.RUnlock()
+
+		// Send the queued updates, several to a datagram
+		case <-flush:
+			flush = nil
+			h.flush()
+
+		case <-retransmit.C:
+			h.retransmit()
 
 		// Periodic cleanup of inactive clients
 		case <-ticker.C:
 			h.mu.Lock()
 			now := time.Now()
 			for key, client := range h.clients {
-				// Remove clients inactive for over 30 seconds
-				if now.Sub(client.LastSeen) > 30*time.Second {
+				// Remove clients inactive for over SubscriptionTimeout
+				if now.Sub(client.LastSeen) > SubscriptionTimeout {
 					delete(h.clients, key)
 					log.Printf("UDP CLIENT TIMED OUT: %s (Remaining: %d)", key, len(h.clients))
+				} else if !client.Expires.IsZero() && now.After(client.Expires) {
+					delete(h.clients, key)
+					udpConn.WriteToUDP([]byte(ReplyBye+" subscription expired\n"), client.Addr)
+					log.Printf("UDP SUBSCRIPTION EXPIRED: %s (Remaining: %d)", key, len(h.clients))
 				}
 			}
 			h.mu.Unlock()
@@ -81,25 +125,69 @@ func (h *Hub) Run() {
 	}
 }
 
-func (h *Hub) BroadcastProgress(update models.UserProgress, username, mangaTitle string) {
-	msg := shared.ProgressUpdate{
-		UserID:         update.UserID,
-		Username:       username,
-		MangaID:        update.MangaID,
-		MangaTitle:     mangaTitle,
-		CurrentChapter: update.CurrentChapter,
-		Status:         update.Status,
-		Timestamp:      time.Now().Unix(),
-		Type:           "progress_update",
+// BroadcastProgress sends a reading progress update to the UDP subscribers
+// of its user or manga
+func (h *Hub) BroadcastProgress(msg shared.ProgressUpdate) {
+	if msg.Timestamp == 0 {
+		msg.Timestamp = time.Now().Unix()
 	}
+	msg.Type = "progress_update"
 
 	data, err := json.Marshal(msg)
 	if err != nil {
 		log.Println("UDP marshal error:", err)
 		return
 	}
+	if !fits(data) {
+		log.Printf("UDP update of %d bytes needs more than %d fragments, not sent", len(data), MaxFragments)
+		return
+	}
 
-	h.broadcast <- append(data, '\n')
+	h.broadcast <- message{update: msg, data: append(data, '\n')}
+}
+
+// refresh records a PING for the subscription id of the address key. It
+// reports whether that subscription exists.
+func (h *Hub) refresh(key, id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	client, ok := h.clients[key]
+	if !ok || client.ID != id {
+		return false
+	}
+	client.LastSeen = time.Now()
+	return true
+}
+
+// renew restarts the subscription of the address key if it belongs to
+// userID and has the same delivery mode, replacing its topics. It returns
+// the subscription's ID and reports whether there was one to renew.
+func (h *Hub) renew(key, userID string, reliable bool, topics map[string]bool, expires time.Time) (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	client, ok := h.clients[key]
+	if !ok || client.UserID != userID || client.Reliable != reliable {
+		return "", false
+	}
+	client.Topics = topics
+	client.Expires = expires
+	client.LastSeen = time.Now()
+	log.Printf("UDP SUBSCRIPTION RENEWED: %s as %s to %v", key, client.Username, client.subscriptions())
+	return client.ID, true
+}
+
+// unsubscribe ends the subscription id of the address key. It reports
+// whether that subscription existed.
+func (h *Hub) unsubscribe(key, id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	client, ok := h.clients[key]
+	if !ok || client.ID != id {
+		return false
+	}
+	delete(h.clients, key)
+	log.Printf("UDP CLIENT UNSUBSCRIBED: %s (Remaining: %d)", key, len(h.clients))
+	return true
 }
 
 func (h *Hub) GetClientCount() int {
@@ -107,3 +195,74 @@ func (h *Hub) GetClientCount() int {
 	defer h.mu.RUnlock()
 	return len(h.clients)
 }
+
+// subscribed reports whether the address key has a subscription
+func (h *Hub) subscribed(key string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, ok := h.clients[key]
+	return ok
+}
+
+// hasRoom reports whether the address key may subscribe without exceeding
+// MaxSubscribers
+func (h *Hub) hasRoom(key string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, replacing := h.clients[key]
+	return replacing || len(h.clients) < MaxSubscribers
+}
+
+// Stats returns the number of subscribers and the datagram counters
+func (h *Hub) Stats() map[string]uint64 {
+	return map[string]uint64{
+		"subscribers":      uint64(h.GetClientCount()),
+		"received":         h.counters.received.Load(),
+		"rate_limited":     h.counters.rateLimited.Load(),
+		"cookie_required":  h.counters.cookieRequired.Load(),
+		"server_full":      h.counters.serverFull.Load(),
+		"replies_withheld": h.counters.repliesWithheld.Load(),
+		"oversized":        h.counters.oversized.Load(),
+		"updates_sent":     h.counters.updatesSent.Load(),
+		"datagrams_sent":   h.counters.datagramsSent.Load(),
+		"fragmented":       h.counters.fragmented.Load(),
+	}
+}
+
+// Clients lists the subscribers, oldest first
+func (h *Hub) Clients() []shared.ClientInfo {
+	h.mu.RLock()
+	clients := make([]shared.ClientInfo, 0, len(h.clients))
+	for key, client := range h.clients {
+		clients = append(clients, shared.ClientInfo{
+			ID:            client.ID,
+			UserID:        client.UserID,
+			Username:      client.Username,
+			RemoteAddr:    key,
+			ConnectedAt:   client.ConnectedAt,
+			LastActivity:  client.LastSeen,
+			QueueDepth:    len(client.pending),
+			Subscriptions: client.subscriptions(),
+		})
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(clients, func(i, j int) bool { return clients[i].ConnectedAt.Before(clients[j].ConnectedAt) })
+	return clients
+}
+
+// Disconnect removes the subscriber with the given ID and tells it with
+// "BYE disconnected by admin". It reports whether the subscriber was found.
+func (h *Hub) Disconnect(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for key, client := range h.clients {
+		if client.ID == id {
+			delete(h.clients, key)
+			udpConn.WriteToUDP([]byte(ReplyBye+" disconnected by admin\n"), client.Addr)
+			log.Printf("UDP CLIENT DISCONNECTED BY ADMIN: %s (Remaining: %d)", key, len(h.clients))
+			return true
+		}
+	}
+	return false
+}
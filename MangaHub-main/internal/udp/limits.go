@@ -0,0 +1,140 @@
+package udp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Limits protecting the server and others from abuse. Every source IP may
+// send RatePerIP datagrams per second, in bursts of up to BurstPerIP; the
+// rest are dropped unanswered. At most MaxSubscribers addresses are
+// subscribed at once. A SUB only counts when it carries a cookie, see
+// CmdCookie, which proves the address receives what is sent to it, and an
+// address without a subscription never gets a reply longer than its
+// request, so the server cannot be used to amplify spoofed traffic.
+const (
+	RatePerIP      = 20
+	BurstPerIP     = 40
+	MaxSubscribers = 10000
+)
+
+// The cookie handshake. A SUB without a valid cookie is answered by
+//
+//	COOKIE <cookie>
+//
+// and the client sends the SUB again behind the cookie:
+//
+//	COOKIE <cookie> SUB <access token> [RELIABLE] <topic> [topic...]
+//
+// Cookies are tied to the address and stay valid for one to two
+// CookieLifetime periods; an expired one is answered by a new COOKIE.
+const (
+	CmdCookie      = "COOKIE"
+	CookieLifetime = 30 * time.Second
+)
+
+// cookie returns the cookie of addr for the given period
+func cookie(addr string, period int64) string {
+	var p [8]byte
+	binary.BigEndian.PutUint64(p[:], uint64(period))
+	mac := hmac.New(sha256.New, subscriptionKey)
+	mac.Write([]byte("cookie " + addr + " "))
+	mac.Write(p[:])
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// newCookie returns the current cookie of addr
+func newCookie(addr string, now time.Time) string {
+	return cookie(addr, now.Unix()/int64(CookieLifetime/time.Second))
+}
+
+// validCookie reports whether c is the current or previous cookie of addr
+func validCookie(c, addr string, now time.Time) bool {
+	period := now.Unix() / int64(CookieLifetime/time.Second)
+	return hmac.Equal([]byte(c), []byte(cookie(addr, period))) ||
+		hmac.Equal([]byte(c), []byte(cookie(addr, period-1)))
+}
+
+// splitCookie removes a leading "COOKIE <cookie>" from a command
+func splitCookie(message string) (cookie, command string) {
+	word, rest, _ := strings.Cut(strings.TrimSpace(message), " ")
+	if !strings.EqualFold(word, CmdCookie) {
+		return "", message
+	}
+	cookie, command, ok := strings.Cut(strings.TrimSpace(rest), " ")
+	if !ok {
+		return "", message
+	}
+	return cookie, command
+}
+
+// bucket is the token bucket of one source IP
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter rate-limits source IPs. It is used by readPump only.
+type limiter struct {
+	buckets map[netip.Addr]*bucket
+	swept   time.Time
+}
+
+// limiterSweep is how often buckets that have refilled are forgotten
+const limiterSweep = 10 * time.Second
+
+// allow takes a token from the bucket of ip, reporting false if it is empty
+func (l *limiter) allow(ip netip.Addr, now time.Time) bool {
+	if now.Sub(l.swept) > limiterSweep {
+		l.sweep(now)
+	}
+	b, ok := l.buckets[ip]
+	if !ok {
+		b = &bucket{tokens: BurstPerIP, last: now}
+		l.buckets[ip] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * RatePerIP
+	if b.tokens > BurstPerIP {
+		b.tokens = BurstPerIP
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// sweep forgets the buckets that are full again, which keeps spoofed
+// source IPs from filling memory
+func (l *limiter) sweep(now time.Time) {
+	if l.buckets == nil {
+		l.buckets = map[netip.Addr]*bucket{}
+	}
+	for ip, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*RatePerIP >= BurstPerIP {
+			delete(l.buckets, ip)
+		}
+	}
+	l.swept = now
+}
+
+// Counters of the datagrams the server received, those it dropped or
+// refused, and those it sent to subscribers, reported by Hub.Stats
+type counters struct {
+	received        atomic.Uint64
+	rateLimited     atomic.Uint64 // Dropped by the per-IP limit
+	cookieRequired  atomic.Uint64 // SUBs without a valid cookie, answered by COOKIE
+	serverFull      atomic.Uint64 // SUBs refused because of MaxSubscribers
+	repliesWithheld atomic.Uint64 // Replies not sent because they were longer than the request
+	oversized       atomic.Uint64 // Commands longer than MaxCommand
+	updatesSent     atomic.Uint64 // Lines queued for subscribers, including retransmits
+	datagramsSent   atomic.Uint64 // Datagrams they were sent in, see CoalesceWindow
+	fragmented      atomic.Uint64 // Lines split into fragments, see CmdFrag
+}
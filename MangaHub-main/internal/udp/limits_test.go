@@ -0,0 +1,234 @@
+package udp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mangahub/internal/auth"
+	"mangahub/internal/shared"
+)
+
+// serverAddr is the in-process server the tests send to
+var serverAddr *net.UDPAddr
+
+func TestMain(m *testing.M) {
+	auth.JWTSecret = []byte("udp-test-secret-0123456789abcdef0123")
+	go GlobalHub.Run()
+	addr, err := Listen("127.0.0.1:0", auth.AnySession)
+	if err != nil {
+		panic(err)
+	}
+	serverAddr = addr.(*net.UDPAddr)
+	os.Exit(m.Run())
+}
+
+// lastIP numbers the loopback addresses handed out by dialFrom
+var lastIP atomic.Uint32
+
+// dialFrom connects to the server from a loopback address not used before,
+// so that every connection has a full rate limit of its own
+func dialFrom(t *testing.T) *net.UDPConn {
+	t.Helper()
+	n := lastIP.Add(1) + 1
+	ip := net.IPv4(127, 0, byte(n>>8), byte(n))
+	conn, err := net.DialUDP("udp", &net.UDPAddr{IP: ip}, serverAddr)
+	if err != nil {
+		t.Skipf("cannot send from %s: %v", ip, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// exchange sends message and returns the reply, without the line break,
+// or reports false if none came
+func exchange(t *testing.T, conn *net.UDPConn, message string) (string, bool) {
+	t.Helper()
+	if _, err := conn.Write([]byte(message)); err != nil {
+		t.Fatal(err)
+	}
+	return read(t, conn, 200*time.Millisecond)
+}
+
+// read returns the next datagram received within timeout
+func read(t *testing.T, conn *net.UDPConn, timeout time.Duration) (string, bool) {
+	t.Helper()
+	buf := make([]byte, 64<<10)
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	n, err := conn.Read(buf)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "", false
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSuffix(string(buf[:n]), "\n"), true
+}
+
+// subCommand returns a SUB to manga:<mangaID> for a user of its own
+func subCommand(t *testing.T, mangaID string) string {
+	t.Helper()
+	token, err := auth.GenerateToken("usr_"+mangaID, "reader", auth.RoleUser, "ses_"+mangaID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprintf("%s %s %s%s", CmdSub, token, shared.TopicManga, mangaID)
+}
+
+// counter returns a counter of Stats
+func counter(name string) uint64 {
+	return GlobalHub.Stats()[name]
+}
+
+func TestLimiterRefills(t *testing.T) {
+	var l limiter
+	ip := netip.MustParseAddr("192.0.2.1")
+	now := time.Now()
+	for i := 0; i < BurstPerIP; i++ {
+		if !l.allow(ip, now) {
+			t.Fatalf("datagram %d of the burst refused", i+1)
+		}
+	}
+	if l.allow(ip, now) {
+		t.Error("datagram beyond the burst allowed")
+	}
+	if !l.allow(netip.MustParseAddr("192.0.2.2"), now) {
+		t.Error("another IP was limited too")
+	}
+
+	// RatePerIP tokens a second come back
+	later := now.Add(2 * time.Second / RatePerIP)
+	if !l.allow(ip, later) || !l.allow(ip, later) || l.allow(ip, later) {
+		t.Error("want exactly 2 datagrams allowed after two refill periods")
+	}
+}
+
+func TestBurstBeyondLimitIsDropped(t *testing.T) {
+	const sent = 100
+	conn := dialFrom(t)
+	received, limited := counter("received"), counter("rate_limited")
+
+	for i := 0; i < sent; i++ {
+		if _, err := conn.Write([]byte("PING x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for counter("received")-received < sent && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A few tokens may come back while the burst is sent
+	dropped := counter("rate_limited") - limited
+	if dropped > sent-BurstPerIP || dropped < sent-BurstPerIP-5 {
+		t.Errorf("%d of %d datagrams dropped, want about %d", dropped, sent, sent-BurstPerIP)
+	}
+}
+
+func TestSubWithoutValidCookieIsRefused(t *testing.T) {
+	conn := dialFrom(t)
+	key := conn.LocalAddr().String()
+	sub := subCommand(t, "cookie")
+	required := counter("cookie_required")
+
+	refused := []string{
+		sub,
+		CmdCookie + " not-a-cookie " + sub,
+		CmdCookie + " " + newCookie("127.0.0.1:1", time.Now()) + " " + sub, // Minted for another address
+		CmdCookie + " " + newCookie(key, time.Now().Add(-3*CookieLifetime)) + " " + sub,
+	}
+	var cookie string
+	for _, message := range refused {
+		reply, ok := exchange(t, conn, message)
+		if !ok || !strings.HasPrefix(reply, CmdCookie+" ") {
+			t.Fatalf("reply %q to %.40q, want a COOKIE", reply, message)
+		}
+		cookie = strings.TrimPrefix(reply, CmdCookie+" ")
+	}
+	if GlobalHub.subscribed(key) {
+		t.Fatal("subscribed without a valid cookie")
+	}
+	if n := counter("cookie_required") - required; n != uint64(len(refused)) {
+		t.Errorf("cookie_required counted %d, want %d", n, len(refused))
+	}
+
+	GlobalHub.BroadcastProgress(shared.ProgressUpdate{MangaID: "cookie", CurrentChapter: 1})
+	if data, ok := read(t, conn, 200*time.Millisecond); ok {
+		t.Errorf("unsubscribed address got %q", data)
+	}
+
+	// The cookie it was given works
+	if reply, _ := exchange(t, conn, CmdCookie+" "+cookie+" "+sub); !strings.HasPrefix(reply, ReplyOK+" ") {
+		t.Fatalf("SUB with the cookie: got %q, want OK", reply)
+	}
+	GlobalHub.BroadcastProgress(shared.ProgressUpdate{MangaID: "cookie", CurrentChapter: 2})
+	if data, ok := read(t, conn, 2*time.Second); !ok || !strings.Contains(data, `"current_chapter":2`) {
+		t.Errorf("subscriber got %q, want chapter 2", data)
+	}
+}
+
+func TestUnverifiedAddressGetsNoLargerReply(t *testing.T) {
+	conn := dialFrom(t)
+	withheld := counter("replies_withheld")
+
+	messages := []string{
+		"", "X", "SUB", "SUB a", "SUB a b", "PING", "PING x", "ACK", "ACK x 1",
+		"UNSUB x", "COOKIE", "COOKIE x SUB", "HELP", strings.Repeat("A", MaxCommand+1),
+	}
+	for _, message := range messages {
+		if reply, ok := exchange(t, conn, message); ok && len(reply)+1 > len(message) {
+			t.Errorf("%d byte reply %q to the %d byte %.20q", len(reply)+1, reply, len(message), message)
+		}
+	}
+	if counter("replies_withheld") == withheld {
+		t.Error("no reply was withheld")
+	}
+}
+
+func TestServerFullRefusesSubscriber(t *testing.T) {
+	conn := dialFrom(t)
+	key := conn.LocalAddr().String()
+
+	// Fill the hub with idle subscribers
+	var fakes []string
+	GlobalHub.mu.Lock()
+	now := time.Now()
+	for port := 1; len(GlobalHub.clients) < MaxSubscribers; port++ {
+		addr := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: port}
+		GlobalHub.clients[addr.String()] = &ClientAddr{ID: fmt.Sprintf("udp_fake%d", port), Addr: addr, LastSeen: now, Expires: now.Add(time.Hour)}
+		fakes = append(fakes, addr.String())
+	}
+	GlobalHub.mu.Unlock()
+	t.Cleanup(func() {
+		GlobalHub.mu.Lock()
+		defer GlobalHub.mu.Unlock()
+		for _, fake := range fakes {
+			delete(GlobalHub.clients, fake)
+		}
+	})
+
+	sub := subCommand(t, "full")
+	reply, _ := exchange(t, conn, sub)
+	sub = reply + " " + sub // Behind the cookie
+	full := counter("server_full")
+	if reply, _ := exchange(t, conn, sub); reply != ReplyErr+" server full" {
+		t.Errorf("subscriber %d got %q, want ERR server full", MaxSubscribers+1, reply)
+	}
+	if counter("server_full") != full+1 || GlobalHub.subscribed(key) {
+		t.Error("refused subscriber was not counted or was subscribed")
+	}
+
+	// Room is made when one leaves
+	GlobalHub.mu.Lock()
+	delete(GlobalHub.clients, fakes[0])
+	GlobalHub.mu.Unlock()
+	if reply, _ := exchange(t, conn, sub); !strings.HasPrefix(reply, ReplyOK+" ") {
+		t.Errorf("got %q once there was room, want OK", reply)
+	}
+}
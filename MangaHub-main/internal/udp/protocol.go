@@ -0,0 +1,190 @@
+package udp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"mangahub/internal/auth"
+	"mangahub/internal/shared"
+)
+
+// The UDP notification protocol. Every datagram from a client holds one
+// command of at most MaxCommand bytes. A client subscribes with
+//
+//	SUB <access token> <topic> [topic...]
+//
+// using the JWT returned by POST /auth/login and the topics of the TCP
+// protocol: user:<id>, manga:<id> or self. The first SUB is answered by a
+// cookie and must be sent again behind it, see CmdCookie. The server then
+// answers "OK <subscription id> <lifetime>", or "ERR <reason>", where
+// lifetime is the seconds until the subscription expires, see
+// SubscriptionLifetime. A second SUB from the same address replaces the
+// first; if it is for the same user and delivery mode, it renews it instead,
+// keeping its ID and unacknowledged updates but taking the new topics and
+// starting a new lifetime. Updates on the topics then arrive as JSON
+// lines, several to a datagram and long ones in fragments (see CmdFrag), or,
+// with RELIABLE after the token, numbered and retransmitted until
+// acknowledged; see CmdAck.
+//
+// The subscription ID is signed for the address it was issued to. The
+// client keeps the subscription alive with
+//
+//	PING <subscription id>
+//
+// at least every SubscriptionTimeout, answered by PONG, and ends it with
+//
+//	UNSUB <subscription id>
+//
+// answered by OK. A PING or UNSUB that does not carry the ID issued to its
+// source address is answered by ERR, so a spoofed address can neither keep
+// nor take over a subscription; the client should SUB again, e.g. after its
+// address changed. A subscription that is not renewed ends after its
+// lifetime, announced by "BYE subscription expired".
+const (
+	CmdSub   = "SUB"
+	CmdUnsub = "UNSUB"
+	CmdPing  = "PING"
+
+	ReplyOK   = "OK"
+	ReplyErr  = "ERR"
+	ReplyPong = "PONG"
+	ReplyBye  = "BYE"
+)
+
+// SubscriptionTimeout is how long a subscription lasts without PING
+const SubscriptionTimeout = 30 * time.Second
+
+// SubscriptionLifetime is how long a subscription lasts unless renewed by
+// another SUB, independent of the expiry of the token it was made with. It
+// is set by servers.StartUDP from the configuration before the hub runs.
+var SubscriptionLifetime = time.Hour
+
+// MaxTopics is how many topics one SUB may name
+const MaxTopics = 64
+
+// handleCommand runs the command in one datagram from addr and returns the
+// reply, without the line break, or "" if there is none. SUB tokens are
+// checked against sessions.
+func handleCommand(addr *net.UDPAddr, message string, sessions auth.Sessions) string {
+	cookie, message := splitCookie(message)
+	fields := strings.Fields(message)
+	if len(fields) == 0 {
+		return errorLine("empty command")
+	}
+
+	cmd, args := strings.ToUpper(fields[0]), fields[1:]
+	switch cmd {
+	case CmdSub:
+		// Prove the address is real before anything is sent to it
+		now := time.Now()
+		if !validCookie(cookie, addr.String(), now) {
+			GlobalHub.counters.cookieRequired.Add(1)
+			return CmdCookie + " " + newCookie(addr.String(), now)
+		}
+		return subscribe(addr, args, sessions)
+
+	case CmdAck:
+		if len(args) < 2 {
+			return errorLine("usage: ACK <subscription id> <seq> [seq...]")
+		}
+		id, ok := verifySubscription(args[0], addr.String())
+		if !ok || !GlobalHub.ack(addr.String(), id, args[1:]) {
+			return errorLine("unknown subscription, SUB again")
+		}
+		return "" // ACKs are not answered
+
+	case CmdPing, CmdUnsub:
+		if len(args) != 1 {
+			return errorLine("usage: %s <subscription id>", cmd)
+		}
+		id, ok := verifySubscription(args[0], addr.String())
+		if !ok {
+			return errorLine("unknown subscription, SUB again")
+		}
+		if cmd == CmdPing {
+			ok = GlobalHub.refresh(addr.String(), id)
+		} else {
+			ok = GlobalHub.unsubscribe(addr.String(), id)
+		}
+		if !ok {
+			return errorLine("unknown subscription, SUB again")
+		}
+		if cmd == CmdPing {
+			return ReplyPong
+		}
+		return ReplyOK
+
+	default:
+		return errorLine("unknown command: use SUB, PING, ACK or UNSUB")
+	}
+}
+
+// subscribe handles SUB
+func subscribe(addr *net.UDPAddr, args []string, sessions auth.Sessions) string {
+	if len(args) < 2 {
+		return errorLine("usage: SUB <access token> [RELIABLE] <topic> [topic...]")
+	}
+	claims, err := auth.Authenticate(context.Background(), sessions, args[0])
+	if errors.Is(err, auth.ErrInvalidToken) {
+		return errorLine("invalid or expired token")
+	} else if errors.Is(err, auth.ErrSessionRevoked) {
+		return errorLine("session has been revoked")
+	} else if err != nil {
+		log.Printf("Failed to check session: %v", err)
+		return errorLine("failed to check session")
+	}
+	reliable := strings.EqualFold(args[1], OptionReliable)
+	if reliable {
+		args = args[1:]
+		if len(args) < 2 {
+			return errorLine("usage: SUB <access token> [RELIABLE] <topic> [topic...]")
+		}
+	}
+	if len(args)-1 > MaxTopics {
+		return errorLine("too many topics, at most %d", MaxTopics)
+	}
+	if !GlobalHub.hasRoom(addr.String()) {
+		GlobalHub.counters.serverFull.Add(1)
+		return errorLine("server full")
+	}
+
+	topics := make(map[string]bool, len(args)-1)
+	for _, arg := range args[1:] {
+		topic, err := shared.ParseTopic(arg, claims.UserID)
+		if err != nil {
+			return errorLine("%v", err)
+		}
+		topics[topic] = true
+	}
+
+	now := time.Now()
+	expires := now.Add(SubscriptionLifetime)
+	lifetime := fmt.Sprintf(" %d", int(SubscriptionLifetime/time.Second))
+	if id, ok := GlobalHub.renew(addr.String(), claims.UserID, reliable, topics, expires); ok {
+		return ReplyOK + " " + signSubscription(id, addr.String()) + lifetime
+	}
+
+	client := &ClientAddr{
+		ID:          auth.GenerateID("udp"),
+		UserID:      claims.UserID,
+		Username:    claims.Username,
+		Addr:        addr,
+		Topics:      topics,
+		LastSeen:    now,
+		ConnectedAt: now,
+		Expires:     expires,
+		Reliable:    reliable,
+	}
+	GlobalHub.Register <- client
+	return ReplyOK + " " + signSubscription(client.ID, addr.String()) + lifetime
+}
+
+// errorLine formats an ERR reply
+func errorLine(format string, args ...interface{}) string {
+	return ReplyErr + " " + fmt.Sprintf(format, args...)
+}
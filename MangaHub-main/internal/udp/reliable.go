@@ -0,0 +1,134 @@
+package udp
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+// Reliable delivery, chosen with SUB <token> RELIABLE <topics...>. Each
+// update then arrives as
+//
+//	DATA <seq> <json>
+//
+// where seq counts the subscriber's updates from 1. The client answers
+//
+//	ACK <subscription id> <seq> [seq...]
+//
+// for every DATA it receives, including duplicates, and gets no reply.
+// Updates not acknowledged within RetransmitTimeout are sent again, waiting
+// twice as long each time up to MaxRetransmitTimeout, until MaxAttempts
+// sends. A retransmitted update may arrive twice, so clients drop seqs they
+// have seen, and after later ones, so clients hold those back until the
+// seqs before them arrived. An update still unacknowledged after MaxAttempts
+// ends the subscription with "BYE gap <seq>", since the client cannot get it
+// any more; it should SUB again and catch up some other way. A subscriber
+// with more than MaxUnacked updates outstanding is dropped as a slow
+// consumer.
+const (
+	CmdAck    = "ACK"
+	ReplyData = "DATA"
+
+	RetransmitTimeout    = 500 * time.Millisecond
+	MaxRetransmitTimeout = 8 * time.Second
+	MaxAttempts          = 8
+	MaxUnacked           = 256
+)
+
+// OptionReliable is the SUB option that turns on reliable delivery
+const OptionReliable = "RELIABLE"
+
+// retransmitTick is how often the hub looks for updates to send again
+const retransmitTick = 100 * time.Millisecond
+
+// pending is an update sent to a reliable subscriber and not yet acknowledged
+type pending struct {
+	data     []byte
+	frag     uint32 // Message id of its fragments, kept for retransmits
+	attempts int
+	next     time.Time // When to send it again
+}
+
+// sendReliable numbers an update for a reliable subscriber, queues it and
+// keeps it until it is acknowledged. It reports false if the subscriber
+// has too many updates outstanding. The hub's lock must be held.
+func (c *ClientAddr) sendReliable(data []byte, now time.Time) bool {
+	if len(c.pending) >= MaxUnacked {
+		return false
+	}
+	c.lastSeq++
+	p := &pending{
+		data:     append([]byte(fmt.Sprintf("%s %d ", ReplyData, c.lastSeq)), data...),
+		attempts: 1,
+		next:     now.Add(RetransmitTimeout),
+	}
+	p.frag = c.fragID(p.data)
+	if c.pending == nil {
+		c.pending = map[uint64]*pending{}
+	}
+	c.pending[c.lastSeq] = p
+	c.queue(p.data, p.frag)
+	return true
+}
+
+// retransmit queues again the updates whose acknowledgement is overdue. It
+// returns the seq of an update sent MaxAttempts times, or 0. The hub's lock
+// must be held.
+func (c *ClientAddr) retransmit(now time.Time) uint64 {
+	for seq, p := range c.pending {
+		if now.Before(p.next) {
+			continue
+		}
+		if p.attempts >= MaxAttempts {
+			return seq
+		}
+		c.queue(p.data, p.frag)
+		backoff := RetransmitTimeout << p.attempts
+		if backoff > MaxRetransmitTimeout {
+			backoff = MaxRetransmitTimeout
+		}
+		p.attempts++
+		p.next = now.Add(backoff)
+	}
+	return 0
+}
+
+// ack handles ACK for the subscription id of the address key. It reports
+// whether that subscription exists.
+func (h *Hub) ack(key, id string, seqs []string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	client, ok := h.clients[key]
+	if !ok || client.ID != id {
+		return false
+	}
+	client.LastSeen = time.Now()
+	for _, s := range seqs {
+		if seq, err := strconv.ParseUint(s, 10, 64); err == nil {
+			delete(client.pending, seq)
+		}
+	}
+	return true
+}
+
+// retransmit runs retransmit for every reliable subscriber and sends what
+// it queued, together with any updates waiting for CoalesceWindow. A
+// subscriber that did not acknowledge an update in MaxAttempts is dropped.
+func (h *Hub) retransmit() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	for key, client := range h.clients {
+		if !client.Reliable {
+			continue
+		}
+		if seq := client.retransmit(now); seq != 0 {
+			delete(h.clients, key)
+			udpConn.WriteToUDP([]byte(fmt.Sprintf("%s gap %d\n", ReplyBye, seq)), client.Addr)
+			log.Printf("UDP UPDATE %d TO %s UNACKNOWLEDGED after %d attempts, subscriber dropped (Remaining: %d)", seq, key, MaxAttempts, len(h.clients))
+			continue
+		}
+		client.flush()
+	}
+}
@@ -0,0 +1,61 @@
+package udp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"sort"
+	"strings"
+
+	"mangahub/internal/shared"
+)
+
+// subscriptionKey signs subscription IDs. It changes with every start of
+// the server, which forgets its subscriptions anyway.
+var subscriptionKey = newSubscriptionKey()
+
+func newSubscriptionKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic("udp: cannot generate subscription key: " + err.Error())
+	}
+	return key
+}
+
+// signSubscription returns the subscription ID given to the client: the
+// subscriber's ID and a MAC binding it to addr
+func signSubscription(id, addr string) string {
+	return id + "." + subscriptionMAC(id, addr)
+}
+
+// verifySubscription checks a subscription ID sent from addr and returns
+// the subscriber's ID
+func verifySubscription(signed, addr string) (string, bool) {
+	id, mac, ok := strings.Cut(signed, ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(subscriptionMAC(id, addr))) {
+		return "", false
+	}
+	return id, true
+}
+
+func subscriptionMAC(id, addr string) string {
+	mac := hmac.New(sha256.New, subscriptionKey)
+	mac.Write([]byte(id + " " + addr))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// wants reports whether the subscriber follows the update's user or manga
+func (c *ClientAddr) wants(update shared.ProgressUpdate) bool {
+	return c.Topics[shared.TopicUser+update.UserID] || c.Topics[shared.TopicManga+update.MangaID]
+}
+
+// subscriptions returns the subscriber's topics in sorted order
+func (c *ClientAddr) subscriptions() []string {
+	topics := make([]string, 0, len(c.Topics))
+	for t := range c.Topics {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+	return topics
+}
@@ -3,33 +3,51 @@ package udp
 import (
 	"log"
 	"net"
-	"strings"
 	"time"
+
+	"mangahub/internal/auth"
 )
 
 var udpConn *net.UDPConn // Shared UDP connection used for sending and receiving packets
 
-func StartUDPListener(addr string) {
+// StartUDPListener answers the commands of UDP clients on addr in the
+// background. SUB tokens are checked against sessions.
+func StartUDPListener(addr string, sessions auth.Sessions) {
+	if _, err := Listen(addr, sessions); err != nil {
+		log.Fatal("UDP listen error:", err)
+	}
+	log.Printf("UDP notification server running on %s", addr)
+}
+
+// Listen is StartUDPListener returning an error rather than exiting. It
+// returns the address listened on, which tells the port chosen for ":0".
+// It may be called once.
+func Listen(addr string, sessions auth.Sessions) (net.Addr, error) {
 	// Resolve string address into UDP address structure
 	udpAddr, err := net.ResolveUDPAddr("udp", addr)
 	if err != nil {
-		log.Fatal("UDP resolve error:", err)
+		return nil, err
 	}
 
     // Open UDP socket and start listening
 	udpConn, err = net.ListenUDP("udp", udpAddr)
 	if err != nil {
-		log.Fatal("UDP listen error:", err)
+		return nil, err
 	}
-	log.Printf("UDP notification server running on %s", addr)
 
-	go readPump() // udp receive
-	go writePump() // udp send
+	go readPump(sessions) // udp receive; GlobalHub.Run sends
+	return udpConn.LocalAddr(), nil
 }
 
-// reads incoming UDP packets
-func readPump() {
-	buffer := make([]byte, 4096)
+// MaxCommand is the longest datagram a client may send. Longer ones are
+// answered by ERR rather than cut off.
+const MaxCommand = 8192
+
+// reads incoming UDP packets and answers the commands in them, see CmdSub
+// and RatePerIP
+func readPump(sessions auth.Sessions) {
+	buffer := make([]byte, 64<<10) // Holds any UDP datagram, so none is truncated
+	var limits limiter
 	for {
 		// Read data from any UDP client
 		n, clientAddr, err := udpConn.ReadFromUDP(buffer)
@@ -37,36 +55,29 @@ func readPump() {
 			log.Println("UDP read error:", err)
 			continue
 		}
+		GlobalHub.counters.received.Add(1)
 
-		// Convert received bytes into trimmed string
-		message := strings.TrimSpace(string(buffer[:n]))
-		addrStr := clientAddr.String()
-
-		if message == "PING" {
-			log.Printf("UDP PING RECEIVED from %s → Sending PONG", addrStr)
-
-			// Register or refresh UDP client in hub
-			client := &ClientAddr{
-				Addr:     clientAddr,
-				LastSeen: time.Now(),
-			}
-			GlobalHub.Register <- client
-
-			// Reply to client to confirm subscription
-			udpConn.WriteToUDP([]byte("PONG\n"), clientAddr)
-			log.Printf("UDP CLIENT SUBSCRIBED: %s — Total subscribers: %d", addrStr, GlobalHub.GetClientCount())
+		now := time.Now()
+		if !limits.allow(clientAddr.AddrPort().Addr().Unmap(), now) {
+			GlobalHub.counters.rateLimited.Add(1)
+			continue
 		}
-	}
-}
 
-// Sends broadcast messages to all registered UDP clients
-func writePump() {
-	for message := range GlobalHub.broadcast {
-		GlobalHub.mu.RLock()
-		for _, client := range GlobalHub.clients {
-			udpConn.WriteToUDP(message, client.Addr)
-			client.LastSeen = time.Now()
+		var reply string
+		if n > MaxCommand {
+			GlobalHub.counters.oversized.Add(1)
+			reply = errorLine("command longer than %d bytes", MaxCommand)
+		} else {
+			reply = handleCommand(clientAddr, string(buffer[:n]), sessions)
+		}
+		if reply == "" {
+			continue
+		}
+		// Only addresses proven by a cookie may get more than they sent
+		if len(reply)+1 > n && !GlobalHub.subscribed(clientAddr.String()) {
+			GlobalHub.counters.repliesWithheld.Add(1)
+			continue
 		}
-		GlobalHub.mu.RUnlock()
+		udpConn.WriteToUDP([]byte(reply+"\n"), clientAddr)
 	}
 }
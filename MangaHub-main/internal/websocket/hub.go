@@ -2,9 +2,14 @@ package websocket
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"mangahub/internal/shared"
+
 	"github.com/gorilla/websocket" 
 )
 
@@ -19,11 +24,18 @@ type Message struct {
 
 // Client represents a single WebSocket connection
 type Client struct {
-	Hub      *Hub              // Reference to the central hub
-	Conn     *websocket.Conn   // WebSocket connection
-	Send     chan []byte       // Outgoing message channel
-	Username string            // Client username
-	Room     string            // Room the client joined
+	ID          string          // Identifies the connection in the admin endpoints
+	Hub         *Hub            // Reference to the central hub
+	Conn        *websocket.Conn // WebSocket connection
+	Send        chan []byte     // Outgoing message channel
+	UserID      string          // User the access token was issued to
+	Username    string          // Client username
+	Room        string          // Room the client joined
+	ConnectedAt time.Time
+
+	topics map[string]bool // Progress topics, fixed when connecting
+
+	lastActive atomic.Int64 // Unix nanoseconds of the last message or pong received
 }
 
 // Hub manages all WebSocket clients and rooms
@@ -33,6 +45,7 @@ type Hub struct {
 	Broadcast  chan []byte                   // Messages to broadcast
 	Register   chan *Client                  // New client registration
 	Unregister chan *Client                  // Client disconnection
+	progress   chan shared.ProgressUpdate    // Progress updates to deliver
 	mu         sync.RWMutex                  // Protects clients & rooms
 
 	messageHistory []Message                 // Last 50 messages (all rooms)
@@ -47,6 +60,7 @@ func NewHub() *Hub {
 		Broadcast:  make(chan []byte, 256),           // Buffered broadcast channel
 		Register:   make(chan *Client),               // Register channel
 		Unregister: make(chan *Client),               // Unregister channel
+		progress:   make(chan shared.ProgressUpdate, 256),
 		messageHistory: make([]Message, 0, 50),       // Pre-allocate history
 	}
 }
@@ -73,7 +87,8 @@ func (h *Hub) Run() {
 		// Handle client disconnection
 		case client := <-h.Unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
+			_, ok := h.clients[client]
+			if ok {
 				delete(h.clients, client)
 				close(client.Send)
 
@@ -86,6 +101,9 @@ func (h *Hub) Run() {
 				}
 			}
 			h.mu.Unlock()
+			if !ok {
+				continue // Already gone, e.g. disconnected by an admin
+			}
 
 			// Broadcast system "leave" message
 			leaveMsg := Message{
@@ -119,25 +137,67 @@ func (h *Hub) Run() {
 			}
 
 			// Send message to clients in the same room
-			h.mu.RLock()
+			h.mu.Lock()
 			if roomClients, exists := h.rooms[msg.Room]; exists {
 				for client := range roomClients {
-					select {
-					case client.Send <- data:
-						// Message sent successfully
-					default:
-						// Client send buffer full → disconnect
-						close(client.Send)
-						delete(h.clients, client)
-						delete(roomClients, client)
-					}
+					h.send(client, data)
+				}
+			}
+			h.mu.Unlock()
+
+		// Deliver progress updates to the clients subscribed to them
+		case update := <-h.progress:
+			text := fmt.Sprintf("%s reached chapter %d of %s", update.Username, update.CurrentChapter, update.MangaTitle)
+			h.mu.Lock()
+			for client := range h.clients {
+				if !client.wants(update) {
+					continue
 				}
+				data, _ := json.Marshal(Message{
+					Type:     "progress",
+					Username: update.Username,
+					Text:     text,
+					Time:     time.Now().Format("15:04"),
+					Room:     client.Room,
+				})
+				h.send(client, data)
+			}
+			h.mu.Unlock()
+		}
+	}
+}
+
+// send queues data for a client, disconnecting it if its send buffer is
+// full. h.mu must be held for writing.
+func (h *Hub) send(client *Client, data []byte) {
+	select {
+	case client.Send <- data:
+		// Message sent successfully
+	default:
+		// Client send buffer full → disconnect
+		close(client.Send)
+		delete(h.clients, client)
+		if roomClients, exists := h.rooms[client.Room]; exists {
+			delete(roomClients, client)
+			if len(roomClients) == 0 {
+				delete(h.rooms, client.Room)
 			}
-			h.mu.RUnlock()
 		}
 	}
 }
 
+// wants reports whether the client subscribed to the update's user or manga
+func (c *Client) wants(update shared.ProgressUpdate) bool {
+	return c.topics[shared.TopicUser+update.UserID] || c.topics[shared.TopicManga+update.MangaID]
+}
+
+// BroadcastProgress announces a reading progress update to the clients
+// subscribed to its user or manga, in their rooms. Progress messages are
+// not kept in the room history.
+func (h *Hub) BroadcastProgress(update shared.ProgressUpdate) {
+	h.progress <- update
+}
+
 // Returns message history for a specific room
 func (h *Hub) GetMessageHistory(room string) []Message {
 	h.historyMu.RLock()
@@ -157,4 +217,58 @@ func (h *Hub) GetClientCount() int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 	return len(h.clients)
-}
\ No newline at end of file
+}
+
+// touch records traffic from the client
+func (c *Client) touch() {
+	c.lastActive.Store(time.Now().UnixNano())
+}
+
+// Clients lists the connected clients, oldest first. Each is subscribed to
+// its room and its progress topics.
+func (h *Hub) Clients() []shared.ClientInfo {
+	h.mu.RLock()
+	clients := make([]shared.ClientInfo, 0, len(h.clients))
+	for client := range h.clients {
+		info := shared.ClientInfo{
+			ID:            client.ID,
+			Username:      client.Username,
+			RemoteAddr:    client.Conn.RemoteAddr().String(),
+			ConnectedAt:   client.ConnectedAt,
+			QueueDepth:    len(client.Send),
+			Subscriptions: []string{"room:" + client.Room},
+		}
+		for topic := range client.topics {
+			info.Subscriptions = append(info.Subscriptions, topic)
+		}
+		sort.Strings(info.Subscriptions[1:])
+		if ns := client.lastActive.Load(); ns != 0 {
+			info.LastActivity = time.Unix(0, ns)
+		}
+		clients = append(clients, info)
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(clients, func(i, j int) bool { return clients[i].ConnectedAt.Before(clients[j].ConnectedAt) })
+	return clients
+}
+
+// Disconnect closes the connection with the given ID and announces that the
+// user left. It reports whether the client was found.
+func (h *Hub) Disconnect(id string) bool {
+	var target *Client
+	h.mu.RLock()
+	for client := range h.clients {
+		if client.ID == id {
+			target = client
+			break
+		}
+	}
+	h.mu.RUnlock()
+
+	if target == nil {
+		return false
+	}
+	h.Unregister <- target
+	return true
+}
@@ -0,0 +1,179 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"mangahub/internal/auth"
+	"mangahub/internal/shared"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket upgrader configuration
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// NewRouter serves the chat page, the /ws endpoint and /stats for hub.
+// hub.Run must be running. Access tokens on /ws are checked against sessions.
+func NewRouter(hub *Hub, sessions auth.Sessions) *gin.Engine {
+	router := gin.Default()
+
+	// Simple CORS middleware (allows browser WebSocket connections)
+	router.Use(func(c *gin.Context) {
+		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		// Handle preflight requests
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(204)
+			return
+		}
+		c.Next()
+	})
+
+	// Serve chat UI HTML file
+	router.GET("/", func(c *gin.Context) {
+		c.File(filepath.Join("web", "broadcast_chatroom.html"))
+	})
+
+	// WebSocket upgrade endpoint
+	router.GET("/ws", hub.handleWebSocket(sessions))
+
+	// Server statistics endpoint
+	router.GET("/stats", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"online_users": hub.GetClientCount(),
+			"timestamp":    time.Now().Format("15:04:05"),
+		})
+	})
+
+	return router
+}
+
+// MaxTopics is how many progress topics one client may subscribe to
+const MaxTopics = 64
+
+// Handles incoming WebSocket connection requests. The client authenticates
+// with its access token, in the Authorization header or, as browsers cannot
+// set it, the token query parameter; its username comes from the token. It
+// receives progress updates only for the topics it lists in the topics
+// query parameter, e.g. topics=self,manga:one-piece.
+func (h *Hub) handleWebSocket(sessions auth.Sessions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" {
+			token = c.Query("token")
+		}
+		if token == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "token required"})
+			return
+		}
+		claims, err := auth.Authenticate(c.Request.Context(), sessions, token)
+		if errors.Is(err, auth.ErrInvalidToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			return
+		} else if errors.Is(err, auth.ErrSessionRevoked) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			return
+		} else if err != nil {
+			log.Printf("Failed to check session: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check session"})
+			return
+		}
+
+		topics, err := parseTopics(c.QueryArray("topics"), claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		room := c.Query("room")
+		// Default room if not provided
+		if room == "" {
+			room = "general"
+		}
+		h.serve(c, claims, room, topics)
+	}
+}
+
+// parseTopics reads the comma-separated topics a client subscribes to
+func parseTopics(args []string, userID string) (map[string]bool, error) {
+	topics := make(map[string]bool)
+	for _, arg := range args {
+		for _, t := range strings.Split(arg, ",") {
+			if t = strings.TrimSpace(t); t == "" {
+				continue
+			}
+			topic, err := shared.ParseTopic(t, userID)
+			if err != nil {
+				return nil, err
+			}
+			topics[topic] = true
+		}
+	}
+	if len(topics) > MaxTopics {
+		return nil, fmt.Errorf("too many topics (max %d)", MaxTopics)
+	}
+	return topics, nil
+}
+
+// serve upgrades an authenticated request and runs the client
+func (h *Hub) serve(c *gin.Context, claims *auth.Claims, room string, topics map[string]bool) {
+	username := claims.Username
+
+	// Upgrade HTTP connection to WebSocket
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Upgrade error: %v", err)
+		return
+	}
+
+	// Create a new WebSocket client
+	client := &Client{
+		ID:          auth.GenerateID("ws"),
+		Hub:         h,
+		Conn:        conn,
+		Send:        make(chan []byte, 256),
+		UserID:      claims.UserID,
+		Username:    username,
+		Room:        room,
+		ConnectedAt: time.Now(),
+		topics:      topics,
+	}
+	client.touch() // The handshake counts as activity
+
+	h.Register <- client
+
+	// Send recent message history for the room
+	history := h.GetMessageHistory(room)
+	for _, msg := range history {
+		data, _ := json.Marshal(msg)
+		client.Send <- data
+	}
+
+	// Broadcast system "join" message
+	joinMsg := Message{
+		Type: "system",
+		Text: fmt.Sprintf("%s joined the room", username),
+		Time: time.Now().Format("15:04"),
+		Room: room,
+	}
+	data, _ := json.Marshal(joinMsg)
+	h.Broadcast <- data
+
+	go client.WritePump()
+	go client.ReadPump()
+}
@@ -25,6 +25,7 @@ func (c *Client) ReadPump() {
 	c.Conn.SetReadDeadline(time.Now().Add(pongWait)) // Set initial read deadline (connection timeout)
 	// Reset read deadline every time a pong is received
 	c.Conn.SetPongHandler(func(string) error {
+		c.touch()
 		c.Conn.SetReadDeadline(time.Now().Add(pongWait))
 		return nil
 	})
@@ -34,6 +35,7 @@ func (c *Client) ReadPump() {
 		if err != nil {
 			break
 		}
+		c.touch()
 
 		var msg Message
 		if err := json.Unmarshal(data, &msg); err != nil {
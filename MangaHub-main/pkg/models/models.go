@@ -1,30 +1,40 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
-    "strings"
 )
 
-
 // User represents a user account
 type User struct {
 	ID           string    `json:"id" db:"id"`
 	Username     string    `json:"username" db:"username"`
 	Email        string    `json:"email" db:"email"`
 	PasswordHash string    `json:"-" db:"password_hash"`
+	Role         string    `json:"role" db:"role"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 }
 
 // Manga represents a manga series
 type Manga struct {
-	ID            string   `json:"id" db:"id"`
-	Title         string   `json:"title" db:"title"`
-	Author        string   `json:"author" db:"author"`
-	Genres        []string `json:"genres"`
-	GenresString  string   `json:"-" db:"genres"` // For DB storage
-	Status        string   `json:"status" db:"status"`
-	TotalChapters int      `json:"total_chapters" db:"total_chapters"`
-	Description   string   `json:"description" db:"description"`
+	ID            string       `json:"id" db:"id"`
+	Title         string       `json:"title" db:"title"`
+	Author        string       `json:"author" db:"author"`
+	Genres        []string     `json:"genres"`
+	GenresString  string       `json:"-"` // Comma-joined genres as read from the database
+	Status        string       `json:"status" db:"status"`
+	TotalChapters int          `json:"total_chapters" db:"total_chapters"`
+	Description   string       `json:"description" db:"description"`
+	Match         *SearchMatch `json:"match,omitempty"` // Set only on full-text search results
+}
+
+// SearchMatch describes why a manga matched a full-text search.
+// Matched terms in Title and Snippet are wrapped in <mark></mark>.
+type SearchMatch struct {
+	Score   float64 `json:"score"`   // BM25 relevance, higher is better
+	Title   string  `json:"title"`   // Title with matched terms highlighted
+	Snippet string  `json:"snippet"` // Best matching excerpt across title, author and description
 }
 
 // PostScan processes the Manga struct after scanning from the database.
@@ -36,12 +46,92 @@ func (m *Manga) PostScan() {
 	}
 }
 
-// PreSave prepares the Manga struct before saving to the database.
-// To save to DB, convert []string to string
-func (m *Manga) PreSave() {
-	if len(m.Genres) > 0 {
-		m.GenresString = strings.Join(m.Genres, ",")
+// NormalizeGenres trims genre names and drops empty and duplicate entries
+// (ignoring case), keeping the first spelling and the original order.
+func NormalizeGenres(genres []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, g := range genres {
+		g = strings.TrimSpace(g)
+		key := strings.ToLower(g)
+		if g == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, g)
+	}
+	return out
+}
+
+// MangaPatch is a partial update to a manga. Nil fields are left unchanged.
+type MangaPatch struct {
+	Title         *string   `json:"title" binding:"omitempty,min=1"`
+	Author        *string   `json:"author"`
+	Genres        *[]string `json:"genres"`
+	Status        *string   `json:"status" binding:"omitempty,oneof=ongoing completed hiatus"`
+	TotalChapters *int      `json:"total_chapters" binding:"omitempty,gte=0"`
+	Description   *string   `json:"description"`
+}
+
+// ValidMangaStatus reports whether status is one the catalog uses
+func ValidMangaStatus(status string) bool {
+	switch status {
+	case "ongoing", "completed", "hiatus":
+		return true
 	}
+	return false
+}
+
+// Empty reports whether the patch changes nothing
+func (p MangaPatch) Empty() bool {
+	return p.Title == nil && p.Author == nil && p.Genres == nil &&
+		p.Status == nil && p.TotalChapters == nil && p.Description == nil
+}
+
+// Genre is a catalog genre with the number of manga tagged with it
+type Genre struct {
+	ID         int    `json:"id" db:"id"`
+	Name       string `json:"name" db:"name"`
+	MangaCount int    `json:"manga_count"`
+}
+
+// MangaQuery describes a filtered, sorted and paginated catalog listing.
+// Zero values mean "no filter". Genres must all be present on a manga,
+// while Statuses matches any of the listed values. Search runs a ranked
+// full-text search over title, author and description.
+type MangaQuery struct {
+	Search      string
+	Title       string
+	Author      string
+	Genres      []string
+	Statuses    []string
+	MinChapters int
+	MaxChapters int    // 0 means no upper bound
+	Sort        string // title, total_chapters, popularity or relevance
+	Desc        bool
+	Limit       int
+	Cursor      string // opaque cursor returned as MangaPage.NextCursor
+}
+
+// FacetCount is the number of manga in a result set sharing one facet value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// MangaFacets holds per-genre and per-status counts for a filtered listing
+type MangaFacets struct {
+	Genres   []FacetCount `json:"genres"`
+	Statuses []FacetCount `json:"statuses"`
+}
+
+// MangaPage is one page of a catalog listing
+type MangaPage struct {
+	Manga      []Manga     `json:"manga"`
+	Count      int         `json:"count"`                 // Items on this page
+	Total      int         `json:"total"`                 // Items matching the filters across all pages
+	NextCursor string      `json:"next_cursor,omitempty"` // Empty on the last page
+	Facets     MangaFacets `json:"facets"`
 }
 
 // UserProgress tracks reading progress
@@ -53,6 +143,58 @@ type UserProgress struct {
 	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// LibraryEntry is a manga in a user's library with their progress
+type LibraryEntry struct {
+	MangaID        string    `json:"manga_id"`
+	Title          string    `json:"title"`
+	CurrentChapter int       `json:"current_chapter"`
+	Status         string    `json:"status"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Deleted        bool      `json:"deleted,omitempty"` // The manga was removed from the catalog
+}
+
+// ProgressUpdate is the message the real-time servers send when reading
+// progress changes
+type ProgressUpdate struct {
+	UserID         string `json:"user_id"`
+	Username       string `json:"username"`
+	MangaID        string `json:"manga_id"`
+	MangaTitle     string `json:"manga_title"`
+	CurrentChapter int    `json:"current_chapter"`
+	Status         string `json:"status"`
+	Timestamp      int64  `json:"timestamp"` // Unix timestamp
+	Type           string `json:"type"`
+	EventID        string `json:"event_id,omitempty"` // Same for every redelivery of one update
+	Seq            uint64 `json:"seq,omitempty"`      // Position in the TCP stream, for RESUME
+}
+
+// Audit entities and actions
+const (
+	AuditEntityManga = "manga"
+
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+)
+
+// AuditEntry records who changed a catalog entry, how and when
+type AuditEntry struct {
+	ID        int64                  `json:"id"`
+	Entity    string                 `json:"entity"`
+	EntityID  string                 `json:"entity_id"`
+	Action    string                 `json:"action"`
+	ActorID   string                 `json:"actor_id"`
+	Changes   map[string]FieldChange `json:"changes,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// FieldChange is the value of one field before and after a change
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
 // LoginRequest for user login
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
@@ -66,9 +208,46 @@ type RegisterRequest struct {
 	Password string `json:"password" binding:"required,min=8"`
 }
 
-// LoginResponse after successful login
+// LoginResponse after successful login or token refresh.
+// Token is a short-lived access token; RefreshToken obtains the next pair
+// from /auth/refresh and is invalidated by that call.
 type LoginResponse struct {
-	Token    string `json:"token"`
-	Username string `json:"username"`
-	UserID   string `json:"user_id"`
-}
\ No newline at end of file
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // Seconds until Token expires
+	Username     string `json:"username"`
+	UserID       string `json:"user_id"`
+	Role         string `json:"role"`
+}
+
+// RefreshRequest exchanges a refresh token for a new token pair
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Session is one login of a user, kept alive by refreshing its tokens
+type Session struct {
+	ID         string    `json:"id" db:"id"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	UserAgent  string    `json:"user_agent" db:"user_agent"`
+	IPAddress  string    `json:"ip_address" db:"ip_address"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at" db:"last_used_at"` // Last login or refresh
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	Current    bool      `json:"current"` // The session of the token making the request
+}
+
+// OutboxEvent is a message written in the same transaction as the change it
+// describes and kept until it has been delivered. ID doubles as the
+// deduplication ID receivers use to drop repeated deliveries.
+type OutboxEvent struct {
+	ID            string          `json:"id"`
+	Topic         string          `json:"topic"`
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+	Attempts      int             `json:"attempts"`
+	DeliveredTo   []string        `json:"delivered_to"` // Subscribers that already received it
+	LastError     string          `json:"last_error,omitempty"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	FailedAt      *time.Time      `json:"failed_at,omitempty"` // When it was dead-lettered
+}
@@ -0,0 +1,383 @@
+// Package progressclient connects Go programs to the TCP progress stream of
+// cmd/tcp-server. It uses the protobuf framing of the protocol (see
+// internal/tcp), so updates arrive as typed proto.ProgressUpdate messages
+// instead of JSON lines.
+//
+//	c, err := progressclient.Dial(ctx, "localhost:9090", token, nil)
+//	if err != nil { ... }
+//	defer c.Close()
+//	c.Subscribe(ctx, progressclient.TopicSelf)
+//	for u := range c.Updates() {
+//		fmt.Println(u.GetMangaTitle(), u.GetCurrentChapter())
+//	}
+//	// c.Err() tells why the stream ended
+package progressclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "mangahub/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Protocol limits, matching the server
+const (
+	// MaxFrameSize bounds one frame
+	MaxFrameSize = 64 << 10
+	// HandshakeTimeout bounds Dial and NewClient
+	HandshakeTimeout = 10 * time.Second
+	// IdleTimeout ends a connection on which nothing, not even the server's
+	// PING sent every 30 seconds, arrived for this long
+	IdleTimeout = 90 * time.Second
+	// UpdateBuffer is the capacity of the Updates channel. A program that
+	// stops reading it is eventually disconnected as a slow consumer.
+	UpdateBuffer = 256
+)
+
+// Topics, see Client.Subscribe
+const TopicSelf = "self"
+
+// UserTopic returns the topic of one user's updates
+func UserTopic(userID string) string { return "user:" + userID }
+
+// MangaTopic returns the topic of the updates on one manga
+func MangaTopic(mangaID string) string { return "manga:" + mangaID }
+
+var (
+	// ErrGap is returned by Resume when the updates after the given sequence
+	// number are no longer retained. Reload the state over REST instead.
+	ErrGap = errors.New("gap too large, resync")
+	// ErrClosed is the Err of a client ended by Close
+	ErrClosed = errors.New("progressclient: connection closed")
+)
+
+// ServerError is an ERR reply, or the reason of the server's BYE
+type ServerError struct {
+	Reason string
+}
+
+func (e *ServerError) Error() string {
+	return "progress server: " + e.Reason
+}
+
+// Client is an authenticated connection. Its methods may be called from
+// several goroutines; commands are sent one at a time.
+type Client struct {
+	// Username is the account the token belongs to
+	Username string
+	// Seq is the sequence number of the last update sent before the client
+	// connected, the starting point for Resume
+	Seq uint64
+
+	conn    net.Conn
+	reader  *bufio.Reader
+	updates chan *pb.ProgressUpdate
+	reply   chan string // Reply to the command in flight
+
+	command sync.Mutex // Held while a command waits for its reply
+	write   sync.Mutex // Keeps frames whole
+
+	once sync.Once
+	done chan struct{}
+	err  error // Why the connection ended, set before done is closed
+}
+
+// Dial connects to addr, using TLS if config is not nil, and authenticates
+// with an access token from POST /auth/login. Without a ServerName, config
+// verifies the host of addr.
+func Dial(ctx context.Context, addr, token string, config *tls.Config) (*Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if config != nil {
+		if config.ServerName == "" {
+			config = config.Clone()
+			config.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		conn = tls.Client(conn, config)
+	}
+	c, err := NewClient(conn, token)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewClient runs the handshake on an open connection and starts reading
+// updates. The connection is closed with the client.
+func NewClient(conn net.Conn, token string) (*Client, error) {
+	c := &Client{
+		conn:    conn,
+		reader:  bufio.NewReaderSize(conn, MaxFrameSize),
+		updates: make(chan *pb.ProgressUpdate, UpdateBuffer),
+		reply:   make(chan string, 1),
+		done:    make(chan struct{}),
+	}
+
+	conn.SetDeadline(time.Now().Add(HandshakeTimeout))
+	if _, err := fmt.Fprintf(conn, "AUTH %s PROTOBUF\n", token); err != nil {
+		return nil, err
+	}
+	line, err := c.readHandshake()
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	detail, err := parseReply(line)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(detail)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("progressclient: unexpected handshake reply %q", line)
+	}
+	if c.Seq, err = strconv.ParseUint(fields[1], 10, 64); err != nil {
+		return nil, fmt.Errorf("progressclient: unexpected handshake reply %q", line)
+	}
+	c.Username = fields[0]
+
+	go c.readLoop()
+	return c, nil
+}
+
+// readHandshake skips the text banner and returns the reply to AUTH, which
+// is a text line if it is an error and the first frame otherwise
+func (c *Client) readHandshake() (string, error) {
+	for {
+		// Frames start with a big-endian size below 16 MiB, text does not
+		b, err := c.reader.Peek(1)
+		if err != nil {
+			return "", err
+		}
+		if b[0] == 0 {
+			frame, err := readFrame(c.reader)
+			if err != nil {
+				return "", err
+			}
+			return frame.GetLine(), nil
+		}
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if line = strings.TrimRight(line, "\r\n"); strings.HasPrefix(line, "ERR") {
+			return line, nil
+		}
+	}
+}
+
+// Updates returns the channel of the updates for the client's topics. It is
+// closed when the connection ends; Err then tells why.
+func (c *Client) Updates() <-chan *pb.ProgressUpdate {
+	return c.updates
+}
+
+// Err returns why the connection ended, or nil while it is open
+func (c *Client) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+// Close ends the connection
+func (c *Client) Close() error {
+	c.fail(ErrClosed)
+	return nil
+}
+
+// Subscribe adds a topic: TopicSelf, UserTopic(id) or MangaTopic(id)
+func (c *Client) Subscribe(ctx context.Context, topic string) error {
+	_, err := c.do(ctx, "SUBSCRIBE "+topic)
+	return err
+}
+
+// Unsubscribe drops a topic, or every topic if topic is empty
+func (c *Client) Unsubscribe(ctx context.Context, topic string) error {
+	_, err := c.do(ctx, strings.TrimSpace("UNSUBSCRIBE "+topic))
+	return err
+}
+
+// Subscriptions returns the client's topics, in sorted order
+func (c *Client) Subscriptions(ctx context.Context) ([]string, error) {
+	detail, err := c.do(ctx, "LIST")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(detail), nil
+}
+
+// Resume asks for the updates after seq that match the client's topics,
+// usually the highest Seq received before reconnecting, or Client.Seq of
+// the previous connection. They are queued on Updates ahead of the reply
+// with their number, so read Updates from another goroutine if more than
+// UpdateBuffer may be missed. If they are no longer retained the error is
+// ErrGap.
+func (c *Client) Resume(ctx context.Context, seq uint64) (int, error) {
+	detail, err := c.do(ctx, "RESUME "+strconv.FormatUint(seq, 10))
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(detail)
+	if err != nil {
+		return 0, fmt.Errorf("progressclient: unexpected RESUME reply %q", detail)
+	}
+	return n, nil
+}
+
+// Update saves the user's progress on a manga, like PUT /users/progress.
+// status may be empty to keep the current one. It returns the event ID the
+// resulting broadcast carries.
+func (c *Client) Update(ctx context.Context, mangaID string, chapter int, status string) (string, error) {
+	return c.do(ctx, strings.TrimSpace(fmt.Sprintf("UPDATE %s %d %s", mangaID, chapter, status)))
+}
+
+// Ping checks that the server answers
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.do(ctx, "PING")
+	return err
+}
+
+// do sends a command and waits for its reply, returning the text after OK.
+// A command whose context ends first closes the client, since its reply
+// could otherwise be taken for that of the next command.
+func (c *Client) do(ctx context.Context, command string) (string, error) {
+	c.command.Lock()
+	defer c.command.Unlock()
+
+	if err := c.send(command); err != nil {
+		c.fail(err)
+		return "", err
+	}
+	select {
+	case line := <-c.reply:
+		return parseReply(line)
+	case <-c.done:
+		return "", c.err
+	case <-ctx.Done():
+		c.fail(fmt.Errorf("progressclient: %s: %w", command, ctx.Err()))
+		return "", ctx.Err()
+	}
+}
+
+// send writes one line frame
+func (c *Client) send(line string) error {
+	data, err := encodeFrame(&pb.ProgressFrame{Body: &pb.ProgressFrame_Line{Line: line}})
+	if err != nil {
+		return err
+	}
+	c.write.Lock()
+	defer c.write.Unlock()
+	_, err = c.conn.Write(data)
+	return err
+}
+
+// readLoop delivers updates and replies until the connection ends
+func (c *Client) readLoop() {
+	defer close(c.updates)
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(IdleTimeout))
+		frame, err := readFrame(c.reader)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+
+		switch body := frame.Body.(type) {
+		case *pb.ProgressFrame_Update:
+			select {
+			case c.updates <- body.Update:
+			case <-c.done:
+				return
+			}
+		case *pb.ProgressFrame_Line:
+			switch line := body.Line; {
+			case line == "PING":
+				c.send("PONG")
+			case strings.HasPrefix(line, "BYE"):
+				c.fail(&ServerError{Reason: strings.TrimSpace(strings.TrimPrefix(line, "BYE"))})
+				return
+			default:
+				select {
+				case c.reply <- line:
+				default: // Nothing is waiting for it
+				}
+			}
+		}
+	}
+}
+
+// fail ends the connection, recording the first reason
+func (c *Client) fail(err error) {
+	c.once.Do(func() {
+		c.err = err
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// parseReply returns the text after OK, or the error of an ERR reply
+func parseReply(line string) (string, error) {
+	switch {
+	case line == "OK" || line == "PONG":
+		return "", nil
+	case strings.HasPrefix(line, "OK "):
+		return line[len("OK "):], nil
+	case line == "ERR "+ErrGap.Error():
+		return "", ErrGap
+	case strings.HasPrefix(line, "ERR"):
+		return "", &ServerError{Reason: strings.TrimSpace(strings.TrimPrefix(line, "ERR"))}
+	default:
+		return "", fmt.Errorf("progressclient: unexpected reply %q", line)
+	}
+}
+
+// encodeFrame marshals frame behind its 4-byte big-endian size
+func encodeFrame(frame *pb.ProgressFrame) ([]byte, error) {
+	body, err := proto.Marshal(frame)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4, 4+len(body))
+	binary.BigEndian.PutUint32(buf, uint32(len(body)))
+	return append(buf, body...), nil
+}
+
+// readFrame reads one length-prefixed frame
+func readFrame(r io.Reader) (*pb.ProgressFrame, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > MaxFrameSize {
+		return nil, fmt.Errorf("progressclient: frame of %d bytes exceeds %d", n, MaxFrameSize)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	frame := &pb.ProgressFrame{}
+	if err := proto.Unmarshal(body, frame); err != nil {
+		return nil, fmt.Errorf("progressclient: invalid frame: %w", err)
+	}
+	return frame, nil
+}
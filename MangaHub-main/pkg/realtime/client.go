@@ -0,0 +1,407 @@
+// Package realtime is a client for the MangaHub progress updates that stays
+// connected. It speaks the TCP protocol (through pkg/progressclient) or the
+// UDP one (through pkg/udpclient), reconnects with backoff when the
+// connection is lost, restores its subscriptions, and delivers typed
+// models.ProgressUpdate values on a channel or to a callback.
+//
+//	c, err := realtime.Connect(ctx, realtime.Config{
+//		Transport: realtime.TCP,
+//		Addr:      "localhost:9090",
+//		Token:     realtime.StaticToken(token),
+//		Topics:    []string{"self"},
+//	})
+//	if err != nil { ... }
+//	defer c.Close()
+//	for u := range c.Updates() {
+//		fmt.Println(u.MangaTitle, u.CurrentChapter)
+//	}
+//
+// Over TCP a reconnecting client asks for the updates it missed with
+// RESUME; if the server no longer has them, Config.OnGap is called. UDP
+// subscriptions cannot catch up, so updates sent while a UDP client is
+// disconnected are lost; a reliable one that loses an update is ended by
+// the server, and Config.OnGap is called before subscribing again.
+package realtime
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"mangahub/internal/shared"
+	"mangahub/pkg/models"
+	"mangahub/pkg/progressclient"
+)
+
+// Transport chooses the protocol of a Client
+type Transport string
+
+const (
+	TCP Transport = "tcp"
+	UDP Transport = "udp"
+)
+
+// Defaults of Config
+const (
+	DefaultHeartbeat  = 30 * time.Second
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+// UpdateBuffer is the capacity of the Updates channel
+const UpdateBuffer = 256
+
+// ErrClosed is returned by the methods of a closed Client
+var ErrClosed = errors.New("realtime: client closed")
+
+// Config of a Client
+type Config struct {
+	// Transport is TCP or UDP
+	Transport Transport
+	// Addr of the server, e.g. localhost:9090 for TCP or localhost:9091
+	// for UDP
+	Addr string
+	// Token returns an access token from POST /auth/login. It is called for
+	// every connection, so it may refresh an expired token.
+	Token func(ctx context.Context) (string, error)
+	// Topics to subscribe to: self, user:<id> or manga:<id>. UDP needs at
+	// least one.
+	Topics []string
+
+	// TLS, if not nil, secures TCP connections
+	TLS *tls.Config
+	// Reliable asks for reliable UDP delivery
+	Reliable bool
+
+	// Handler, if set, receives the updates instead of Updates. It runs on
+	// the client's goroutine, so a slow handler holds up the connection.
+	Handler func(models.ProgressUpdate)
+	// OnDisconnect, if set, is called with the reason whenever a
+	// connection ends or cannot be made, before the client tries again
+	OnDisconnect func(error)
+	// OnGap, if set, is called when a reconnected TCP client missed
+	// updates the server no longer has, or the server ended a reliable UDP
+	// subscription that lost one. Reload the state over REST.
+	OnGap func()
+
+	// Heartbeat is how often a TCP connection is pinged, DefaultHeartbeat
+	// if zero. UDP subscriptions are pinged by pkg/udpclient.
+	Heartbeat time.Duration
+	// MinBackoff and MaxBackoff bound the wait before reconnecting, which
+	// doubles after every failed attempt
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// StaticToken returns a Config.Token that always returns token
+func StaticToken(token string) func(context.Context) (string, error) {
+	return func(context.Context) (string, error) { return token, nil }
+}
+
+// conn is one connection of a Client
+type conn interface {
+	// run delivers updates until the connection ends and returns why
+	run(deliver func(models.ProgressUpdate)) error
+	subscribe(ctx context.Context, topic string) error
+	unsubscribe(ctx context.Context, topic string) error
+	close()
+}
+
+// Client is a connection that reconnects until closed. Its methods may be
+// called from several goroutines.
+type Client struct {
+	cfg     Config
+	updates chan models.ProgressUpdate
+
+	mu      sync.Mutex
+	topics  map[string]bool
+	current conn // nil while reconnecting
+	restart bool // current was closed to apply new topics
+	lastSeq uint64
+	hasSeq  bool // lastSeq is known
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// Connect makes the first connection, returning its error if it fails, and
+// then keeps the client connected until Close
+func Connect(ctx context.Context, cfg Config) (*Client, error) {
+	if cfg.Transport != TCP && cfg.Transport != UDP {
+		return nil, fmt.Errorf("realtime: unknown transport %q", cfg.Transport)
+	}
+	if cfg.Addr == "" || cfg.Token == nil {
+		return nil, errors.New("realtime: Addr and Token are required")
+	}
+	if cfg.Transport == UDP && len(cfg.Topics) == 0 {
+		return nil, errors.New("realtime: UDP needs at least one topic")
+	}
+	if cfg.Heartbeat <= 0 {
+		cfg.Heartbeat = DefaultHeartbeat
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = DefaultMinBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = max(DefaultMaxBackoff, cfg.MinBackoff)
+	}
+
+	c := &Client{
+		cfg:     cfg,
+		updates: make(chan models.ProgressUpdate, UpdateBuffer),
+		topics:  map[string]bool{},
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for _, topic := range cfg.Topics {
+		if err := checkTopic(topic); err != nil {
+			return nil, err
+		}
+		c.topics[topic] = true
+	}
+
+	first, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.current = first
+	go c.loop(first)
+	return c, nil
+}
+
+// Updates returns the channel of the updates on the client's topics, unless
+// Config.Handler is set. It is closed by Close.
+func (c *Client) Updates() <-chan models.ProgressUpdate {
+	return c.updates
+}
+
+// Topics returns the client's topics in sorted order
+func (c *Client) Topics() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	topics := make([]string, 0, len(c.topics))
+	for t := range c.topics {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Connected reports whether the client has a connection right now
+func (c *Client) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.current != nil
+}
+
+// Subscribe adds a topic, now if connected and otherwise on reconnecting.
+// A UDP client subscribes again with the new set of topics.
+func (c *Client) Subscribe(ctx context.Context, topic string) error {
+	if err := checkTopic(topic); err != nil {
+		return err
+	}
+	return c.change(ctx, topic, true)
+}
+
+// Unsubscribe drops a topic. A UDP client keeps at least one.
+func (c *Client) Unsubscribe(ctx context.Context, topic string) error {
+	return c.change(ctx, topic, false)
+}
+
+// change adds or drops a topic and applies it to the connection
+func (c *Client) change(ctx context.Context, topic string, add bool) error {
+	c.mu.Lock()
+	select {
+	case <-c.stop:
+		c.mu.Unlock()
+		return ErrClosed
+	default:
+	}
+	if c.topics[topic] == add {
+		c.mu.Unlock()
+		return nil
+	}
+	if !add && c.cfg.Transport == UDP && len(c.topics) == 1 {
+		c.mu.Unlock()
+		return errors.New("realtime: UDP needs at least one topic")
+	}
+	if add {
+		c.topics[topic] = true
+	} else {
+		delete(c.topics, topic)
+	}
+	current := c.current
+	if current != nil && c.cfg.Transport == UDP {
+		// A UDP subscription's topics are fixed, so subscribe again
+		c.restart = true
+		current.close()
+	}
+	c.mu.Unlock()
+
+	if current == nil || c.cfg.Transport == UDP {
+		return nil
+	}
+	var err error
+	if add {
+		err = current.subscribe(ctx, topic)
+	} else {
+		err = current.unsubscribe(ctx, topic)
+	}
+	var serverErr *progressclient.ServerError
+	if errors.As(err, &serverErr) {
+		// Refused, e.g. too many topics: forget it again
+		c.mu.Lock()
+		if add {
+			delete(c.topics, topic)
+		}
+		c.mu.Unlock()
+		return err
+	}
+	return nil // Connection errors are handled by reconnecting
+}
+
+// Close ends the connection and stops reconnecting. Updates is closed once
+// the client has stopped.
+func (c *Client) Close() error {
+	c.once.Do(func() {
+		c.mu.Lock()
+		close(c.stop)
+		if c.current != nil {
+			c.current.close()
+		}
+		c.mu.Unlock()
+	})
+	<-c.done
+	return nil
+}
+
+// loop runs connections until Close
+func (c *Client) loop(current conn) {
+	defer close(c.done)
+	defer close(c.updates)
+
+	for {
+		err := current.run(c.deliver)
+
+		c.mu.Lock()
+		c.current = nil
+		restart := c.restart
+		c.restart = false
+		c.mu.Unlock()
+
+		if c.stopped() {
+			return
+		}
+		if !restart {
+			c.disconnected(err)
+		}
+
+		current = c.reconnect(restart)
+		if current == nil {
+			return
+		}
+	}
+}
+
+// reconnect dials until it succeeds, waiting longer after each failure,
+// or returns nil if the client is closed meanwhile. With now, the first
+// attempt is made at once.
+func (c *Client) reconnect(now bool) conn {
+	backoff := c.cfg.MinBackoff
+	wait := backoff
+	if now {
+		wait = 0
+	}
+	for {
+		select {
+		case <-time.After(wait):
+		case <-c.stop:
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+		next, err := c.dial(ctx)
+		cancel()
+		if err == nil {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			select {
+			case <-c.stop:
+				next.close()
+				return nil
+			default:
+			}
+			c.current = next
+			return next
+		}
+
+		c.disconnected(err)
+		backoff = min(2*backoff, c.cfg.MaxBackoff)
+		wait = backoff
+	}
+}
+
+// dialTimeout bounds one connection attempt
+const dialTimeout = 15 * time.Second
+
+// dial makes a connection with the current topics
+func (c *Client) dial(ctx context.Context) (conn, error) {
+	token, err := c.cfg.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("realtime: get token: %w", err)
+	}
+	if c.cfg.Transport == UDP {
+		return dialUDP(ctx, c, token)
+	}
+	return dialTCP(ctx, c, token)
+}
+
+// deliver hands an update to the handler or the Updates channel, unless
+// its seq shows it was delivered already
+func (c *Client) deliver(u models.ProgressUpdate) {
+	if u.Seq != 0 {
+		c.mu.Lock()
+		if c.hasSeq && u.Seq <= c.lastSeq {
+			c.mu.Unlock()
+			return // Delivered already, e.g. both live and replayed by RESUME
+		}
+		c.lastSeq, c.hasSeq = u.Seq, true
+		c.mu.Unlock()
+	}
+	if c.cfg.Handler != nil {
+		c.cfg.Handler(u)
+		return
+	}
+	select {
+	case c.updates <- u:
+	case <-c.stop:
+	}
+}
+
+func (c *Client) disconnected(err error) {
+	if c.cfg.OnDisconnect != nil && err != nil {
+		c.cfg.OnDisconnect(err)
+	}
+}
+
+func (c *Client) stopped() bool {
+	select {
+	case <-c.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// checkTopic validates a topic before it is sent to the server
+func checkTopic(topic string) error {
+	if _, err := shared.ParseTopic(topic, "me"); err != nil {
+		return fmt.Errorf("realtime: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package realtime_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"mangahub/internal/auth"
+	"mangahub/internal/shared"
+	"mangahub/internal/tcp"
+	"mangahub/internal/udp"
+	"mangahub/pkg/models"
+	"mangahub/pkg/realtime"
+)
+
+// udpAddr is the in-process UDP server; TCP servers are started per test
+var udpAddr string
+
+func TestMain(m *testing.M) {
+	auth.JWTSecret = []byte("realtime-test-secret-0123456789abcdef")
+	go tcp.GlobalHub.Run()
+	go udp.GlobalHub.Run()
+	addr, err := udp.Listen("127.0.0.1:0", auth.AnySession)
+	if err != nil {
+		panic(err)
+	}
+	udpAddr = addr.String()
+	os.Exit(m.Run())
+}
+
+// serveTCP runs the TCP server on addr until the returned listener is closed
+func serveTCP(t *testing.T, addr string) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go tcp.Serve(listener, nil, nil, auth.AnySession)
+	return listener
+}
+
+// tokenFor returns a Config.Token for a user of its own
+func tokenFor(t *testing.T, userID string) func(context.Context) (string, error) {
+	t.Helper()
+	token, err := auth.GenerateToken(userID, "reader", auth.RoleUser, "ses_"+userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return realtime.StaticToken(token)
+}
+
+func connect(t *testing.T, cfg realtime.Config) *realtime.Client {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := realtime.Connect(ctx, cfg)
+	if err != nil {
+		t.Fatalf("connecting: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// waitForClient waits for a connection of userID other than the one with
+// ID old and subscribed to topics, as listed by clients
+func waitForClient(t *testing.T, clients func() []shared.ClientInfo, userID, old string, topics ...string) shared.ClientInfo {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, info := range clients() {
+			if info.UserID == userID && info.ID != old && slices.Equal(info.Subscriptions, topics) {
+				return info
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no connection of %s subscribed to %v; clients %+v", userID, topics, clients())
+	return shared.ClientInfo{}
+}
+
+// next returns the client's next update
+func next(t *testing.T, c *realtime.Client) models.ProgressUpdate {
+	t.Helper()
+	select {
+	case u, ok := <-c.Updates():
+		if !ok {
+			t.Fatal("client closed")
+		}
+		return u
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update")
+		return models.ProgressUpdate{}
+	}
+}
+
+// expectChapters checks that the next updates are the given chapters, in
+// order, and nothing else follows
+func expectChapters(t *testing.T, c *realtime.Client, chapters ...int) {
+	t.Helper()
+	for _, want := range chapters {
+		if u := next(t, c); u.CurrentChapter != want {
+			t.Fatalf("got chapter %d, want %d of %v", u.CurrentChapter, want, chapters)
+		}
+	}
+	select {
+	case u := <-c.Updates():
+		t.Errorf("extra update: chapter %d", u.CurrentChapter)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestTCPReconnectResumesMissedUpdates(t *testing.T) {
+	listener := serveTCP(t, "127.0.0.1:0")
+	disconnects := make(chan error, 16)
+	c := connect(t, realtime.Config{
+		Transport:    realtime.TCP,
+		Addr:         listener.Addr().String(),
+		Token:        tokenFor(t, "usr_resume"),
+		Topics:       []string{"manga:resume-a"},
+		MinBackoff:   300 * time.Millisecond, // Long enough to miss updates
+		OnDisconnect: func(err error) { disconnects <- err },
+	})
+	if err := c.Subscribe(context.Background(), "self"); err != nil {
+		t.Fatal(err)
+	}
+	info := waitForClient(t, tcp.GlobalHub.Clients, "usr_resume", "", "manga:resume-a", "user:usr_resume")
+
+	tcp.GlobalHub.BroadcastProgress(models.ProgressUpdate{MangaID: "resume-a", CurrentChapter: 1})
+	expectChapters(t, c, 1)
+
+	tcp.GlobalHub.Disconnect(info.ID)
+	tcp.GlobalHub.BroadcastProgress(models.ProgressUpdate{MangaID: "resume-a", CurrentChapter: 2})
+	tcp.GlobalHub.BroadcastProgress(models.ProgressUpdate{MangaID: "resume-other", CurrentChapter: 99})
+	tcp.GlobalHub.BroadcastProgress(models.ProgressUpdate{UserID: "usr_resume", MangaID: "resume-b", CurrentChapter: 3})
+	select {
+	case <-disconnects:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnDisconnect was not called")
+	}
+
+	// Both topics are restored, and RESUME replays what was missed
+	waitForClient(t, tcp.GlobalHub.Clients, "usr_resume", info.ID, "manga:resume-a", "user:usr_resume")
+	tcp.GlobalHub.BroadcastProgress(models.ProgressUpdate{MangaID: "resume-a", CurrentChapter: 4})
+	expectChapters(t, c, 2, 3, 4)
+}
+
+func TestTCPResumeGapCallsOnGap(t *testing.T) {
+	listener := serveTCP(t, "127.0.0.1:0")
+	gaps := make(chan struct{}, 1)
+	c := connect(t, realtime.Config{
+		Transport:  realtime.TCP,
+		Addr:       listener.Addr().String(),
+		Token:      tokenFor(t, "usr_gap"),
+		Topics:     []string{"manga:gap-a"},
+		MinBackoff: 300 * time.Millisecond,
+		OnGap:      func() { gaps <- struct{}{} },
+	})
+	info := waitForClient(t, tcp.GlobalHub.Clients, "usr_gap", "", "manga:gap-a")
+
+	// More updates than the hub retains push the client's position out
+	tcp.GlobalHub.Disconnect(info.ID)
+	tcp.GlobalHub.BroadcastProgress(models.ProgressUpdate{MangaID: "gap-a", CurrentChapter: 1})
+	for i := 0; i < tcp.RetainedUpdates; i++ {
+		tcp.GlobalHub.BroadcastProgress(models.ProgressUpdate{MangaID: "gap-other", CurrentChapter: i})
+	}
+	select {
+	case <-gaps:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnGap was not called")
+	}
+
+	// Live updates carry on after the gap
+	tcp.GlobalHub.BroadcastProgress(models.ProgressUpdate{MangaID: "gap-a", CurrentChapter: 2})
+	expectChapters(t, c, 2)
+}
+
+func TestTCPReconnectBacksOff(t *testing.T) {
+	const minBackoff, maxBackoff = 50 * time.Millisecond, 200 * time.Millisecond
+	listener := serveTCP(t, "127.0.0.1:0")
+	addr := listener.Addr().String()
+	failures := make(chan time.Time, 64)
+	c := connect(t, realtime.Config{
+		Transport:  realtime.TCP,
+		Addr:       addr,
+		Token:      tokenFor(t, "usr_backoff"),
+		Topics:     []string{"self"},
+		MinBackoff: minBackoff,
+		MaxBackoff: maxBackoff,
+		OnDisconnect: func(error) {
+			select {
+			case failures <- time.Now():
+			default:
+			}
+		},
+	})
+	info := waitForClient(t, tcp.GlobalHub.Clients, "usr_backoff", "", "user:usr_backoff")
+
+	// The connection is lost and every attempt is refused
+	listener.Close()
+	tcp.GlobalHub.Disconnect(info.ID)
+	var at []time.Time
+	for len(at) < 6 {
+		select {
+		case failure := <-failures:
+			at = append(at, failure)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d disconnects reported", len(at))
+		}
+	}
+	waits := []time.Duration{minBackoff, 2 * minBackoff, maxBackoff, maxBackoff, maxBackoff}
+	for i, want := range waits {
+		if got := at[i+1].Sub(at[i]); got < want || got > 2*want {
+			t.Errorf("wait %d was %v, want %v", i+1, got, want)
+		}
+	}
+
+	// Once the server is back the client reconnects with its topics
+	serveTCP(t, addr)
+	waitForClient(t, tcp.GlobalHub.Clients, "usr_backoff", info.ID, "user:usr_backoff")
+	tcp.GlobalHub.BroadcastProgress(models.ProgressUpdate{UserID: "usr_backoff", CurrentChapter: 1})
+	expectChapters(t, c, 1)
+	if !c.Connected() {
+		t.Error("client not connected after reconnecting")
+	}
+}
+
+func TestUDPReconnectRestoresTopics(t *testing.T) {
+	disconnects := make(chan error, 16)
+	c := connect(t, realtime.Config{
+		Transport:    realtime.UDP,
+		Addr:         udpAddr,
+		Token:        tokenFor(t, "usr_udp"),
+		Topics:       []string{"manga:udp-a"},
+		Reliable:     true,
+		MinBackoff:   50 * time.Millisecond,
+		OnDisconnect: func(err error) { disconnects <- err },
+	})
+	waitForClient(t, udp.GlobalHub.Clients, "usr_udp", "", "manga:udp-a")
+	udp.GlobalHub.BroadcastProgress(models.ProgressUpdate{MangaID: "udp-a", CurrentChapter: 1})
+	expectChapters(t, c, 1)
+
+	// A new topic subscribes again with both
+	if err := c.Subscribe(context.Background(), "manga:udp-b"); err != nil {
+		t.Fatal(err)
+	}
+	info := waitForClient(t, udp.GlobalHub.Clients, "usr_udp", "", "manga:udp-a", "manga:udp-b")
+	udp.GlobalHub.BroadcastProgress(models.ProgressUpdate{MangaID: "udp-b", CurrentChapter: 2})
+	expectChapters(t, c, 2)
+
+	// Dropped by the server, it subscribes again to both topics
+	udp.GlobalHub.Disconnect(info.ID)
+	select {
+	case <-disconnects:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnDisconnect was not called")
+	}
+	waitForClient(t, udp.GlobalHub.Clients, "usr_udp", info.ID, "manga:udp-a", "manga:udp-b")
+	udp.GlobalHub.BroadcastProgress(models.ProgressUpdate{MangaID: "udp-b", CurrentChapter: 3})
+	udp.GlobalHub.BroadcastProgress(models.ProgressUpdate{MangaID: "udp-a", CurrentChapter: 4})
+	expectChapters(t, c, 3, 4)
+}
@@ -0,0 +1,199 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"mangahub/pkg/models"
+	"mangahub/pkg/progressclient"
+	"mangahub/pkg/udpclient"
+	pb "mangahub/proto"
+)
+
+// tcpConn is a connection to the TCP progress server
+type tcpConn struct {
+	owner  *Client
+	client *progressclient.Client
+	// resume is the seq to RESUME after, if resuming
+	resume   uint64
+	resuming bool
+}
+
+// dialTCP connects, subscribes to the client's topics and prepares to
+// resume after the last update seen
+func dialTCP(ctx context.Context, owner *Client, token string) (conn, error) {
+	client, err := progressclient.Dial(ctx, owner.cfg.Addr, token, owner.cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+	for _, topic := range owner.Topics() {
+		if err := client.Subscribe(ctx, topic); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	t := &tcpConn{owner: owner, client: client}
+	owner.mu.Lock()
+	if owner.hasSeq {
+		t.resume, t.resuming = owner.lastSeq, true
+	} else {
+		owner.lastSeq, owner.hasSeq = client.Seq, true
+	}
+	owner.mu.Unlock()
+	return t, nil
+}
+
+func (t *tcpConn) run(deliver func(models.ProgressUpdate)) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	go t.heartbeat(stop)
+	if t.resuming {
+		held := t.catchUp()
+		sort.Slice(held, func(i, j int) bool { return held[i].Seq < held[j].Seq })
+		for _, u := range held {
+			deliver(u) // Drops the live updates the replay repeats
+		}
+	}
+
+	for u := range t.client.Updates() {
+		deliver(fromProto(u))
+	}
+	return t.client.Err()
+}
+
+// catchUp asks for the updates missed while disconnected and returns them
+// together with the live ones that arrived meanwhile, which the replay
+// repeats, so that they can be delivered in order. The replay follows the
+// reply to RESUME and covers every seq after t.resume up to then, so it is
+// complete once that many distinct seqs were received.
+func (t *tcpConn) catchUp() []models.ProgressUpdate {
+	type result struct {
+		n   int
+		err error
+	}
+	replied := make(chan result, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+		defer cancel()
+		n, err := t.client.Resume(ctx, t.resume)
+		replied <- result{n, err}
+	}()
+
+	var held []models.ProgressUpdate
+	seen := map[uint64]bool{}
+	replay := -1 // Number of replayed updates, once the reply arrived
+	for replay < 0 || len(seen) < replay {
+		select {
+		case u, ok := <-t.client.Updates():
+			if !ok {
+				return held // Connection lost; it is made again
+			}
+			held = append(held, fromProto(u))
+			seen[u.GetSeq()] = true
+		case r := <-replied:
+			replay = r.n
+			if errors.Is(r.err, progressclient.ErrGap) {
+				t.gap()
+				return held
+			} else if r.err != nil {
+				return held // Other errors end the connection, which is then made again
+			}
+		}
+	}
+	return held
+}
+
+// gap starts over from the server's current position after a RESUME it
+// could not serve
+func (t *tcpConn) gap() {
+	t.owner.mu.Lock()
+	t.owner.lastSeq = t.client.Seq
+	t.owner.mu.Unlock()
+	if t.owner.cfg.OnGap != nil {
+		t.owner.cfg.OnGap()
+	}
+}
+
+// heartbeat pings the server until stop is closed. A ping that is not
+// answered in time closes the connection.
+func (t *tcpConn) heartbeat(stop <-chan struct{}) {
+	ticker := time.NewTicker(t.owner.cfg.Heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), t.owner.cfg.Heartbeat)
+			t.client.Ping(ctx)
+			cancel()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (t *tcpConn) subscribe(ctx context.Context, topic string) error {
+	return t.client.Subscribe(ctx, topic)
+}
+
+func (t *tcpConn) unsubscribe(ctx context.Context, topic string) error {
+	return t.client.Unsubscribe(ctx, topic)
+}
+
+func (t *tcpConn) close() {
+	t.client.Close()
+}
+
+// fromProto converts an update of the protobuf framing
+func fromProto(u *pb.ProgressUpdate) models.ProgressUpdate {
+	return models.ProgressUpdate{
+		UserID:         u.GetUserId(),
+		Username:       u.GetUsername(),
+		MangaID:        u.GetMangaId(),
+		MangaTitle:     u.GetMangaTitle(),
+		CurrentChapter: int(u.GetCurrentChapter()),
+		Status:         u.GetStatus(),
+		Timestamp:      u.GetTimestamp(),
+		Type:           u.GetType(),
+		EventID:        u.GetEventId(),
+		Seq:            u.GetSeq(),
+	}
+}
+
+// udpConn is a UDP subscription
+type udpConn struct {
+	owner  *Client
+	client *udpclient.Client
+}
+
+// dialUDP subscribes to the client's topics, renewing the subscription with
+// tokens from Config.Token
+func dialUDP(ctx context.Context, owner *Client, token string) (conn, error) {
+	opts := udpclient.Options{Reliable: owner.cfg.Reliable, Renew: owner.cfg.Token}
+	client, err := udpclient.Dial(ctx, owner.cfg.Addr, token, owner.Topics(), opts)
+	if err != nil {
+		return nil, err
+	}
+	return &udpConn{owner: owner, client: client}, nil
+}
+
+func (u *udpConn) run(deliver func(models.ProgressUpdate)) error {
+	for update := range u.client.Updates() {
+		deliver(update)
+	}
+	err := u.client.Err()
+	if errors.Is(err, udpclient.ErrGap) && u.owner.cfg.OnGap != nil {
+		u.owner.cfg.OnGap()
+	}
+	return err
+}
+
+// A UDP subscription's topics are changed by subscribing again, see Client.change
+func (u *udpConn) subscribe(context.Context, string) error   { return nil }
+func (u *udpConn) unsubscribe(context.Context, string) error { return nil }
+
+func (u *udpConn) close() {
+	u.client.Close()
+}
@@ -0,0 +1,457 @@
+// Package udpclient subscribes Go programs to the UDP notifications of
+// cmd/udp-server (see internal/udp for the protocol). It keeps the
+// subscription alive, renews it given Options.Renew, subscribes again when
+// the server forgets it, splits batched datagrams into updates and
+// reassembles fragmented ones, and in reliable mode acknowledges updates,
+// drops the duplicates that retransmission causes and delivers them in
+// order.
+//
+//	c, err := udpclient.Dial(ctx, "localhost:9091", token, []string{"self"}, udpclient.Options{Reliable: true})
+//	if err != nil { ... }
+//	defer c.Close()
+//	for u := range c.Updates() {
+//		fmt.Println(u.MangaTitle, u.CurrentChapter)
+//	}
+//	// c.Err() tells why the subscription ended
+package udpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mangahub/pkg/models"
+)
+
+// Timing of the client
+const (
+	// ResendInterval is how often SUB is sent until the server answers
+	ResendInterval = time.Second
+	// PingInterval is how often an active subscription is refreshed, a
+	// third of the server's 30-second timeout so that lost PINGs are
+	// tolerated
+	PingInterval = 10 * time.Second
+	// IdleTimeout ends a client that received nothing, not even PONG, for
+	// this long
+	IdleTimeout = 3*PingInterval + 5*time.Second
+	// UpdateBuffer is the capacity of the Updates channel. In reliable
+	// mode a program that stops reading it stops acknowledging, and is
+	// eventually dropped as a slow consumer.
+	UpdateBuffer = 256
+	// AckDelay is how long acknowledgements are collected into one ACK,
+	// well below the server's 500ms retransmit timeout. The server limits
+	// the datagrams each IP may send.
+	AckDelay = 50 * time.Millisecond
+)
+
+// maxAckBatch is how many seqs one ACK carries at most
+const maxAckBatch = 64
+
+// maxDatagram bounds what the server sends
+const maxDatagram = 64 << 10
+
+// ErrClosed is the Err of a client ended by Close
+var ErrClosed = errors.New("udpclient: subscription closed")
+
+// ErrGap is the Err of a reliable subscription the server ended because an
+// update could not be delivered. Subscribe again and reload what may have
+// been missed, e.g. over REST.
+var ErrGap = errors.New("udpclient: an update was lost")
+
+// ServerError is an ERR reply to SUB, or the reason of the server's BYE
+type ServerError struct {
+	Reason string
+}
+
+func (e *ServerError) Error() string {
+	return "udp server: " + e.Reason
+}
+
+// Options of a subscription
+type Options struct {
+	// Reliable asks for numbered updates that are retransmitted until
+	// acknowledged
+	Reliable bool
+	// Renew, if set, returns a fresh access token, with which the client
+	// renews the subscription after two thirds of the lifetime the server
+	// gave it. Without it the subscription ends when that runs out.
+	Renew func(ctx context.Context) (string, error)
+}
+
+// Stats counts what a client received
+type Stats struct {
+	Received     uint64 // Updates delivered on Updates
+	Duplicates   uint64 // Retransmitted updates dropped because they had arrived already
+	Missed       uint64 // Reliable updates the client stopped waiting for
+	Resubscribed uint64 // Times the server forgot the subscription
+	Reassembled  uint64 // Messages joined from fragments
+	Incomplete   uint64 // Fragmented messages given up on, see FragmentTimeout
+}
+
+// Client is a subscription. Its methods may be called from several
+// goroutines.
+type Client struct {
+	conn    net.Conn
+	sub     string // SUB arguments after the token
+	renew   func(ctx context.Context) (string, error)
+	updates chan models.ProgressUpdate
+	ready   chan struct{} // Closed on the first OK
+
+	mu       sync.Mutex
+	token    string
+	cookie   string    // Proof of our address, sent before SUB
+	id       string    // Subscription ID, empty while subscribing
+	renewAt  time.Time // When to renew the subscription, zero if never
+	lastPing time.Time
+	seen     window
+	acks     []string // Seqs to acknowledge, see AckDelay
+	frags    reassembly
+	stats    Stats
+
+	once sync.Once
+	done chan struct{}
+	err  error // Why the subscription ended, set before done is closed
+}
+
+// Dial subscribes to topics (self, user:<id> or manga:<id>) on the server
+// at addr with an access token from POST /auth/login
+func Dial(ctx context.Context, addr, token string, topics []string, opts Options) (*Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewClient(ctx, conn, token, topics, opts)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewClient subscribes on a connected UDP socket, such as one wrapped in a
+// LossyConn, and waits for the server to accept. The connection is closed
+// with the client.
+func NewClient(ctx context.Context, conn net.Conn, token string, topics []string, opts Options) (*Client, error) {
+	if len(topics) == 0 {
+		return nil, errors.New("udpclient: no topics")
+	}
+	sub := ""
+	if opts.Reliable {
+		sub = " RELIABLE"
+	}
+	c := &Client{
+		conn:    conn,
+		sub:     sub + " " + strings.Join(topics, " "),
+		renew:   opts.Renew,
+		token:   token,
+		updates: make(chan models.ProgressUpdate, UpdateBuffer),
+		ready:   make(chan struct{}),
+		frags:   reassembly{},
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+	go c.keepAlive()
+
+	select {
+	case <-c.ready:
+		return c, nil
+	case <-c.done:
+		return nil, c.err
+	case <-ctx.Done():
+		c.fail(ctx.Err())
+		return nil, ctx.Err()
+	}
+}
+
+// Updates returns the channel of the updates on the client's topics. It is
+// closed when the subscription ends; Err then tells why.
+func (c *Client) Updates() <-chan models.ProgressUpdate {
+	return c.updates
+}
+
+// Err returns why the subscription ended, or nil while it is active
+func (c *Client) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+// Stats returns the client's counters
+func (c *Client) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Close ends the subscription
+func (c *Client) Close() error {
+	c.mu.Lock()
+	id := c.id
+	c.mu.Unlock()
+	if id != "" {
+		c.send("UNSUB " + id) // Best effort; it times out otherwise
+	}
+	c.fail(ErrClosed)
+	return nil
+}
+
+// keepAlive sends SUB until the server answers, then PING every
+// PingInterval, renewing the subscription when due
+func (c *Client) keepAlive() {
+	ticker := time.NewTicker(ResendInterval)
+	defer ticker.Stop()
+	for {
+		c.mu.Lock()
+		command := ""
+		switch {
+		case c.id == "":
+			command = c.subCommand()
+		case !c.renewAt.IsZero() && time.Now().After(c.renewAt):
+			// Until the OK arrives, try again every PingInterval
+			c.renewAt = time.Now().Add(PingInterval)
+			c.mu.Unlock()
+			command = c.renewal()
+			c.mu.Lock()
+		case time.Since(c.lastPing) >= PingInterval:
+			command = "PING " + c.id
+			c.lastPing = time.Now()
+		}
+		c.mu.Unlock()
+
+		if command != "" {
+			if err := c.send(command); err != nil {
+				c.fail(err)
+				return
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// readLoop handles datagrams until the subscription ends
+func (c *Client) readLoop() {
+	defer close(c.updates)
+	buf := make([]byte, maxDatagram)
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(IdleTimeout))
+		n, err := c.conn.Read(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				err = fmt.Errorf("udpclient: nothing received for %v", IdleTimeout)
+			}
+			c.fail(err)
+			return
+		}
+
+		datagram := buf[:n]
+		if bytes.HasPrefix(datagram, []byte(fragPrefix)) {
+			c.mu.Lock()
+			message, complete, dropped := c.frags.add(datagram, time.Now())
+			c.stats.Incomplete += dropped
+			if complete {
+				c.stats.Reassembled++
+			}
+			c.mu.Unlock()
+			if !complete {
+				continue
+			}
+			datagram = message
+		}
+		// The server sends several lines to a datagram when it can
+		for _, line := range strings.Split(string(datagram), "\n") {
+			if !c.handle(strings.TrimSpace(line)) {
+				return
+			}
+		}
+
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+	}
+}
+
+// handle runs one line from the server and reports false if it ended the
+// subscription
+func (c *Client) handle(message string) bool {
+	switch {
+	case strings.HasPrefix(message, "{"):
+		c.deliver(message)
+	case strings.HasPrefix(message, "DATA "):
+		c.data(message)
+	case message == "PONG":
+	case strings.HasPrefix(message, "COOKIE "):
+		// Answer to a SUB without a valid cookie: send it again with this one
+		c.mu.Lock()
+		c.cookie = strings.TrimPrefix(message, "COOKIE ")
+		command := c.subCommand()
+		c.mu.Unlock()
+		c.send(command)
+	case strings.HasPrefix(message, "OK "):
+		id, lifetime, _ := strings.Cut(strings.TrimPrefix(message, "OK "), " ")
+		seconds, _ := strconv.Atoi(lifetime)
+		c.subscribed(id, time.Duration(seconds)*time.Second)
+	case message == "ERR unknown subscription, SUB again":
+		c.mu.Lock()
+		if c.id != "" {
+			c.id = ""
+			c.stats.Resubscribed++
+		}
+		c.mu.Unlock()
+	case strings.HasPrefix(message, "BYE gap"):
+		c.fail(fmt.Errorf("%w: server gave up on update %s", ErrGap, strings.TrimSpace(strings.TrimPrefix(message, "BYE gap"))))
+		return false
+	case strings.HasPrefix(message, "ERR "), strings.HasPrefix(message, "BYE "):
+		c.fail(&ServerError{Reason: message[strings.IndexByte(message, ' ')+1:]})
+		return false
+	}
+	return true
+}
+
+// subCommand returns SUB, behind the cookie if there is one. c.mu must
+// be held.
+func (c *Client) subCommand() string {
+	sub := "SUB " + c.token + c.sub
+	if c.cookie == "" {
+		return sub
+	}
+	return "COOKIE " + c.cookie + " " + sub
+}
+
+// renewal fetches a fresh token and returns the SUB renewing the
+// subscription with it, or "" if there is no token
+func (c *Client) renewal() string {
+	ctx, cancel := context.WithTimeout(context.Background(), PingInterval)
+	token, err := c.renew(ctx)
+	cancel()
+	if err != nil || token == "" {
+		return "" // Tried again later; the subscription lasts meanwhile
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+	return c.subCommand()
+}
+
+// subscribed records the subscription ID and lifetime of an OK. A new
+// subscription numbers its reliable updates and fragmented messages from 1
+// again; a renewed one keeps its ID and numbering.
+func (c *Client) subscribed(id string, lifetime time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.renewAt = time.Time{}
+	if c.renew != nil && lifetime > 0 {
+		c.renewAt = time.Now().Add(lifetime * 2 / 3)
+	}
+	if c.id == id {
+		return // Renewed, or an answer to a SUB resent before the first OK arrived
+	}
+	c.id = id
+	c.lastPing = time.Now()
+	c.seen = window{}
+	clear(c.frags)
+	select {
+	case <-c.ready:
+	default:
+		close(c.ready)
+	}
+}
+
+// data acknowledges a reliable update and delivers it, together with any
+// later ones that were waiting for it, unless it is a duplicate
+func (c *Client) data(message string) {
+	fields := strings.SplitN(message, " ", 3)
+	if len(fields) != 3 {
+		return
+	}
+	seq, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	if c.id == "" {
+		c.mu.Unlock()
+		return // Sent before the server forgot the subscription
+	}
+	ready, fresh, skipped := c.seen.add(seq, fields[2])
+	c.stats.Missed += skipped
+	if !fresh {
+		c.stats.Duplicates++
+	}
+	// Acknowledge duplicates too, since the first ACK was probably lost
+	c.acks = append(c.acks, fields[1])
+	switch {
+	case len(c.acks) >= maxAckBatch:
+		c.flushAcks()
+	case len(c.acks) == 1:
+		time.AfterFunc(AckDelay, func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.flushAcks()
+		})
+	}
+	c.mu.Unlock()
+
+	for _, update := range ready {
+		c.deliver(update)
+	}
+}
+
+// flushAcks sends the pending acknowledgements in one ACK. c.mu must be
+// held.
+func (c *Client) flushAcks() {
+	if len(c.acks) == 0 || c.id == "" {
+		c.acks = c.acks[:0]
+		return
+	}
+	c.send("ACK " + c.id + " " + strings.Join(c.acks, " "))
+	c.acks = c.acks[:0]
+}
+
+// deliver decodes an update and queues it on Updates
+func (c *Client) deliver(data string) {
+	var update models.ProgressUpdate
+	if err := json.Unmarshal([]byte(data), &update); err != nil {
+		return
+	}
+	select {
+	case c.updates <- update:
+		c.mu.Lock()
+		c.stats.Received++
+		c.mu.Unlock()
+	case <-c.done:
+	}
+}
+
+// send writes one command datagram
+func (c *Client) send(command string) error {
+	_, err := c.conn.Write([]byte(command + "\n"))
+	return err
+}
+
+// fail ends the subscription, recording the first reason
+func (c *Client) fail(err error) {
+	c.once.Do(func() {
+		c.err = err
+		close(c.done)
+		c.conn.Close()
+	})
+}
@@ -0,0 +1,211 @@
+package udpclient_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"mangahub/internal/auth"
+	"mangahub/internal/udp"
+	"mangahub/pkg/models"
+	"mangahub/pkg/udpclient"
+)
+
+// serverAddr is the in-process UDP server the tests subscribe to
+var serverAddr string
+
+func TestMain(m *testing.M) {
+	auth.JWTSecret = []byte("udpclient-test-secret-0123456789abcdef")
+	go udp.GlobalHub.Run()
+	addr, err := udp.Listen("127.0.0.1:0", auth.AnySession)
+	if err != nil {
+		panic(err)
+	}
+	serverAddr = addr.String()
+	os.Exit(m.Run())
+}
+
+// subscribe subscribes to manga:<mangaID> over conn as a user of its own
+func subscribe(t *testing.T, conn net.Conn, mangaID string, opts udpclient.Options) *udpclient.Client {
+	t.Helper()
+	token, err := auth.GenerateToken("usr_"+mangaID, "reader", auth.RoleUser, "ses_"+mangaID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Lost SUBs and cookies are sent again every ResendInterval
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	c, err := udpclient.NewClient(ctx, conn, token, []string{"manga:" + mangaID}, opts)
+	if err != nil {
+		t.Fatalf("subscribing: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// dial opens a connection to the test server
+func dial(t *testing.T) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("udp", serverAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// dialLossy subscribes to manga:<mangaID> through a LossyConn dropping
+// rate of the datagrams
+func dialLossy(t *testing.T, mangaID string, rate float64) (*udpclient.Client, *udpclient.LossyConn) {
+	t.Helper()
+	lossy := udpclient.NewLossyConn(dial(t), rate)
+	return subscribe(t, lossy, mangaID, udpclient.Options{Reliable: true}), lossy
+}
+
+// tamperConn records the datagrams read through it. It can also deliver
+// the fragments of each message in reverse order and drop the first
+// fragment with index drop it sees.
+type tamperConn struct {
+	net.Conn
+	reverse bool
+	drop    int // -1 to drop nothing
+
+	mu        sync.Mutex
+	datagrams [][]byte
+
+	dropped bool
+	held    [][]byte // Fragments of the message being reordered
+	pending [][]byte // Datagrams to return before reading more
+}
+
+func newTamperConn(conn net.Conn) *tamperConn {
+	return &tamperConn{Conn: conn, drop: -1}
+}
+
+// Read is called by the client's read loop only
+func (c *tamperConn) Read(b []byte) (int, error) {
+	for {
+		if len(c.pending) > 0 {
+			n := copy(b, c.pending[0])
+			c.pending = c.pending[1:]
+			return n, nil
+		}
+		n, err := c.Conn.Read(b)
+		if err != nil {
+			return n, err
+		}
+		datagram := bytes.Clone(b[:n])
+		c.mu.Lock()
+		c.datagrams = append(c.datagrams, datagram)
+		c.mu.Unlock()
+
+		var id uint32
+		var index, count int
+		if _, err := fmt.Sscanf(string(datagram), udp.CmdFrag+" %d %d/%d ", &id, &index, &count); err != nil {
+			return n, nil // Not a fragment
+		}
+		if index == c.drop && !c.dropped {
+			c.dropped = true
+		} else {
+			c.held = append(c.held, datagram)
+		}
+		if index < count-1 {
+			continue // The server sends them in order, and loopback keeps it
+		}
+		if c.reverse {
+			slices.Reverse(c.held)
+		}
+		c.pending, c.held = c.held, nil
+	}
+}
+
+// received returns the datagrams read so far
+func (c *tamperConn) received() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.datagrams)
+}
+
+func TestReliableDeliveryOverLossyConn(t *testing.T) {
+	if testing.Short() {
+		t.Skip("retransmits take seconds")
+	}
+	const updates = 200
+	c, lossy := dialLossy(t, "lossy", 0.1)
+
+	go func() {
+		for i := 1; i <= updates; i++ {
+			udp.GlobalHub.BroadcastProgress(models.ProgressUpdate{
+				UserID:         "usr_lossy",
+				MangaID:        "lossy",
+				CurrentChapter: i,
+			})
+			time.Sleep(udp.CoalesceWindow + 5*time.Millisecond) // One datagram each
+		}
+	}()
+
+	timeout := time.After(60 * time.Second)
+	for want := 1; want <= updates; want++ {
+		select {
+		case u, ok := <-c.Updates():
+			if !ok {
+				t.Fatalf("subscription ended after %d updates: %v", want-1, c.Err())
+			}
+			if u.CurrentChapter != want {
+				t.Fatalf("got chapter %d, want %d: updates must arrive once and in order", u.CurrentChapter, want)
+			}
+		case <-timeout:
+			t.Fatalf("only %d of %d updates arrived; stats %+v", want-1, updates, c.Stats())
+		}
+	}
+
+	// Late retransmits must not be delivered again
+	select {
+	case u := <-c.Updates():
+		t.Errorf("extra update after the last one: chapter %d", u.CurrentChapter)
+	case <-time.After(2 * time.Second):
+	}
+
+	stats := c.Stats()
+	if stats.Received != updates || stats.Missed != 0 {
+		t.Errorf("stats %+v, want %d received and none missed", stats, updates)
+	}
+	t.Logf("dropped %d datagrams; stats %+v", lossy.Dropped(), stats)
+}
+
+func TestCoalescedUpdatesShareADatagram(t *testing.T) {
+	const updates = 5
+	conn := newTamperConn(dial(t))
+	c := subscribe(t, conn, "coalesce", udpclient.Options{})
+
+	// Sent within one CoalesceWindow
+	for i := 1; i <= updates; i++ {
+		udp.GlobalHub.BroadcastProgress(models.ProgressUpdate{MangaID: "coalesce", CurrentChapter: i})
+	}
+	for want := 1; want <= updates; want++ {
+		select {
+		case u := <-c.Updates():
+			if u.CurrentChapter != want {
+				t.Fatalf("got chapter %d, want %d", u.CurrentChapter, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d updates arrived", want-1, updates)
+		}
+	}
+
+	var carrying [][]byte
+	for _, datagram := range conn.received() {
+		if bytes.HasPrefix(datagram, []byte("{")) {
+			carrying = append(carrying, datagram)
+		}
+	}
+	if len(carrying) != 1 || bytes.Count(carrying[0], []byte("\n")) != updates {
+		t.Errorf("updates came in %d datagram(s) %q, want all %d in one", len(carrying), carrying, updates)
+	}
+}
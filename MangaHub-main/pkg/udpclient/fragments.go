@@ -0,0 +1,95 @@
+package udpclient
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+)
+
+// FragmentTimeout is how long the fragments of an incomplete message are
+// kept. Reliable updates are retransmitted with the same message id, so
+// fragments of several sends combine within it.
+const FragmentTimeout = 30 * time.Second
+
+// Limits of the reassembly: the server splits a message into at most
+// maxFragments, and at most maxPartial incomplete messages are kept
+const (
+	maxFragments = 64
+	maxPartial   = 32
+)
+
+// fragPrefix starts a datagram holding one fragment:
+//
+//	FRAG <message id> <index>/<count> <bytes>
+const fragPrefix = "FRAG "
+
+// partial is a message whose fragments are arriving
+type partial struct {
+	parts   [][]byte
+	missing int
+	started time.Time
+}
+
+// reassembly joins the fragments of messages longer than the server's MTU,
+// by message id
+type reassembly map[string]*partial
+
+// add records a fragment and returns the message once all its fragments
+// arrived. It reports how many incomplete messages were given up on.
+func (r reassembly) add(datagram []byte, now time.Time) (message []byte, complete bool, dropped uint64) {
+	fields := bytes.SplitN(datagram, []byte(" "), 4)
+	if len(fields) != 4 {
+		return nil, false, 0
+	}
+	id := string(fields[1])
+	i, n, ok := bytes.Cut(fields[2], []byte("/"))
+	if !ok {
+		return nil, false, 0
+	}
+	index, err1 := strconv.Atoi(string(i))
+	count, err2 := strconv.Atoi(string(n))
+	if err1 != nil || err2 != nil || count < 1 || count > maxFragments || index < 0 || index >= count {
+		return nil, false, 0
+	}
+
+	p, ok := r[id]
+	if ok && len(p.parts) != count {
+		delete(r, id) // Message id reused by a new subscription
+		dropped++
+		ok = false
+	}
+	if !ok {
+		dropped += r.expire(now)
+		p = &partial{parts: make([][]byte, count), missing: count, started: now}
+		r[id] = p
+	}
+	if p.parts[index] == nil {
+		p.parts[index] = bytes.Clone(fields[3]) // The read buffer is reused
+		p.missing--
+	}
+	if p.missing > 0 {
+		return nil, false, dropped
+	}
+	delete(r, id)
+	return bytes.Join(p.parts, nil), true, dropped
+}
+
+// expire forgets messages older than FragmentTimeout, and the oldest one if
+// maxPartial are still incomplete, returning how many it forgot
+func (r reassembly) expire(now time.Time) uint64 {
+	var dropped uint64
+	oldest := ""
+	for id, p := range r {
+		if now.Sub(p.started) > FragmentTimeout {
+			delete(r, id)
+			dropped++
+		} else if oldest == "" || p.started.Before(r[oldest].started) {
+			oldest = id
+		}
+	}
+	if len(r) >= maxPartial {
+		delete(r, oldest)
+		dropped++
+	}
+	return dropped
+}
@@ -0,0 +1,59 @@
+package udpclient_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"mangahub/internal/udp"
+	"mangahub/pkg/models"
+	"mangahub/pkg/udpclient"
+)
+
+func TestFragmentedUpdateIsReassembled(t *testing.T) {
+	tests := []struct {
+		name    string
+		reverse bool
+		drop    int
+	}{
+		{"in order", false, -1},
+		{"out of order", true, -1},
+		{"fragment lost", true, 1}, // Completed by the retransmit
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mangaID := "frag" + string(rune('a'+i))
+			conn := newTamperConn(dial(t))
+			conn.reverse, conn.drop = tt.reverse, tt.drop
+			c := subscribe(t, conn, mangaID, udpclient.Options{Reliable: true})
+
+			title := strings.Repeat("long title ", 3*udp.MTU/10)
+			udp.GlobalHub.BroadcastProgress(models.ProgressUpdate{MangaID: mangaID, MangaTitle: title, CurrentChapter: 7})
+			select {
+			case u := <-c.Updates():
+				if u.MangaTitle != title || u.CurrentChapter != 7 {
+					t.Errorf("got chapter %d with a %d byte title, want chapter 7 with %d bytes", u.CurrentChapter, len(u.MangaTitle), len(title))
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("update did not arrive; stats %+v", c.Stats())
+			}
+
+			fragments := 0
+			for _, datagram := range conn.received() {
+				if len(datagram) > udp.MTU {
+					t.Errorf("datagram of %d bytes, over the MTU of %d", len(datagram), udp.MTU)
+				}
+				if bytes.HasPrefix(datagram, []byte(udp.CmdFrag+" ")) {
+					fragments++
+				}
+			}
+			if fragments < 4 {
+				t.Errorf("update came in %d fragments, want at least 4", fragments)
+			}
+			if stats := c.Stats(); stats.Reassembled != 1 || stats.Received != 1 {
+				t.Errorf("stats %+v, want one update reassembled and received", stats)
+			}
+		})
+	}
+}
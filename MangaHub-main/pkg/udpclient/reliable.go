@@ -0,0 +1,99 @@
+package udpclient
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxAhead bounds how many updates past a missing one are remembered. The
+// server gives up on an update after about 40 seconds; a client that
+// received this many later ones stops waiting for it.
+const maxAhead = 1024
+
+// window tracks which seqs of a reliable subscription have arrived, and
+// holds back those that arrived before an earlier one
+type window struct {
+	next  uint64            // Every seq up to next was delivered or given up on
+	ahead map[uint64]string // Updates above next+1 received, by seq
+}
+
+// add records the update data numbered seq. It returns the updates that
+// are now in order to be delivered, reports whether seq is new, and how many
+// missing seqs were given up on to make room.
+func (w *window) add(seq uint64, data string) (ready []string, fresh bool, skipped uint64) {
+	if _, ok := w.ahead[seq]; ok || seq <= w.next {
+		return nil, false, 0
+	}
+	if w.ahead == nil {
+		w.ahead = map[uint64]string{}
+	}
+	w.ahead[seq] = data
+
+	for {
+		if data, ok := w.ahead[w.next+1]; ok {
+			delete(w.ahead, w.next+1)
+			ready = append(ready, data)
+		} else if len(w.ahead) > maxAhead {
+			skipped++
+		} else {
+			return ready, true, skipped
+		}
+		w.next++
+	}
+}
+
+// LossyConn drops datagrams at random in both directions, simulating a bad
+// network to try reliable mode on:
+//
+//	conn, _ := net.Dial("udp", "localhost:9091")
+//	lossy := udpclient.NewLossyConn(conn, 0.3)
+//	c, err := udpclient.NewClient(ctx, lossy, token, topics, udpclient.Options{Reliable: true})
+type LossyConn struct {
+	net.Conn
+	rate float64
+
+	mu      sync.Mutex
+	rand    *rand.Rand
+	dropped atomic.Uint64
+}
+
+// NewLossyConn wraps conn to drop the given fraction of datagrams
+func NewLossyConn(conn net.Conn, rate float64) *LossyConn {
+	return &LossyConn{Conn: conn, rate: rate, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Read returns the next datagram that is not dropped
+func (c *LossyConn) Read(b []byte) (int, error) {
+	for {
+		n, err := c.Conn.Read(b)
+		if err != nil || !c.drop() {
+			return n, err
+		}
+	}
+}
+
+// Write sends b unless it is dropped
+func (c *LossyConn) Write(b []byte) (int, error) {
+	if c.drop() {
+		return len(b), nil
+	}
+	return c.Conn.Write(b)
+}
+
+// Dropped returns how many datagrams were dropped
+func (c *LossyConn) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+func (c *LossyConn) drop() bool {
+	c.mu.Lock()
+	lost := c.rand.Float64() < c.rate
+	c.mu.Unlock()
+	if lost {
+		c.dropped.Add(1)
+	}
+	return lost
+}
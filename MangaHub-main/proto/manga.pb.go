@@ -255,17 +255,88 @@ func (x *SearchMangaRequest) GetGenre() string {
 	return ""
 }
 
+// SearchResult is a full-text match with its relevance and highlights.
+// Matched terms are wrapped in <mark></mark>.
+type SearchResult struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Manga          *Manga                 `protobuf:"bytes,1,opt,name=manga,proto3" json:"manga,omitempty"`
+	Score          float64                `protobuf:"fixed64,2,opt,name=score,proto3" json:"score,omitempty"`
+	TitleHighlight string                 `protobuf:"bytes,3,opt,name=title_highlight,json=titleHighlight,proto3" json:"title_highlight,omitempty"`
+	Snippet        string                 `protobuf:"bytes,4,opt,name=snippet,proto3" json:"snippet,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SearchResult) Reset() {
+	*x = SearchResult{}
+	mi := &file_proto_manga_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResult) ProtoMessage() {}
+
+func (x *SearchResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResult.ProtoReflect.Descriptor instead.
+func (*SearchResult) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SearchResult) GetManga() *Manga {
+	if x != nil {
+		return x.Manga
+	}
+	return nil
+}
+
+func (x *SearchResult) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *SearchResult) GetTitleHighlight() string {
+	if x != nil {
+		return x.TitleHighlight
+	}
+	return ""
+}
+
+func (x *SearchResult) GetSnippet() string {
+	if x != nil {
+		return x.Snippet
+	}
+	return ""
+}
+
 type SearchMangaResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Mangas        []*Manga               `protobuf:"bytes,1,rep,name=mangas,proto3" json:"mangas,omitempty"`
 	Count         int32                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Results       []*SearchResult        `protobuf:"bytes,3,rep,name=results,proto3" json:"results,omitempty"` // Ranked matches, set when query is non-empty
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *SearchMangaResponse) Reset() {
 	*x = SearchMangaResponse{}
-	mi := &file_proto_manga_proto_msgTypes[4]
+	mi := &file_proto_manga_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -277,7 +348,7 @@ func (x *SearchMangaResponse) String() string {
 func (*SearchMangaResponse) ProtoMessage() {}
 
 func (x *SearchMangaResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_manga_proto_msgTypes[4]
+	mi := &file_proto_manga_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -290,7 +361,7 @@ func (x *SearchMangaResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SearchMangaResponse.ProtoReflect.Descriptor instead.
 func (*SearchMangaResponse) Descriptor() ([]byte, []int) {
-	return file_proto_manga_proto_rawDescGZIP(), []int{4}
+	return file_proto_manga_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *SearchMangaResponse) GetMangas() []*Manga {
@@ -307,6 +378,13 @@ func (x *SearchMangaResponse) GetCount() int32 {
 	return 0
 }
 
+func (x *SearchMangaResponse) GetResults() []*SearchResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
 type UpdateProgressRequest struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
 	UserId         string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
@@ -318,7 +396,7 @@ type UpdateProgressRequest struct {
 
 func (x *UpdateProgressRequest) Reset() {
 	*x = UpdateProgressRequest{}
-	mi := &file_proto_manga_proto_msgTypes[5]
+	mi := &file_proto_manga_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -330,7 +408,7 @@ func (x *UpdateProgressRequest) String() string {
 func (*UpdateProgressRequest) ProtoMessage() {}
 
 func (x *UpdateProgressRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_manga_proto_msgTypes[5]
+	mi := &file_proto_manga_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -343,7 +421,7 @@ func (x *UpdateProgressRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateProgressRequest.ProtoReflect.Descriptor instead.
 func (*UpdateProgressRequest) Descriptor() ([]byte, []int) {
-	return file_proto_manga_proto_rawDescGZIP(), []int{5}
+	return file_proto_manga_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *UpdateProgressRequest) GetUserId() string {
@@ -377,7 +455,7 @@ type UpdateProgressResponse struct {
 
 func (x *UpdateProgressResponse) Reset() {
 	*x = UpdateProgressResponse{}
-	mi := &file_proto_manga_proto_msgTypes[6]
+	mi := &file_proto_manga_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -389,7 +467,7 @@ func (x *UpdateProgressResponse) String() string {
 func (*UpdateProgressResponse) ProtoMessage() {}
 
 func (x *UpdateProgressResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_manga_proto_msgTypes[6]
+	mi := &file_proto_manga_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -402,7 +480,7 @@ func (x *UpdateProgressResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateProgressResponse.ProtoReflect.Descriptor instead.
 func (*UpdateProgressResponse) Descriptor() ([]byte, []int) {
-	return file_proto_manga_proto_rawDescGZIP(), []int{6}
+	return file_proto_manga_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *UpdateProgressResponse) GetSuccess() bool {
@@ -419,6 +497,592 @@ func (x *UpdateProgressResponse) GetMessage() string {
 	return ""
 }
 
+// CreateMangaRequest adds a catalog entry. The server assigns the ID.
+type CreateMangaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Manga         *Manga                 `protobuf:"bytes,1,opt,name=manga,proto3" json:"manga,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateMangaRequest) Reset() {
+	*x = CreateMangaRequest{}
+	mi := &file_proto_manga_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateMangaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateMangaRequest) ProtoMessage() {}
+
+func (x *CreateMangaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateMangaRequest.ProtoReflect.Descriptor instead.
+func (*CreateMangaRequest) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CreateMangaRequest) GetManga() *Manga {
+	if x != nil {
+		return x.Manga
+	}
+	return nil
+}
+
+type CreateMangaResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Manga         *Manga                 `protobuf:"bytes,1,opt,name=manga,proto3" json:"manga,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateMangaResponse) Reset() {
+	*x = CreateMangaResponse{}
+	mi := &file_proto_manga_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateMangaResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateMangaResponse) ProtoMessage() {}
+
+func (x *CreateMangaResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateMangaResponse.ProtoReflect.Descriptor instead.
+func (*CreateMangaResponse) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CreateMangaResponse) GetManga() *Manga {
+	if x != nil {
+		return x.Manga
+	}
+	return nil
+}
+
+// UpdateMangaRequest changes a catalog entry. update_mask names the fields
+// of manga to apply (title, author, genres, status, total_chapters,
+// description); an empty mask replaces them all.
+type UpdateMangaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Manga         *Manga                 `protobuf:"bytes,2,opt,name=manga,proto3" json:"manga,omitempty"`
+	UpdateMask    []string               `protobuf:"bytes,3,rep,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateMangaRequest) Reset() {
+	*x = UpdateMangaRequest{}
+	mi := &file_proto_manga_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateMangaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateMangaRequest) ProtoMessage() {}
+
+func (x *UpdateMangaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateMangaRequest.ProtoReflect.Descriptor instead.
+func (*UpdateMangaRequest) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *UpdateMangaRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UpdateMangaRequest) GetManga() *Manga {
+	if x != nil {
+		return x.Manga
+	}
+	return nil
+}
+
+func (x *UpdateMangaRequest) GetUpdateMask() []string {
+	if x != nil {
+		return x.UpdateMask
+	}
+	return nil
+}
+
+type UpdateMangaResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Manga         *Manga                 `protobuf:"bytes,1,opt,name=manga,proto3" json:"manga,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateMangaResponse) Reset() {
+	*x = UpdateMangaResponse{}
+	mi := &file_proto_manga_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateMangaResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateMangaResponse) ProtoMessage() {}
+
+func (x *UpdateMangaResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateMangaResponse.ProtoReflect.Descriptor instead.
+func (*UpdateMangaResponse) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *UpdateMangaResponse) GetManga() *Manga {
+	if x != nil {
+		return x.Manga
+	}
+	return nil
+}
+
+// DeleteMangaRequest hides a catalog entry; user progress is kept
+type DeleteMangaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMangaRequest) Reset() {
+	*x = DeleteMangaRequest{}
+	mi := &file_proto_manga_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMangaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMangaRequest) ProtoMessage() {}
+
+func (x *DeleteMangaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMangaRequest.ProtoReflect.Descriptor instead.
+func (*DeleteMangaRequest) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DeleteMangaRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type DeleteMangaResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMangaResponse) Reset() {
+	*x = DeleteMangaResponse{}
+	mi := &file_proto_manga_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMangaResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMangaResponse) ProtoMessage() {}
+
+func (x *DeleteMangaResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMangaResponse.ProtoReflect.Descriptor instead.
+func (*DeleteMangaResponse) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *DeleteMangaResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *DeleteMangaResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type RestoreMangaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreMangaRequest) Reset() {
+	*x = RestoreMangaRequest{}
+	mi := &file_proto_manga_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreMangaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreMangaRequest) ProtoMessage() {}
+
+func (x *RestoreMangaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreMangaRequest.ProtoReflect.Descriptor instead.
+func (*RestoreMangaRequest) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *RestoreMangaRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type RestoreMangaResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Manga         *Manga                 `protobuf:"bytes,1,opt,name=manga,proto3" json:"manga,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreMangaResponse) Reset() {
+	*x = RestoreMangaResponse{}
+	mi := &file_proto_manga_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreMangaResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreMangaResponse) ProtoMessage() {}
+
+func (x *RestoreMangaResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreMangaResponse.ProtoReflect.Descriptor instead.
+func (*RestoreMangaResponse) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RestoreMangaResponse) GetManga() *Manga {
+	if x != nil {
+		return x.Manga
+	}
+	return nil
+}
+
+// ProgressUpdate is a reading progress broadcast of the TCP progress
+// protocol, the binary form of its JSON update lines
+type ProgressUpdate struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	UserId         string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Username       string                 `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
+	MangaId        string                 `protobuf:"bytes,3,opt,name=manga_id,json=mangaId,proto3" json:"manga_id,omitempty"`
+	MangaTitle     string                 `protobuf:"bytes,4,opt,name=manga_title,json=mangaTitle,proto3" json:"manga_title,omitempty"`
+	CurrentChapter int32                  `protobuf:"varint,5,opt,name=current_chapter,json=currentChapter,proto3" json:"current_chapter,omitempty"`
+	Status         string                 `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	Timestamp      int64                  `protobuf:"varint,7,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // Unix seconds
+	Type           string                 `protobuf:"bytes,8,opt,name=type,proto3" json:"type,omitempty"`
+	EventId        string                 `protobuf:"bytes,9,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"` // Same for every redelivery of one update
+	Seq            uint64                 `protobuf:"varint,10,opt,name=seq,proto3" json:"seq,omitempty"`                      // Position in the stream, for RESUME
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ProgressUpdate) Reset() {
+	*x = ProgressUpdate{}
+	mi := &file_proto_manga_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProgressUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProgressUpdate) ProtoMessage() {}
+
+func (x *ProgressUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProgressUpdate.ProtoReflect.Descriptor instead.
+func (*ProgressUpdate) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ProgressUpdate) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ProgressUpdate) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *ProgressUpdate) GetMangaId() string {
+	if x != nil {
+		return x.MangaId
+	}
+	return ""
+}
+
+func (x *ProgressUpdate) GetMangaTitle() string {
+	if x != nil {
+		return x.MangaTitle
+	}
+	return ""
+}
+
+func (x *ProgressUpdate) GetCurrentChapter() int32 {
+	if x != nil {
+		return x.CurrentChapter
+	}
+	return 0
+}
+
+func (x *ProgressUpdate) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ProgressUpdate) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *ProgressUpdate) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ProgressUpdate) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *ProgressUpdate) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+// ProgressFrame is one message of the TCP progress protocol after an
+// "AUTH <token> PROTOBUF" handshake. On the wire each frame is preceded by
+// its size in bytes as a 4-byte big-endian integer.
+type ProgressFrame struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Body:
+	//
+	//	*ProgressFrame_Line
+	//	*ProgressFrame_Update
+	Body          isProgressFrame_Body `protobuf_oneof:"body"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProgressFrame) Reset() {
+	*x = ProgressFrame{}
+	mi := &file_proto_manga_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProgressFrame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProgressFrame) ProtoMessage() {}
+
+func (x *ProgressFrame) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_manga_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProgressFrame.ProtoReflect.Descriptor instead.
+func (*ProgressFrame) Descriptor() ([]byte, []int) {
+	return file_proto_manga_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ProgressFrame) GetBody() isProgressFrame_Body {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+func (x *ProgressFrame) GetLine() string {
+	if x != nil {
+		if x, ok := x.Body.(*ProgressFrame_Line); ok {
+			return x.Line
+		}
+	}
+	return ""
+}
+
+func (x *ProgressFrame) GetUpdate() *ProgressUpdate {
+	if x != nil {
+		if x, ok := x.Body.(*ProgressFrame_Update); ok {
+			return x.Update
+		}
+	}
+	return nil
+}
+
+type isProgressFrame_Body interface {
+	isProgressFrame_Body()
+}
+
+type ProgressFrame_Line struct {
+	// A protocol line without its line break: a command from the client,
+	// or a reply, PING, PONG or BYE from the server
+	Line string `protobuf:"bytes,1,opt,name=line,proto3,oneof"`
+}
+
+type ProgressFrame_Update struct {
+	Update *ProgressUpdate `protobuf:"bytes,2,opt,name=update,proto3,oneof"` // Sent by the server only
+}
+
+func (*ProgressFrame_Line) isProgressFrame_Body() {}
+
+func (*ProgressFrame_Update) isProgressFrame_Body() {}
+
 var File_proto_manga_proto protoreflect.FileDescriptor
 
 const file_proto_manga_proto_rawDesc = "" +
@@ -438,21 +1102,68 @@ const file_proto_manga_proto_rawDesc = "" +
 	"\x05manga\x18\x01 \x01(\v2\f.manga.MangaR\x05manga\"@\n" +
 	"\x12SearchMangaRequest\x12\x14\n" +
 	"\x05query\x18\x01 \x01(\tR\x05query\x12\x14\n" +
-	"\x05genre\x18\x02 \x01(\tR\x05genre\"Q\n" +
+	"\x05genre\x18\x02 \x01(\tR\x05genre\"\x8b\x01\n" +
+	"\fSearchResult\x12\"\n" +
+	"\x05manga\x18\x01 \x01(\v2\f.manga.MangaR\x05manga\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x01R\x05score\x12'\n" +
+	"\x0ftitle_highlight\x18\x03 \x01(\tR\x0etitleHighlight\x12\x18\n" +
+	"\asnippet\x18\x04 \x01(\tR\asnippet\"\x80\x01\n" +
 	"\x13SearchMangaResponse\x12$\n" +
 	"\x06mangas\x18\x01 \x03(\v2\f.manga.MangaR\x06mangas\x12\x14\n" +
-	"\x05count\x18\x02 \x01(\x05R\x05count\"t\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count\x12-\n" +
+	"\aresults\x18\x03 \x03(\v2\x13.manga.SearchResultR\aresults\"t\n" +
 	"\x15UpdateProgressRequest\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x19\n" +
 	"\bmanga_id\x18\x02 \x01(\tR\amangaId\x12'\n" +
 	"\x0fcurrent_chapter\x18\x03 \x01(\x05R\x0ecurrentChapter\"L\n" +
 	"\x16UpdateProgressResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage2\xe0\x01\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"8\n" +
+	"\x12CreateMangaRequest\x12\"\n" +
+	"\x05manga\x18\x01 \x01(\v2\f.manga.MangaR\x05manga\"9\n" +
+	"\x13CreateMangaResponse\x12\"\n" +
+	"\x05manga\x18\x01 \x01(\v2\f.manga.MangaR\x05manga\"i\n" +
+	"\x12UpdateMangaRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\"\n" +
+	"\x05manga\x18\x02 \x01(\v2\f.manga.MangaR\x05manga\x12\x1f\n" +
+	"\vupdate_mask\x18\x03 \x03(\tR\n" +
+	"updateMask\"9\n" +
+	"\x13UpdateMangaResponse\x12\"\n" +
+	"\x05manga\x18\x01 \x01(\v2\f.manga.MangaR\x05manga\"$\n" +
+	"\x12DeleteMangaRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"I\n" +
+	"\x13DeleteMangaResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"%\n" +
+	"\x13RestoreMangaRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\":\n" +
+	"\x14RestoreMangaResponse\x12\"\n" +
+	"\x05manga\x18\x01 \x01(\v2\f.manga.MangaR\x05manga\"\xa1\x02\n" +
+	"\x0eProgressUpdate\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1a\n" +
+	"\busername\x18\x02 \x01(\tR\busername\x12\x19\n" +
+	"\bmanga_id\x18\x03 \x01(\tR\amangaId\x12\x1f\n" +
+	"\vmanga_title\x18\x04 \x01(\tR\n" +
+	"mangaTitle\x12'\n" +
+	"\x0fcurrent_chapter\x18\x05 \x01(\x05R\x0ecurrentChapter\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\tR\x06status\x12\x1c\n" +
+	"\ttimestamp\x18\a \x01(\x03R\ttimestamp\x12\x12\n" +
+	"\x04type\x18\b \x01(\tR\x04type\x12\x19\n" +
+	"\bevent_id\x18\t \x01(\tR\aeventId\x12\x10\n" +
+	"\x03seq\x18\n" +
+	" \x01(\x04R\x03seq\"^\n" +
+	"\rProgressFrame\x12\x14\n" +
+	"\x04line\x18\x01 \x01(\tH\x00R\x04line\x12/\n" +
+	"\x06update\x18\x02 \x01(\v2\x15.manga.ProgressUpdateH\x00R\x06updateB\x06\n" +
+	"\x04body2\xfb\x03\n" +
 	"\fMangaService\x12;\n" +
 	"\bGetManga\x12\x16.manga.GetMangaRequest\x1a\x17.manga.GetMangaResponse\x12D\n" +
 	"\vSearchManga\x12\x19.manga.SearchMangaRequest\x1a\x1a.manga.SearchMangaResponse\x12M\n" +
-	"\x0eUpdateProgress\x12\x1c.manga.UpdateProgressRequest\x1a\x1d.manga.UpdateProgressResponseB\x10Z\x0emangahub/protob\x06proto3"
+	"\x0eUpdateProgress\x12\x1c.manga.UpdateProgressRequest\x1a\x1d.manga.UpdateProgressResponse\x12D\n" +
+	"\vCreateManga\x12\x19.manga.CreateMangaRequest\x1a\x1a.manga.CreateMangaResponse\x12D\n" +
+	"\vUpdateManga\x12\x19.manga.UpdateMangaRequest\x1a\x1a.manga.UpdateMangaResponse\x12D\n" +
+	"\vDeleteManga\x12\x19.manga.DeleteMangaRequest\x1a\x1a.manga.DeleteMangaResponse\x12G\n" +
+	"\fRestoreManga\x12\x1a.manga.RestoreMangaRequest\x1a\x1b.manga.RestoreMangaResponseB\x10Z\x0emangahub/protob\x06proto3"
 
 var (
 	file_proto_manga_proto_rawDescOnce sync.Once
@@ -466,30 +1177,57 @@ func file_proto_manga_proto_rawDescGZIP() []byte {
 	return file_proto_manga_proto_rawDescData
 }
 
-var file_proto_manga_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_proto_manga_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
 var file_proto_manga_proto_goTypes = []any{
 	(*Manga)(nil),                  // 0: manga.Manga
 	(*GetMangaRequest)(nil),        // 1: manga.GetMangaRequest
 	(*GetMangaResponse)(nil),       // 2: manga.GetMangaResponse
 	(*SearchMangaRequest)(nil),     // 3: manga.SearchMangaRequest
-	(*SearchMangaResponse)(nil),    // 4: manga.SearchMangaResponse
-	(*UpdateProgressRequest)(nil),  // 5: manga.UpdateProgressRequest
-	(*UpdateProgressResponse)(nil), // 6: manga.UpdateProgressResponse
+	(*SearchResult)(nil),           // 4: manga.SearchResult
+	(*SearchMangaResponse)(nil),    // 5: manga.SearchMangaResponse
+	(*UpdateProgressRequest)(nil),  // 6: manga.UpdateProgressRequest
+	(*UpdateProgressResponse)(nil), // 7: manga.UpdateProgressResponse
+	(*CreateMangaRequest)(nil),     // 8: manga.CreateMangaRequest
+	(*CreateMangaResponse)(nil),    // 9: manga.CreateMangaResponse
+	(*UpdateMangaRequest)(nil),     // 10: manga.UpdateMangaRequest
+	(*UpdateMangaResponse)(nil),    // 11: manga.UpdateMangaResponse
+	(*DeleteMangaRequest)(nil),     // 12: manga.DeleteMangaRequest
+	(*DeleteMangaResponse)(nil),    // 13: manga.DeleteMangaResponse
+	(*RestoreMangaRequest)(nil),    // 14: manga.RestoreMangaRequest
+	(*RestoreMangaResponse)(nil),   // 15: manga.RestoreMangaResponse
+	(*ProgressUpdate)(nil),         // 16: manga.ProgressUpdate
+	(*ProgressFrame)(nil),          // 17: manga.ProgressFrame
 }
 var file_proto_manga_proto_depIdxs = []int32{
-	0, // 0: manga.GetMangaResponse.manga:type_name -> manga.Manga
-	0, // 1: manga.SearchMangaResponse.mangas:type_name -> manga.Manga
-	1, // 2: manga.MangaService.GetManga:input_type -> manga.GetMangaRequest
-	3, // 3: manga.MangaService.SearchManga:input_type -> manga.SearchMangaRequest
-	5, // 4: manga.MangaService.UpdateProgress:input_type -> manga.UpdateProgressRequest
-	2, // 5: manga.MangaService.GetManga:output_type -> manga.GetMangaResponse
-	4, // 6: manga.MangaService.SearchManga:output_type -> manga.SearchMangaResponse
-	6, // 7: manga.MangaService.UpdateProgress:output_type -> manga.UpdateProgressResponse
-	5, // [5:8] is the sub-list for method output_type
-	2, // [2:5] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	0,  // 0: manga.GetMangaResponse.manga:type_name -> manga.Manga
+	0,  // 1: manga.SearchResult.manga:type_name -> manga.Manga
+	0,  // 2: manga.SearchMangaResponse.mangas:type_name -> manga.Manga
+	4,  // 3: manga.SearchMangaResponse.results:type_name -> manga.SearchResult
+	0,  // 4: manga.CreateMangaRequest.manga:type_name -> manga.Manga
+	0,  // 5: manga.CreateMangaResponse.manga:type_name -> manga.Manga
+	0,  // 6: manga.UpdateMangaRequest.manga:type_name -> manga.Manga
+	0,  // 7: manga.UpdateMangaResponse.manga:type_name -> manga.Manga
+	0,  // 8: manga.RestoreMangaResponse.manga:type_name -> manga.Manga
+	16, // 9: manga.ProgressFrame.update:type_name -> manga.ProgressUpdate
+	1,  // 10: manga.MangaService.GetManga:input_type -> manga.GetMangaRequest
+	3,  // 11: manga.MangaService.SearchManga:input_type -> manga.SearchMangaRequest
+	6,  // 12: manga.MangaService.UpdateProgress:input_type -> manga.UpdateProgressRequest
+	8,  // 13: manga.MangaService.CreateManga:input_type -> manga.CreateMangaRequest
+	10, // 14: manga.MangaService.UpdateManga:input_type -> manga.UpdateMangaRequest
+	12, // 15: manga.MangaService.DeleteManga:input_type -> manga.DeleteMangaRequest
+	14, // 16: manga.MangaService.RestoreManga:input_type -> manga.RestoreMangaRequest
+	2,  // 17: manga.MangaService.GetManga:output_type -> manga.GetMangaResponse
+	5,  // 18: manga.MangaService.SearchManga:output_type -> manga.SearchMangaResponse
+	7,  // 19: manga.MangaService.UpdateProgress:output_type -> manga.UpdateProgressResponse
+	9,  // 20: manga.MangaService.CreateManga:output_type -> manga.CreateMangaResponse
+	11, // 21: manga.MangaService.UpdateManga:output_type -> manga.UpdateMangaResponse
+	13, // 22: manga.MangaService.DeleteManga:output_type -> manga.DeleteMangaResponse
+	15, // 23: manga.MangaService.RestoreManga:output_type -> manga.RestoreMangaResponse
+	17, // [17:24] is the sub-list for method output_type
+	10, // [10:17] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
 }
 
 func init() { file_proto_manga_proto_init() }
@@ -497,13 +1235,17 @@ func file_proto_manga_proto_init() {
 	if File_proto_manga_proto != nil {
 		return
 	}
+	file_proto_manga_proto_msgTypes[17].OneofWrappers = []any{
+		(*ProgressFrame_Line)(nil),
+		(*ProgressFrame_Update)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_manga_proto_rawDesc), len(file_proto_manga_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   7,
+			NumMessages:   18,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
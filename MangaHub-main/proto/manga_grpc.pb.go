@@ -22,17 +22,27 @@ const (
 	MangaService_GetManga_FullMethodName       = "/manga.MangaService/GetManga"
 	MangaService_SearchManga_FullMethodName    = "/manga.MangaService/SearchManga"
 	MangaService_UpdateProgress_FullMethodName = "/manga.MangaService/UpdateProgress"
+	MangaService_CreateManga_FullMethodName    = "/manga.MangaService/CreateManga"
+	MangaService_UpdateManga_FullMethodName    = "/manga.MangaService/UpdateManga"
+	MangaService_DeleteManga_FullMethodName    = "/manga.MangaService/DeleteManga"
+	MangaService_RestoreManga_FullMethodName   = "/manga.MangaService/RestoreManga"
 )
 
 // MangaServiceClient is the client API for MangaService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 //
-// MangaService - Internal service for manga operations
+// MangaService - Internal service for manga operations.
+// Catalog writes require a bearer token for an admin or moderator in the
+// "authorization" metadata.
 type MangaServiceClient interface {
 	GetManga(ctx context.Context, in *GetMangaRequest, opts ...grpc.CallOption) (*GetMangaResponse, error)
 	SearchManga(ctx context.Context, in *SearchMangaRequest, opts ...grpc.CallOption) (*SearchMangaResponse, error)
 	UpdateProgress(ctx context.Context, in *UpdateProgressRequest, opts ...grpc.CallOption) (*UpdateProgressResponse, error)
+	CreateManga(ctx context.Context, in *CreateMangaRequest, opts ...grpc.CallOption) (*CreateMangaResponse, error)
+	UpdateManga(ctx context.Context, in *UpdateMangaRequest, opts ...grpc.CallOption) (*UpdateMangaResponse, error)
+	DeleteManga(ctx context.Context, in *DeleteMangaRequest, opts ...grpc.CallOption) (*DeleteMangaResponse, error)
+	RestoreManga(ctx context.Context, in *RestoreMangaRequest, opts ...grpc.CallOption) (*RestoreMangaResponse, error)
 }
 
 type mangaServiceClient struct {
@@ -73,15 +83,61 @@ func (c *mangaServiceClient) UpdateProgress(ctx context.Context, in *UpdateProgr
 	return out, nil
 }
 
+func (c *mangaServiceClient) CreateManga(ctx context.Context, in *CreateMangaRequest, opts ...grpc.CallOption) (*CreateMangaResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateMangaResponse)
+	err := c.cc.Invoke(ctx, MangaService_CreateManga_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mangaServiceClient) UpdateManga(ctx context.Context, in *UpdateMangaRequest, opts ...grpc.CallOption) (*UpdateMangaResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateMangaResponse)
+	err := c.cc.Invoke(ctx, MangaService_UpdateManga_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mangaServiceClient) DeleteManga(ctx context.Context, in *DeleteMangaRequest, opts ...grpc.CallOption) (*DeleteMangaResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteMangaResponse)
+	err := c.cc.Invoke(ctx, MangaService_DeleteManga_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mangaServiceClient) RestoreManga(ctx context.Context, in *RestoreMangaRequest, opts ...grpc.CallOption) (*RestoreMangaResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RestoreMangaResponse)
+	err := c.cc.Invoke(ctx, MangaService_RestoreManga_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MangaServiceServer is the server API for MangaService service.
 // All implementations must embed UnimplementedMangaServiceServer
 // for forward compatibility.
 //
-// MangaService - Internal service for manga operations
+// MangaService - Internal service for manga operations.
+// Catalog writes require a bearer token for an admin or moderator in the
+// "authorization" metadata.
 type MangaServiceServer interface {
 	GetManga(context.Context, *GetMangaRequest) (*GetMangaResponse, error)
 	SearchManga(context.Context, *SearchMangaRequest) (*SearchMangaResponse, error)
 	UpdateProgress(context.Context, *UpdateProgressRequest) (*UpdateProgressResponse, error)
+	CreateManga(context.Context, *CreateMangaRequest) (*CreateMangaResponse, error)
+	UpdateManga(context.Context, *UpdateMangaRequest) (*UpdateMangaResponse, error)
+	DeleteManga(context.Context, *DeleteMangaRequest) (*DeleteMangaResponse, error)
+	RestoreManga(context.Context, *RestoreMangaRequest) (*RestoreMangaResponse, error)
 	mustEmbedUnimplementedMangaServiceServer()
 }
 
@@ -101,6 +157,18 @@ func (UnimplementedMangaServiceServer) SearchManga(context.Context, *SearchManga
 func (UnimplementedMangaServiceServer) UpdateProgress(context.Context, *UpdateProgressRequest) (*UpdateProgressResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method UpdateProgress not implemented")
 }
+func (UnimplementedMangaServiceServer) CreateManga(context.Context, *CreateMangaRequest) (*CreateMangaResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateManga not implemented")
+}
+func (UnimplementedMangaServiceServer) UpdateManga(context.Context, *UpdateMangaRequest) (*UpdateMangaResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method UpdateManga not implemented")
+}
+func (UnimplementedMangaServiceServer) DeleteManga(context.Context, *DeleteMangaRequest) (*DeleteMangaResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteManga not implemented")
+}
+func (UnimplementedMangaServiceServer) RestoreManga(context.Context, *RestoreMangaRequest) (*RestoreMangaResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RestoreManga not implemented")
+}
 func (UnimplementedMangaServiceServer) mustEmbedUnimplementedMangaServiceServer() {}
 func (UnimplementedMangaServiceServer) testEmbeddedByValue()                      {}
 
@@ -176,6 +244,78 @@ func _MangaService_UpdateProgress_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MangaService_CreateManga_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateMangaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MangaServiceServer).CreateManga(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MangaService_CreateManga_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MangaServiceServer).CreateManga(ctx, req.(*CreateMangaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MangaService_UpdateManga_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateMangaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MangaServiceServer).UpdateManga(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MangaService_UpdateManga_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MangaServiceServer).UpdateManga(ctx, req.(*UpdateMangaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MangaService_DeleteManga_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteMangaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MangaServiceServer).DeleteManga(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MangaService_DeleteManga_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MangaServiceServer).DeleteManga(ctx, req.(*DeleteMangaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MangaService_RestoreManga_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreMangaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MangaServiceServer).RestoreManga(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MangaService_RestoreManga_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MangaServiceServer).RestoreManga(ctx, req.(*RestoreMangaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MangaService_ServiceDesc is the grpc.ServiceDesc for MangaService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -195,6 +335,22 @@ var MangaService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateProgress",
 			Handler:    _MangaService_UpdateProgress_Handler,
 		},
+		{
+			MethodName: "CreateManga",
+			Handler:    _MangaService_CreateManga_Handler,
+		},
+		{
+			MethodName: "UpdateManga",
+			Handler:    _MangaService_UpdateManga_Handler,
+		},
+		{
+			MethodName: "DeleteManga",
+			Handler:    _MangaService_DeleteManga_Handler,
+		},
+		{
+			MethodName: "RestoreManga",
+			Handler:    _MangaService_RestoreManga_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/manga.proto",